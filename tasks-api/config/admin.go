@@ -0,0 +1,6 @@
+package config
+
+// AdminEmails lists the accounts that are made admins at startup, so a fresh
+// instance can get its first admin. TASKS_ADMIN_EMAILS takes a comma
+// separated list.
+var AdminEmails = envList("TASKS_ADMIN_EMAILS", nil)
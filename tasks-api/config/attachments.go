@@ -0,0 +1,66 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Attachment settings. They are read from the environment at startup and can
+// be changed before serving requests.
+var (
+	// AttachmentsDir is the directory attachment files are stored in,
+	// TASKS_ATTACHMENTS_DIR (default ./attachments)
+	AttachmentsDir = envString("TASKS_ATTACHMENTS_DIR", "./attachments")
+	// MaxAttachmentSize is the largest file that can be uploaded in bytes,
+	// TASKS_ATTACHMENTS_MAX_SIZE (default 10 MiB)
+	MaxAttachmentSize = envInt64("TASKS_ATTACHMENTS_MAX_SIZE", 10<<20)
+	// AllowedAttachmentTypes lists the MIME types that can be uploaded; an entry
+	// like "image/*" allows a whole type. TASKS_ATTACHMENTS_ALLOWED_TYPES takes
+	// a comma separated list.
+	AllowedAttachmentTypes = envList("TASKS_ATTACHMENTS_ALLOWED_TYPES", []string{
+		"image/png",
+		"image/jpeg",
+		"image/gif",
+		"image/webp",
+		"application/pdf",
+		"text/plain",
+		"text/csv",
+		"application/zip",
+	})
+)
+
+// envString returns the environment variable key, or fallback when it is not set
+func envString(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// envInt64 returns the environment variable key as a number, or fallback when
+// it is not set or not a positive number
+func envInt64(key string, fallback int64) int64 {
+	value, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// envList returns the comma separated environment variable key, or fallback
+// when it is not set
+func envList(key string, fallback []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
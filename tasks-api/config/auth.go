@@ -0,0 +1,31 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+const (
+	// AccessTokenDuration is how long an access token stays valid without being used
+	AccessTokenDuration = 15 * time.Minute
+	// RefreshTokenDuration is how long a refresh token can be exchanged for new tokens
+	RefreshTokenDuration = 30 * 24 * time.Hour
+	// InvitationDuration is how long a workspace invitation can be accepted
+	InvitationDuration = 7 * 24 * time.Hour
+	// PasswordResetDuration is how long a password reset token can be used
+	PasswordResetDuration = time.Hour
+	// EmailVerificationDuration is how long an email verification link works
+	EmailVerificationDuration = 48 * time.Hour
+	// LoginFailureWindow is how long failed logins count towards a lockout;
+	// they are also forgotten after a successful login
+	LoginFailureWindow = 24 * time.Hour
+)
+
+// HashToken returns the hex encoded SHA-256 hash a token is stored under.
+// Handlers store sessions, access tokens and one-time tokens with it and the
+// auth middleware looks access tokens up with it, so both must use this one.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
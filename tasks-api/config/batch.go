@@ -0,0 +1,11 @@
+package config
+
+// MaxBatchSize is the largest number of operations a single batch request can
+// run, TASKS_BATCH_MAX_SIZE (default 500)
+var MaxBatchSize = int(envInt64("TASKS_BATCH_MAX_SIZE", 500))
+
+// MaxBatchOperationSize is the room a single operation gets in the body of a
+// batch request in bytes, TASKS_BATCH_MAX_OPERATION_SIZE (default 16 KiB).
+// Bodies larger than MaxBatchSize operations of this size are refused before
+// they are read.
+var MaxBatchOperationSize = envInt64("TASKS_BATCH_MAX_OPERATION_SIZE", 16<<10)
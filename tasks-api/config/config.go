@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"runtime"
 
+	_ "github.com/eokwukwe/golearn/tasks/migrations" // registers Go migrations
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/pressly/goose/v3"
 )
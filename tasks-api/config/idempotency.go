@@ -0,0 +1,14 @@
+package config
+
+import "time"
+
+// IdempotencyKeyTTL is how long the response to a request with an
+// Idempotency-Key header is kept for replays, TASKS_IDEMPOTENCY_KEY_TTL as a
+// duration like "24h" (default 24 hours)
+var IdempotencyKeyTTL = envDuration("TASKS_IDEMPOTENCY_KEY_TTL", 24*time.Hour)
+
+// MaxIdempotentRequestSize is the largest request body in bytes read into
+// memory to hash a request with an Idempotency-Key,
+// TASKS_IDEMPOTENCY_MAX_BODY_SIZE (default the largest attachment upload plus
+// 1 MiB for its multipart headers)
+var MaxIdempotentRequestSize = envInt64("TASKS_IDEMPOTENCY_MAX_BODY_SIZE", MaxAttachmentSize+1<<20)
@@ -0,0 +1,36 @@
+package config
+
+import "github.com/eokwukwe/golearn/tasks/mail"
+
+// Mail settings. They are read from the environment at startup and can be
+// changed before serving requests.
+var (
+	// BaseURL is the address of the web app links in emails point to,
+	// TASKS_BASE_URL (default http://localhost:3000)
+	BaseURL = envString("TASKS_BASE_URL", "http://localhost:3000")
+	// Mailer sends the emails of the API. It sends them through the SMTP
+	// server TASKS_SMTP_HOST (with TASKS_SMTP_PORT, default 587, and the
+	// optional TASKS_SMTP_USERNAME and TASKS_SMTP_PASSWORD) when it is set,
+	// and otherwise writes them to the file TASKS_MAIL_FILE or to the log.
+	// Emails are sent from TASKS_MAIL_FROM (default no-reply@localhost).
+	Mailer = newMailer()
+	// SendInBackground runs send, which sends an email, without making the
+	// request wait for it, so responses take as long whether or not an email
+	// goes out. Tests replace it to run send right away or to wait for it.
+	SendInBackground = func(send func()) { go send() }
+)
+
+// newMailer returns the mailer configured in the environment
+func newMailer() mail.Mailer {
+	from := envString("TASKS_MAIL_FROM", "no-reply@localhost")
+	if host := envString("TASKS_SMTP_HOST", ""); host != "" {
+		return &mail.SMTPMailer{
+			Host:     host,
+			Port:     int(envInt64("TASKS_SMTP_PORT", 587)),
+			Username: envString("TASKS_SMTP_USERNAME", ""),
+			Password: envString("TASKS_SMTP_PASSWORD", ""),
+			From:     from,
+		}
+	}
+	return &mail.LogMailer{Path: envString("TASKS_MAIL_FILE", ""), From: from}
+}
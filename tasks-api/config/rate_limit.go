@@ -0,0 +1,102 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit allows Requests requests per Window. Requests can be spent at
+// once and are refilled evenly over the window. A limit of zero requests
+// disables rate limiting.
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Rate limit and login lockout settings. They are read from the environment
+// at startup and can be changed before serving requests.
+var (
+	// DefaultRateLimit applies to routes without a limit of their own,
+	// TASKS_RATE_LIMIT as "requests/window" like "300/1m" (the default)
+	DefaultRateLimit = envRateLimit("TASKS_RATE_LIMIT", RateLimit{Requests: 300, Window: time.Minute})
+	// RouteRateLimits sets the limits of single routes by their pattern.
+	// TASKS_RATE_LIMIT_ROUTES takes a comma separated list like
+	// "/api/v1/login=10/1m,/api/v1/tasks=600/1m".
+	RouteRateLimits = envRateLimits("TASKS_RATE_LIMIT_ROUTES", map[string]RateLimit{
+		"/api/v1/login":           {Requests: 10, Window: time.Minute},
+		"/api/v1/register":        {Requests: 5, Window: time.Minute},
+		"/api/v1/token/refresh":   {Requests: 30, Window: time.Minute},
+		"/api/v1/password/forgot": {Requests: 5, Window: time.Minute},
+		"/api/v1/password/reset":  {Requests: 10, Window: time.Minute},
+		"/api/v1/email/resend":    {Requests: 5, Window: time.Minute},
+	})
+
+	// LoginLockoutThreshold is how many failed logins in a row lock an
+	// account, TASKS_LOGIN_LOCKOUT_THRESHOLD (default 5)
+	LoginLockoutThreshold = int(envInt64("TASKS_LOGIN_LOCKOUT_THRESHOLD", 5))
+	// LoginLockoutDuration is how long the first lockout lasts; every further
+	// failed login doubles it up to LoginLockoutMaxDuration.
+	// TASKS_LOGIN_LOCKOUT_DURATION (default 1m)
+	LoginLockoutDuration = envDuration("TASKS_LOGIN_LOCKOUT_DURATION", time.Minute)
+	// LoginLockoutMaxDuration caps the lockout, TASKS_LOGIN_LOCKOUT_MAX_DURATION
+	// (default 1h)
+	LoginLockoutMaxDuration = envDuration("TASKS_LOGIN_LOCKOUT_MAX_DURATION", time.Hour)
+)
+
+// RouteRateLimit returns the rate limit of the route with the given pattern
+func RouteRateLimit(pattern string) RateLimit {
+	if limit, ok := RouteRateLimits[pattern]; ok {
+		return limit
+	}
+	return DefaultRateLimit
+}
+
+// parseRateLimit parses a limit written as "requests/window", like "10/1m"
+func parseRateLimit(value string) (RateLimit, bool) {
+	requests, window, ok := strings.Cut(value, "/")
+	if !ok {
+		return RateLimit{}, false
+	}
+
+	limit := RateLimit{}
+	var err error
+	if limit.Requests, err = strconv.Atoi(strings.TrimSpace(requests)); err != nil || limit.Requests < 0 {
+		return RateLimit{}, false
+	}
+	if limit.Window, err = time.ParseDuration(strings.TrimSpace(window)); err != nil || limit.Window <= 0 {
+		return RateLimit{}, false
+	}
+	return limit, true
+}
+
+// envRateLimit returns the environment variable key as a rate limit, or
+// fallback when it is not set or not valid
+func envRateLimit(key string, fallback RateLimit) RateLimit {
+	if limit, ok := parseRateLimit(os.Getenv(key)); ok {
+		return limit
+	}
+	return fallback
+}
+
+// envRateLimits returns fallback with the limits of the comma separated
+// "pattern=requests/window" environment variable key added. Invalid entries
+// are ignored.
+func envRateLimits(key string, fallback map[string]RateLimit) map[string]RateLimit {
+	limits := map[string]RateLimit{}
+	for pattern, limit := range fallback {
+		limits[pattern] = limit
+	}
+
+	for _, entry := range envList(key, nil) {
+		pattern, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		if limit, ok := parseRateLimit(value); ok {
+			limits[strings.TrimSpace(pattern)] = limit
+		}
+	}
+	return limits
+}
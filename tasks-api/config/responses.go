@@ -13,9 +13,16 @@ type Response struct {
 	Status  string            `json:"status"`
 	Message string            `json:"message"`
 	Data    any               `json:"data,omitempty"`
+	Meta    *Meta             `json:"meta,omitempty"`
 	Errors  map[string]string `json:"errors,omitempty"`
 }
 
+// Meta holds pagination details for list responses
+type Meta struct {
+	Limit      int     `json:"limit"`
+	NextCursor *string `json:"next_cursor"`
+}
+
 // WriteResponse writes a JSON response with the given status code and response object
 func WriteResponse(w http.ResponseWriter, status int, resp *Response) {
 	w.Header().Set("Content-Type", "application/json")
@@ -29,12 +36,29 @@ func WriteSuccessResponse(w http.ResponseWriter, message string, data any) {
 	WriteResponse(w, http.StatusOK, resp)
 }
 
+// WritePaginatedResponse writes a success response with pagination details.
+// An empty nextCursor means there are no more pages.
+func WritePaginatedResponse(w http.ResponseWriter, message string, data any, limit int, nextCursor string) {
+	resp := NewSuccessResponse(message, data)
+	resp.Meta = &Meta{Limit: limit}
+	if nextCursor != "" {
+		resp.Meta.NextCursor = &nextCursor
+	}
+	WriteResponse(w, http.StatusOK, resp)
+}
+
 // WriteErrorResponse writes an error response
 func WriteErrorResponse(w http.ResponseWriter, status int, message string, err error) {
 	resp := NewErrorResponse(message, err)
 	WriteResponse(w, status, resp)
 }
 
+// WriteValidationErrorResponse writes a 422 response with a field to message map
+func WriteValidationErrorResponse(w http.ResponseWriter, errs map[string]string) {
+	resp := NewErrorResponse("Validation failed", errs)
+	WriteResponse(w, http.StatusUnprocessableEntity, resp)
+}
+
 // WriteCreatedResponse writes a response for successful creation
 func WriteCreatedResponse(w http.ResponseWriter, message string, data any) {
 	resp := NewSuccessResponse(message, data)
@@ -76,6 +100,8 @@ func NewErrorResponse(message string, validationErrors any) *Response {
 				errMap[field] = msg
 			}
 			resp.Errors = errMap
+		case map[string]string:
+			resp.Errors = v
 		case error:
 			resp.Message = v.Error()
 		}
@@ -0,0 +1,21 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// TrashRetention is how long deleted tasks stay in the trash before they are
+// purged, TASKS_TRASH_RETENTION as a duration like "720h" (default 30 days).
+// "0" keeps them until they are purged by hand.
+var TrashRetention = envDuration("TASKS_TRASH_RETENTION", 30*24*time.Hour)
+
+// envDuration returns the environment variable key as a duration, or fallback
+// when it is not set or not a valid, non-negative duration
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value < 0 {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"crypto/rand"
+	"log"
+	"time"
+)
+
+// UnverifiedPolicy decides what users who have not verified their email yet
+// are allowed to do
+type UnverifiedPolicy string
+
+const (
+	// UnverifiedAllow lets unverified users do everything verified users can
+	UnverifiedAllow UnverifiedPolicy = "allow"
+	// UnverifiedReadOnly lets unverified users log in and read, but refuses
+	// their requests that change anything
+	UnverifiedReadOnly UnverifiedPolicy = "read_only"
+	// UnverifiedNoLogin refuses to log unverified users in
+	UnverifiedNoLogin UnverifiedPolicy = "no_login"
+)
+
+// Email verification settings. They are read from the environment at startup
+// and can be changed before serving requests.
+var (
+	// UnverifiedUsers is the policy for users who have not verified their
+	// email, TASKS_UNVERIFIED_USERS as "allow", "read_only" (the default) or
+	// "no_login"
+	UnverifiedUsers = envUnverifiedPolicy("TASKS_UNVERIFIED_USERS", UnverifiedReadOnly)
+	// EmailVerificationSecret signs the links in verification emails,
+	// TASKS_EMAIL_VERIFICATION_SECRET. Without it a random secret is used and
+	// links sent before a restart stop working.
+	EmailVerificationSecret = envSecret("TASKS_EMAIL_VERIFICATION_SECRET")
+	// EmailVerificationResendInterval is how long a user has to wait before
+	// another verification email is sent, TASKS_EMAIL_VERIFICATION_RESEND_INTERVAL
+	// (default 1m)
+	EmailVerificationResendInterval = envDuration("TASKS_EMAIL_VERIFICATION_RESEND_INTERVAL", time.Minute)
+)
+
+// envUnverifiedPolicy returns the environment variable key as a policy for
+// unverified users, or fallback when it is not set or not a known policy
+func envUnverifiedPolicy(key string, fallback UnverifiedPolicy) UnverifiedPolicy {
+	switch policy := UnverifiedPolicy(envString(key, "")); policy {
+	case UnverifiedAllow, UnverifiedReadOnly, UnverifiedNoLogin:
+		return policy
+	}
+	return fallback
+}
+
+// envSecret returns the environment variable key as a signing secret, or 32
+// random bytes when it is not set
+func envSecret(key string) []byte {
+	if value := envString(key, ""); value != "" {
+		return []byte(value)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatalf("Failed to generate %s: %v", key, err)
+	}
+	return secret
+}
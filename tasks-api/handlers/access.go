@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/models"
+)
+
+// roleRanks orders the roles from least to most privileged
+var roleRanks = map[string]int{
+	models.RoleViewer: 1,
+	models.RoleEditor: 2,
+	models.RoleOwner:  3,
+}
+
+// rankRoles maps a rank back to its role
+var rankRoles = map[int]string{
+	1: models.RoleViewer,
+	2: models.RoleEditor,
+	3: models.RoleOwner,
+}
+
+// shareRankSQL ranks a share role column the same way as roleRanks
+const shareRankSQL = "CASE role WHEN 'editor' THEN 2 ELSE 1 END"
+
+// taskRankSQL computes the rank of the role a user has on a task. Inside the
+// current workspace the creator is the owner and other members get the rank
+// of their workspace role; elsewhere it is the highest role granted by a share
+// of the task or of its project. It is 0 without access and takes the
+// workspace ID, the user ID, the workspace rank and the user ID twice more.
+const taskRankSQL = `CASE WHEN tasks.workspace_id = ? THEN (CASE WHEN tasks.user_id = ? THEN 3 ELSE ? END) ELSE MAX(
+	COALESCE((SELECT ` + shareRankSQL + ` FROM task_shares WHERE task_shares.task_id = tasks.id AND task_shares.user_id = ?), 0),
+	COALESCE((SELECT ` + shareRankSQL + ` FROM project_shares WHERE project_shares.project_id = tasks.project_id AND project_shares.user_id = ?), 0)
+) END`
+
+// projectRankSQL computes the rank of the role a user has on a project the
+// same way as taskRankSQL. It takes the workspace ID, the user ID, the
+// workspace rank and the user ID again.
+const projectRankSQL = `CASE WHEN projects.workspace_id = ? THEN (CASE WHEN projects.user_id = ? THEN 3 ELSE ? END) ELSE COALESCE(
+	(SELECT ` + shareRankSQL + ` FROM project_shares WHERE project_shares.project_id = projects.id AND project_shares.user_id = ?), 0
+) END`
+
+// workspaceRank is the rank workspace members have on tasks and projects they
+// did not create: admins and owners manage everything, members can edit
+func workspaceRank(membership models.WorkspaceMembership) int {
+	if membership.Role == models.WorkspaceRoleMember {
+		return roleRanks[models.RoleEditor]
+	}
+	return roleRanks[models.RoleOwner]
+}
+
+// hasRole reports whether role grants at least the permissions of minRole
+func hasRole(role, minRole string) bool {
+	return roleRanks[role] >= roleRanks[minRole]
+}
+
+// getTaskForUser fetches a task of the current workspace or one shared with
+// the user, along with the user's role on it. trashed picks tasks in the trash
+// instead of the others. It returns sql.ErrNoRows when the user has no access.
+func getTaskForUser(q querier, membership models.WorkspaceMembership, taskID any, trashed bool) (models.Task, string, error) {
+	state := "deleted_at IS NULL"
+	if trashed {
+		state = "deleted_at IS NOT NULL"
+	}
+
+	var rank int
+	task, err := scanTask(
+		q.QueryRow(
+			"SELECT "+taskColumns+", "+taskRankSQL+" FROM tasks WHERE id = ? AND "+state,
+			membership.WorkspaceID, membership.UserID, workspaceRank(membership), membership.UserID, membership.UserID,
+			taskID,
+		),
+		&rank,
+	)
+	if err != nil {
+		return task, "", err
+	}
+
+	if rank == 0 {
+		return task, "", sql.ErrNoRows
+	}
+	return task, rankRoles[rank], nil
+}
+
+// getProjectForUser fetches a project of the current workspace or one shared
+// with the user, along with the user's role on it. It returns sql.ErrNoRows
+// when the user has no access.
+func getProjectForUser(q querier, membership models.WorkspaceMembership, projectID any) (models.ProjectResponse, string, error) {
+	var rank int
+	project, err := scanProject(
+		q.QueryRow(
+			"SELECT "+projectColumns+", "+projectRankSQL+" FROM projects WHERE id = ?",
+			membership.WorkspaceID, membership.UserID, workspaceRank(membership), membership.UserID,
+			projectID,
+		),
+		&rank,
+	)
+	if err != nil {
+		return project, "", err
+	}
+
+	if rank == 0 {
+		return project, "", sql.ErrNoRows
+	}
+	return project, rankRoles[rank], nil
+}
+
+// authorizeTask fetches a task and checks that the user has at least minRole
+// on it. It writes a 404 when the user cannot see the task and a 403 when the
+// role is not enough, and returns false in both cases. Tasks in the trash are
+// not found.
+func authorizeTask(w http.ResponseWriter, q querier, membership models.WorkspaceMembership, taskID any, minRole string) (models.Task, bool) {
+	return authorizeTaskState(w, q, membership, taskID, minRole, false)
+}
+
+// authorizeTrashedTask is authorizeTask for tasks in the trash
+func authorizeTrashedTask(w http.ResponseWriter, q querier, membership models.WorkspaceMembership, taskID any, minRole string) (models.Task, bool) {
+	return authorizeTaskState(w, q, membership, taskID, minRole, true)
+}
+
+// authorizeTaskState implements authorizeTask and authorizeTrashedTask
+func authorizeTaskState(w http.ResponseWriter, q querier, membership models.WorkspaceMembership, taskID any, minRole string, trashed bool) (models.Task, bool) {
+	task, role, err := getTaskForUser(q, membership, taskID, trashed)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			config.WriteErrorResponse(w, http.StatusNotFound, "Task not found", nil)
+			return task, false
+		}
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch task", err)
+		return task, false
+	}
+
+	if !hasRole(role, minRole) {
+		config.WriteErrorResponse(w, http.StatusForbidden, "You need the "+minRole+" role on this task", nil)
+		return task, false
+	}
+	return task, true
+}
+
+// authorizeProject fetches a project and checks that the user has at least
+// minRole on it, writing a 404 or 403 the same way as authorizeTask
+func authorizeProject(w http.ResponseWriter, q querier, membership models.WorkspaceMembership, projectID any, minRole string) (models.ProjectResponse, bool) {
+	project, role, err := getProjectForUser(q, membership, projectID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			config.WriteErrorResponse(w, http.StatusNotFound, "Project not found", nil)
+			return project, false
+		}
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch project", err)
+		return project, false
+	}
+
+	if !hasRole(role, minRole) {
+		config.WriteErrorResponse(w, http.StatusForbidden, "You need the "+minRole+" role on this project", nil)
+		return project, false
+	}
+	return project, true
+}
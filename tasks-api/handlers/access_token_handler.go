@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/go-playground/validator/v10"
+)
+
+// accessTokenColumns lists the personal access token columns in the order
+// scanAccessToken expects them
+const accessTokenColumns = "id, name, scopes, created_at, expires_at, last_used_at"
+
+// GetAccessTokens lists the personal access tokens of the authenticated
+// user, newest first: GET /api/v1/tokens
+func GetAccessTokens(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	rows, err := config.DB.Query("SELECT "+accessTokenColumns+" FROM personal_access_tokens WHERE user_id = ? ORDER BY id DESC", userID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch tokens", err)
+		return
+	}
+
+	defer rows.Close()
+
+	tokens := []models.AccessTokenResponse{}
+	for rows.Next() {
+		token, err := scanAccessToken(rows)
+		if err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to scan token", err)
+			return
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch tokens", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Tokens retrieved successfully", tokens)
+}
+
+// CreateAccessToken creates a personal access token for the authenticated
+// user: POST /api/v1/tokens. Only the hash of the token is stored, so the
+// response is the only time it is shown.
+func CreateAccessToken(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	// Check if request body is empty
+	if r.ContentLength == 0 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Request body is required", nil)
+		return
+	}
+
+	var req models.CreateAccessTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	// Validate input
+	req.Name = strings.TrimSpace(req.Name)
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Validation failed", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		config.WriteValidationErrorResponse(w, map[string]string{"expires_at": "expires_at must be in the future"})
+		return
+	}
+
+	// Store every scope once, in a stable order
+	seen := map[string]bool{}
+	var scopes []string
+	for _, scope := range req.Scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	sort.Strings(scopes)
+
+	secret, err := generateToken()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to generate token", err)
+		return
+	}
+	token := models.AccessTokenPrefix + secret
+
+	result, err := config.DB.Exec(
+		"INSERT INTO personal_access_tokens (user_id, name, token_hash, scopes, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)",
+		userID,
+		req.Name,
+		config.HashToken(token),
+		strings.Join(scopes, " "),
+		sqlTime(&now),
+		sqlTime(req.ExpiresAt),
+	)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create token", err)
+		return
+	}
+
+	// Get the last inserted ID
+	lastID, err := result.LastInsertId()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get token ID", err)
+		return
+	}
+
+	created, err := scanAccessToken(config.DB.QueryRow("SELECT "+accessTokenColumns+" FROM personal_access_tokens WHERE id = ?", lastID))
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch created token", err)
+		return
+	}
+
+	config.WriteCreatedResponse(w, "Token created successfully. Copy it now, it will not be shown again", models.CreatedAccessTokenResponse{
+		AccessTokenResponse: created,
+		Token:               token,
+	})
+}
+
+// DeleteAccessToken revokes a personal access token of the authenticated
+// user: DELETE /api/v1/tokens/{id}
+func DeleteAccessToken(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	// Get token ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/tokens/")
+	if len(segments) != 1 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Token ID is required", nil)
+		return
+	}
+
+	tokenID, err := strconv.Atoi(segments[0])
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid token ID", err)
+		return
+	}
+
+	result, err := config.DB.Exec("DELETE FROM personal_access_tokens WHERE id = ? AND user_id = ?", tokenID, userID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to revoke token", err)
+		return
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to revoke token", err)
+		return
+	}
+
+	if affected == 0 {
+		config.WriteErrorResponse(w, http.StatusNotFound, "Token not found", nil)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Token revoked successfully", nil)
+}
+
+// revokeAccessTokens deletes every personal access token of the user, for
+// when whoever created them may have known a password that is no longer
+// trusted. It returns the number of tokens that were deleted.
+func revokeAccessTokens(q querier, userID int) (int64, error) {
+	result, err := q.Exec("DELETE FROM personal_access_tokens WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// PurgeExpiredAccessTokens deletes the personal access tokens that have expired
+func PurgeExpiredAccessTokens() (int64, error) {
+	now := time.Now().UTC()
+	result, err := config.DB.Exec("DELETE FROM personal_access_tokens WHERE expires_at IS NOT NULL AND expires_at <= ?", sqlTime(&now))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// scanAccessToken scans a row selected with accessTokenColumns
+func scanAccessToken(row rowScanner) (models.AccessTokenResponse, error) {
+	var token models.AccessTokenResponse
+	var scopes string
+	err := row.Scan(&token.ID, &token.Name, &scopes, &token.CreatedAt, &token.ExpiresAt, &token.LastUsedAt)
+	token.Scopes = strings.Fields(scopes)
+	return token, err
+}
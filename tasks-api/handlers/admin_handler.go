@@ -0,0 +1,681 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	defaultAdminLimit = 50
+	maxAdminLimit     = 100
+)
+
+// adminUserColumns lists the user columns in the order scanAdminUser expects them
+const adminUserColumns = "id, name, email, role, email_verified_at, timezone, created_at, disabled_at"
+
+// GetAdminUsers lists the users of the instance, newest first, one page at a
+// time: GET /api/v1/admin/users?q=&role=&status=&limit=&cursor=. q matches
+// the name or email, and status is one of active, disabled or unverified.
+func GetAdminUsers(w http.ResponseWriter, r *http.Request) {
+	// Get admin from context
+	admin, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User not found in context", fmt.Errorf("user not found in context"))
+		return
+	}
+
+	// Validate filter and pagination parameters
+	values := r.URL.Query()
+	errs := map[string]string{}
+
+	limit, cursorID := parseAdminPage(values.Get("limit"), values.Get("cursor"), decodeUserCursor, errs)
+
+	var conditions []string
+	var args []any
+	if q := strings.TrimSpace(values.Get("q")); q != "" {
+		// Escaped so % and _ in the search term match themselves
+		pattern := "%" + escapeLike(q) + "%"
+		conditions = append(conditions, `(name LIKE ? ESCAPE '\' OR email LIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern)
+	}
+
+	switch role := values.Get("role"); role {
+	case "":
+	case models.UserRoleUser, models.UserRoleAdmin:
+		conditions = append(conditions, "role = ?")
+		args = append(args, role)
+	default:
+		errs["role"] = "role must be one of user, admin"
+	}
+
+	switch values.Get("status") {
+	case "":
+	case "active":
+		conditions = append(conditions, "disabled_at IS NULL")
+	case "disabled":
+		conditions = append(conditions, "disabled_at IS NOT NULL")
+	case "unverified":
+		conditions = append(conditions, "email_verified_at IS NULL")
+	default:
+		errs["status"] = "status must be one of active, disabled, unverified"
+	}
+
+	if len(errs) > 0 {
+		config.WriteValidationErrorResponse(w, errs)
+		return
+	}
+
+	if cursorID > 0 {
+		conditions = append(conditions, "id < ?")
+		args = append(args, cursorID)
+	}
+
+	query := "SELECT " + adminUserColumns + " FROM users"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// Fetch one extra user to know whether there is a next page
+	rows, err := config.DB.Query(query+" ORDER BY id DESC LIMIT ?", append(args, limit+1)...)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch users", err)
+		return
+	}
+
+	users := []models.AdminUserResponse{}
+	hasMore := false
+	for rows.Next() {
+		user, err := scanAdminUser(rows)
+		if err != nil {
+			rows.Close()
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to scan user", err)
+			return
+		}
+		if len(users) == limit {
+			hasMore = true
+			break
+		}
+		users = append(users, user)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch users", err)
+		return
+	}
+
+	// Add the sessions once the users have been read
+	for i := range users {
+		if err := loadSessionActivity(config.DB, &users[i]); err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch sessions", err)
+			return
+		}
+	}
+
+	details := map[string]any{"count": len(users)}
+	for _, name := range []string{"q", "role", "status", "cursor"} {
+		if value := values.Get(name); value != "" {
+			details[name] = value
+		}
+	}
+	if err := recordAdminAction(config.DB, r, admin, models.AdminActionListUsers, nil, details); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to record admin action", err)
+		return
+	}
+
+	// Only hand out a cursor when more users are available
+	nextCursor := ""
+	if hasMore {
+		nextCursor = encodeUserCursor(users[len(users)-1].ID)
+	}
+
+	config.WritePaginatedResponse(w, "Users retrieved successfully", users, limit, nextCursor)
+}
+
+// GetAdminUser retrieves a single user: GET /api/v1/admin/users/{id}
+func GetAdminUser(w http.ResponseWriter, r *http.Request) {
+	// Get admin from context
+	admin, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User not found in context", fmt.Errorf("user not found in context"))
+		return
+	}
+
+	// Get user ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/admin/users/")
+	if len(segments) != 1 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "User ID is required", nil)
+		return
+	}
+
+	user, ok := findAdminUser(w, segments[0])
+	if !ok {
+		return
+	}
+
+	if err := recordAdminAction(config.DB, r, admin, models.AdminActionViewUser, &user.ID, nil); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to record admin action", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "User retrieved successfully", user)
+}
+
+// DisableUser disables an account, logs it out of every session and revokes
+// its personal access tokens: POST /api/v1/admin/users/{id}/disable. Disabled
+// users cannot log in until an admin enables the account again.
+func DisableUser(w http.ResponseWriter, r *http.Request) {
+	// Get admin from context
+	admin, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User not found in context", fmt.Errorf("user not found in context"))
+		return
+	}
+
+	// Get user ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/admin/users/")
+	if len(segments) != 2 || segments[1] != "disable" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "User ID is required", nil)
+		return
+	}
+
+	// The reason is optional, so the body may be empty
+	var req models.DisableUserRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+			return
+		}
+
+		// Validate input
+		validate := validator.New()
+		if err := validate.Struct(req); err != nil {
+			config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Validation failed", err)
+			return
+		}
+	}
+
+	user, ok := findAdminUser(w, segments[0])
+	if !ok {
+		return
+	}
+
+	// Admins would lock themselves out
+	if user.ID == admin.ID {
+		config.WriteErrorResponse(w, http.StatusConflict, "You cannot disable your own account", nil)
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to disable user", err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Disabling a disabled account keeps the original time
+	now := time.Now().UTC()
+	if user.DisabledAt == nil {
+		if _, err := tx.Exec("UPDATE users SET disabled_at = ? WHERE id = ?", sqlTime(&now), user.ID); err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to disable user", err)
+			return
+		}
+		user.DisabledAt = &now
+	}
+
+	revoked, err := revokeAllSessions(tx, user.ID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to revoke sessions", err)
+		return
+	}
+
+	revokedTokens, err := revokeAccessTokens(tx, user.ID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to revoke access tokens", err)
+		return
+	}
+
+	details := map[string]any{"revoked_sessions": revoked, "revoked_access_tokens": revokedTokens}
+	if req.Reason != "" {
+		details["reason"] = req.Reason
+	}
+	if err := recordAdminAction(tx, r, admin, models.AdminActionDisableUser, &user.ID, details); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to record admin action", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to disable user", err)
+		return
+	}
+
+	user.ActiveSessions = 0
+	config.WriteSuccessResponse(w, "User disabled successfully", user)
+}
+
+// EnableUser enables a disabled account again:
+// POST /api/v1/admin/users/{id}/enable
+func EnableUser(w http.ResponseWriter, r *http.Request) {
+	// Get admin from context
+	admin, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User not found in context", fmt.Errorf("user not found in context"))
+		return
+	}
+
+	// Get user ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/admin/users/")
+	if len(segments) != 2 || segments[1] != "enable" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "User ID is required", nil)
+		return
+	}
+
+	user, ok := findAdminUser(w, segments[0])
+	if !ok {
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to enable user", err)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE users SET disabled_at = NULL WHERE id = ?", user.ID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to enable user", err)
+		return
+	}
+
+	if err := recordAdminAction(tx, r, admin, models.AdminActionEnableUser, &user.ID, map[string]any{"was_disabled": user.DisabledAt != nil}); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to record admin action", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to enable user", err)
+		return
+	}
+
+	user.DisabledAt = nil
+	config.WriteSuccessResponse(w, "User enabled successfully", user)
+}
+
+// LogoutUser revokes every session, refresh token and personal access token
+// of a user: POST /api/v1/admin/users/{id}/logout
+func LogoutUser(w http.ResponseWriter, r *http.Request) {
+	// Get admin from context
+	admin, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User not found in context", fmt.Errorf("user not found in context"))
+		return
+	}
+
+	// Get user ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/admin/users/")
+	if len(segments) != 2 || segments[1] != "logout" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "User ID is required", nil)
+		return
+	}
+
+	user, ok := findAdminUser(w, segments[0])
+	if !ok {
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to revoke sessions", err)
+		return
+	}
+	defer tx.Rollback()
+
+	revoked, err := revokeAllSessions(tx, user.ID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to revoke sessions", err)
+		return
+	}
+
+	revokedTokens, err := revokeAccessTokens(tx, user.ID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to revoke access tokens", err)
+		return
+	}
+
+	details := map[string]any{"revoked_sessions": revoked, "revoked_access_tokens": revokedTokens}
+	if err := recordAdminAction(tx, r, admin, models.AdminActionLogoutUser, &user.ID, details); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to record admin action", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to revoke sessions", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "User logged out of all sessions", map[string]int64{"revoked": revoked, "revoked_access_tokens": revokedTokens})
+}
+
+// GetAdminStats counts what is stored on the instance: GET /api/v1/admin/stats
+func GetAdminStats(w http.ResponseWriter, r *http.Request) {
+	// Get admin from context
+	admin, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User not found in context", fmt.Errorf("user not found in context"))
+		return
+	}
+
+	var stats models.AdminStatsResponse
+	counts := []struct {
+		query string
+		args  []any
+		dest  any
+	}{
+		{"SELECT COUNT(*) FROM users", nil, &stats.Users.Total},
+		{"SELECT COUNT(*) FROM users WHERE role = ?", []any{models.UserRoleAdmin}, &stats.Users.Admins},
+		{"SELECT COUNT(*) FROM users WHERE disabled_at IS NOT NULL", nil, &stats.Users.Disabled},
+		{"SELECT COUNT(*) FROM users WHERE email_verified_at IS NULL", nil, &stats.Users.Unverified},
+		{"SELECT COUNT(*) FROM sessions WHERE expires_at > ?", []any{time.Now().UTC()}, &stats.ActiveSessions},
+		{"SELECT COUNT(*) FROM workspaces", nil, &stats.Workspaces},
+		{"SELECT COUNT(*) FROM projects", nil, &stats.Projects},
+		{"SELECT COUNT(*) FROM tasks", nil, &stats.Tasks.Total},
+		{"SELECT COUNT(*) FROM tasks WHERE completed = TRUE", nil, &stats.Tasks.Completed},
+		{"SELECT COUNT(*) FROM tasks WHERE deleted_at IS NOT NULL", nil, &stats.Tasks.Trashed},
+		{"SELECT COUNT(*) FROM task_comments", nil, &stats.Comments},
+		{"SELECT COUNT(*) FROM task_attachments", nil, &stats.Attachments},
+		{"SELECT COALESCE(SUM(size), 0) FROM task_attachments", nil, &stats.AttachmentBytes},
+	}
+	for _, count := range counts {
+		if err := config.DB.QueryRow(count.query, count.args...).Scan(count.dest); err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to count records", err)
+			return
+		}
+	}
+
+	if err := recordAdminAction(config.DB, r, admin, models.AdminActionViewStats, nil, nil); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to record admin action", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Stats retrieved successfully", stats)
+}
+
+// GetAdminAudit lists the actions admins took, newest first, one page at a
+// time: GET /api/v1/admin/audit?user_id=&limit=&cursor=. user_id only lists
+// the actions taken on that user.
+func GetAdminAudit(w http.ResponseWriter, r *http.Request) {
+	// Get admin from context
+	admin, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User not found in context", fmt.Errorf("user not found in context"))
+		return
+	}
+
+	// Validate filter and pagination parameters
+	values := r.URL.Query()
+	errs := map[string]string{}
+
+	limit, cursorID := parseAdminPage(values.Get("limit"), values.Get("cursor"), decodeEventCursor, errs)
+
+	var conditions []string
+	var args []any
+	if raw := values.Get("user_id"); raw != "" {
+		targetID, err := strconv.Atoi(raw)
+		if err != nil {
+			errs["user_id"] = "user_id must be a number"
+		} else {
+			conditions = append(conditions, "target_user_id = ?")
+			args = append(args, targetID)
+		}
+	}
+
+	if len(errs) > 0 {
+		config.WriteValidationErrorResponse(w, errs)
+		return
+	}
+
+	if cursorID > 0 {
+		conditions = append(conditions, "id < ?")
+		args = append(args, cursorID)
+	}
+
+	query := "SELECT id, admin_id, admin_email, action, target_user_id, details, ip_address, created_at FROM admin_audit_log"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// Fetch one extra entry to know whether there is a next page
+	rows, err := config.DB.Query(query+" ORDER BY id DESC LIMIT ?", append(args, limit+1)...)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch audit log", err)
+		return
+	}
+
+	entries := []models.AdminAuditEntry{}
+	hasMore := false
+	for rows.Next() {
+		var entry models.AdminAuditEntry
+		var details string
+		if err := rows.Scan(&entry.ID, &entry.AdminID, &entry.AdminEmail, &entry.Action, &entry.TargetUserID, &details, &entry.IPAddress, &entry.CreatedAt); err != nil {
+			rows.Close()
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to scan audit entry", err)
+			return
+		}
+		if err := json.Unmarshal([]byte(details), &entry.Details); err != nil {
+			rows.Close()
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to scan audit entry", err)
+			return
+		}
+		if len(entries) == limit {
+			hasMore = true
+			break
+		}
+		entries = append(entries, entry)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch audit log", err)
+		return
+	}
+
+	// Reading the audit log is an admin action too
+	details := map[string]any{"count": len(entries)}
+	if raw := values.Get("user_id"); raw != "" {
+		details["user_id"] = raw
+	}
+	if err := recordAdminAction(config.DB, r, admin, models.AdminActionViewAudit, nil, details); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to record admin action", err)
+		return
+	}
+
+	// Only hand out a cursor when more entries are available
+	nextCursor := ""
+	if hasMore {
+		nextCursor = encodeEventCursor(entries[len(entries)-1].ID)
+	}
+
+	config.WritePaginatedResponse(w, "Audit log retrieved successfully", entries, limit, nextCursor)
+}
+
+// PromoteAdmins gives the admin role to the users with the given emails. It
+// runs at startup with config.AdminEmails so an instance always has its admins.
+func PromoteAdmins(emails []string) (int64, error) {
+	var promoted int64
+	for _, email := range emails {
+		result, err := config.DB.Exec("UPDATE users SET role = ? WHERE email = ? AND role != ?", models.UserRoleAdmin, email, models.UserRoleAdmin)
+		if err != nil {
+			return promoted, err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return promoted, err
+		}
+		promoted += affected
+	}
+	return promoted, nil
+}
+
+// parseAdminPage reads the limit and cursor of an admin listing, adding
+// problems to errs. Each listing passes the decoder of its own cursor format.
+// A cursor of zero means the first page.
+func parseAdminPage(rawLimit, rawCursor string, decodeCursor func(string) (int, error), errs map[string]string) (int, int) {
+	limit := defaultAdminLimit
+	if rawLimit != "" {
+		parsed, err := strconv.Atoi(rawLimit)
+		if err != nil || parsed < 1 || parsed > maxAdminLimit {
+			errs["limit"] = fmt.Sprintf("limit must be a number between 1 and %d", maxAdminLimit)
+		} else {
+			limit = parsed
+		}
+	}
+
+	cursorID := 0
+	if rawCursor != "" {
+		parsed, err := decodeCursor(rawCursor)
+		if err != nil || parsed < 1 {
+			errs["cursor"] = "cursor is invalid"
+		} else {
+			cursorID = parsed
+		}
+	}
+
+	return limit, cursorID
+}
+
+// encodeUserCursor turns the ID of the last user on a page into the opaque
+// cursor of the admin user listing.
+func encodeUserCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("user:" + strconv.Itoa(id)))
+}
+
+func decodeUserCursor(raw string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return 0, err
+	}
+	id, ok := strings.CutPrefix(string(data), "user:")
+	if !ok {
+		return 0, fmt.Errorf("not a user cursor")
+	}
+	return strconv.Atoi(id)
+}
+
+// escapeLike escapes the LIKE wildcards in s for use with ESCAPE '\'.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// findAdminUser loads the user with the raw ID from the URL, with their
+// sessions. It writes the error response and returns false when there is none.
+func findAdminUser(w http.ResponseWriter, rawID string) (models.AdminUserResponse, bool) {
+	userID, err := strconv.Atoi(rawID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID", err)
+		return models.AdminUserResponse{}, false
+	}
+
+	user, err := scanAdminUser(config.DB.QueryRow("SELECT "+adminUserColumns+" FROM users WHERE id = ?", userID))
+	if err == sql.ErrNoRows {
+		config.WriteErrorResponse(w, http.StatusNotFound, "User not found", nil)
+		return user, false
+	}
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch user", err)
+		return user, false
+	}
+
+	if err := loadSessionActivity(config.DB, &user); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch sessions", err)
+		return user, false
+	}
+
+	return user, true
+}
+
+// scanAdminUser scans a row selected with adminUserColumns
+func scanAdminUser(row rowScanner) (models.AdminUserResponse, error) {
+	var user models.AdminUserResponse
+	var verifiedAt *time.Time
+	err := row.Scan(
+		&user.ID,
+		&user.Name,
+		&user.Email,
+		&user.Role,
+		&verifiedAt,
+		&user.Timezone,
+		&user.CreatedAt,
+		&user.DisabledAt,
+	)
+	user.EmailVerified = verifiedAt != nil
+	return user, err
+}
+
+// loadSessionActivity counts the active sessions of user and finds when one
+// of them was last used
+func loadSessionActivity(q querier, user *models.AdminUserResponse) error {
+	rows, err := q.Query("SELECT created_at, last_used_at FROM sessions WHERE user_id = ? AND expires_at > ?", user.ID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	user.ActiveSessions = 0
+	user.LastActiveAt = nil
+	for rows.Next() {
+		var createdAt time.Time
+		var lastUsedAt *time.Time
+		if err := rows.Scan(&createdAt, &lastUsedAt); err != nil {
+			return err
+		}
+
+		// Sessions that were never used were last active when they started
+		if lastUsedAt == nil {
+			lastUsedAt = &createdAt
+		}
+		if user.LastActiveAt == nil || lastUsedAt.After(*user.LastActiveAt) {
+			user.LastActiveAt = lastUsedAt
+		}
+		user.ActiveSessions++
+	}
+	return rows.Err()
+}
+
+// recordAdminAction adds an action admin took to the audit log. targetUserID
+// is nil for actions that are not about a single user.
+func recordAdminAction(q querier, r *http.Request, admin models.User, action string, targetUserID *int, details map[string]any) error {
+	if details == nil {
+		details = map[string]any{}
+	}
+	encoded, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	_, err = q.Exec(
+		"INSERT INTO admin_audit_log (admin_id, admin_email, action, target_user_id, details, ip_address, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		admin.ID,
+		admin.Email,
+		action,
+		targetUserID,
+		string(encoded),
+		middleware.ClientIP(r),
+		sqlTime(&now),
+	)
+	return err
+}
@@ -0,0 +1,443 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// attachmentColumns lists the attachment columns in the order scanAttachment expects them
+const attachmentColumns = "id, task_id, filename, content_type, size, sha256, user_id, created_at"
+
+// attachmentSniffLen is how much of a file is read to detect its MIME type
+const attachmentSniffLen = 3072
+
+// attachmentFilesMu serialises storing and removing attachment files, so a
+// file is not removed while an upload of the same content is referencing it
+var attachmentFilesMu sync.Mutex
+
+// GetTaskAttachments lists the attachments of a task:
+// GET /api/v1/tasks/{id}/attachments
+func GetTaskAttachments(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get task ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/tasks/")
+	if len(segments) != 2 || segments[1] != "attachments" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Task ID is required", nil)
+		return
+	}
+
+	task, ok := authorizeTask(w, config.DB, membership, segments[0], models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	rows, err := config.DB.Query("SELECT "+attachmentColumns+" FROM task_attachments WHERE task_id = ? ORDER BY created_at, id", task.ID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch attachments", err)
+		return
+	}
+
+	defer rows.Close()
+
+	attachments := []models.AttachmentResponse{}
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to scan attachment", err)
+			return
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	if err := rows.Err(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch attachments", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Attachments retrieved successfully", attachments)
+}
+
+// UploadTaskAttachment stores the "file" field of a multipart form as an
+// attachment of a task: POST /api/v1/tasks/{id}/attachments. The MIME type is
+// detected from the content and must be in config.AllowedAttachmentTypes.
+func UploadTaskAttachment(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get task ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/tasks/")
+	if len(segments) != 2 || segments[1] != "attachments" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Task ID is required", nil)
+		return
+	}
+
+	// Shared users need the editor role to add files
+	task, ok := authorizeTask(w, config.DB, membership, segments[0], models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	// Leave room for the multipart headers around the file
+	r.Body = http.MaxBytesReader(w, r.Body, config.MaxAttachmentSize+1<<20)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Request body must be multipart/form-data", err)
+		return
+	}
+
+	// Find the file field, skipping any other fields
+	var part io.Reader
+	var filename string
+	for {
+		p, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeUploadError(w, err)
+			return
+		}
+		if p.FormName() == "file" && p.FileName() != "" {
+			part, filename = p, p.FileName()
+			break
+		}
+	}
+
+	if part == nil {
+		config.WriteValidationErrorResponse(w, map[string]string{"file": "file is required"})
+		return
+	}
+
+	// Detect the MIME type from the start of the content
+	head := make([]byte, attachmentSniffLen)
+	n, err := io.ReadFull(part, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		writeUploadError(w, err)
+		return
+	}
+	head = head[:n]
+
+	detected := mimetype.Detect(head)
+	if !attachmentTypeAllowed(detected) {
+		config.WriteErrorResponse(w, http.StatusUnsupportedMediaType, "Files of type "+detected.String()+" are not allowed", nil)
+		return
+	}
+
+	temp, hash, size, err := receiveAttachmentFile(io.MultiReader(bytes.NewReader(head), part))
+	if err != nil {
+		writeUploadError(w, err)
+		return
+	}
+	defer os.Remove(temp)
+
+	// Keeping the file and inserting its row under the lock stops a removal of
+	// the same content from deleting the file in between
+	attachmentFilesMu.Lock()
+	defer attachmentFilesMu.Unlock()
+
+	if err := keepAttachmentFile(temp, hash); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to store attachment", err)
+		return
+	}
+
+	result, err := config.DB.Exec(
+		"INSERT INTO task_attachments (task_id, user_id, filename, content_type, size, sha256) VALUES (?, ?, ?, ?, ?, ?)",
+		task.ID,
+		membership.UserID,
+		attachmentFilename(filename),
+		detected.String(),
+		size,
+		hash,
+	)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create attachment", err)
+		return
+	}
+
+	// Get the last inserted ID
+	lastID, err := result.LastInsertId()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get attachment ID", err)
+		return
+	}
+
+	attachment, err := getAttachment(config.DB, task.ID, lastID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch created attachment", err)
+		return
+	}
+
+	config.WriteCreatedResponse(w, "Attachment uploaded successfully", attachment)
+}
+
+// DownloadTaskAttachment serves the content of an attachment:
+// GET /api/v1/tasks/{id}/attachments/{attachment_id}. Range requests are
+// supported.
+func DownloadTaskAttachment(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get task ID and attachment ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/tasks/")
+	if len(segments) != 3 || segments[1] != "attachments" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Task ID and attachment ID are required", nil)
+		return
+	}
+
+	attachment, ok := authorizeAttachment(w, membership, segments[0], segments[2], models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	file, err := os.Open(attachmentPath(attachment.SHA256))
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to open attachment", err)
+		return
+	}
+	defer file.Close()
+
+	// Always download rather than render, and never let browsers guess the type
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", `"`+attachment.SHA256+`"`)
+	http.ServeContent(w, r, attachment.Filename, attachment.CreatedAt, file)
+}
+
+// DeleteTaskAttachment deletes an attachment:
+// DELETE /api/v1/tasks/{id}/attachments/{attachment_id}
+func DeleteTaskAttachment(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get task ID and attachment ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/tasks/")
+	if len(segments) != 3 || segments[1] != "attachments" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Task ID and attachment ID are required", nil)
+		return
+	}
+
+	attachment, ok := authorizeAttachment(w, membership, segments[0], segments[2], models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	if _, err := config.DB.Exec("DELETE FROM task_attachments WHERE id = ?", attachment.ID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete attachment", err)
+		return
+	}
+
+	removeAttachmentFiles([]string{attachment.SHA256})
+
+	config.WriteSuccessResponse(w, "Attachment deleted successfully", nil)
+}
+
+// authorizeAttachment fetches an attachment of a task the user has at least
+// minRole on, writing a 404 when the task or attachment cannot be found
+func authorizeAttachment(w http.ResponseWriter, membership models.WorkspaceMembership, taskID, attachmentID string, minRole string) (models.AttachmentResponse, bool) {
+	task, ok := authorizeTask(w, config.DB, membership, taskID, minRole)
+	if !ok {
+		return models.AttachmentResponse{}, false
+	}
+
+	attachment, err := getAttachment(config.DB, task.ID, attachmentID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			config.WriteErrorResponse(w, http.StatusNotFound, "Attachment not found", nil)
+			return attachment, false
+		}
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch attachment", err)
+		return attachment, false
+	}
+	return attachment, true
+}
+
+// attachmentTypeAllowed reports whether a detected MIME type matches
+// config.AllowedAttachmentTypes
+func attachmentTypeAllowed(detected *mimetype.MIME) bool {
+	for _, allowed := range config.AllowedAttachmentTypes {
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok {
+			if strings.HasPrefix(detected.String(), prefix+"/") {
+				return true
+			}
+		} else if detected.Is(allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// attachmentFilename cleans the file name sent by the client
+func attachmentFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		return "attachment"
+	}
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	return name
+}
+
+// attachmentPath is where the file with the given SHA-256 is stored. Files are
+// named after their content, so identical uploads share one file.
+func attachmentPath(hash string) string {
+	return filepath.Join(config.AttachmentsDir, hash[:2], hash)
+}
+
+// receiveAttachmentFile writes content to a temporary file in the
+// attachments directory and returns its path, SHA-256 and size. It fails with
+// errAttachmentTooLarge when the content is larger than config.MaxAttachmentSize.
+func receiveAttachmentFile(content io.Reader) (string, string, int64, error) {
+	if err := os.MkdirAll(config.AttachmentsDir, 0o755); err != nil {
+		return "", "", 0, err
+	}
+
+	temp, err := os.CreateTemp(config.AttachmentsDir, "upload-*")
+	if err != nil {
+		return "", "", 0, err
+	}
+	defer temp.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(temp, hasher), io.LimitReader(content, config.MaxAttachmentSize+1))
+	if err == nil && size > config.MaxAttachmentSize {
+		err = errAttachmentTooLarge
+	}
+	if err == nil {
+		err = temp.Close()
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+		return "", "", 0, err
+	}
+
+	return temp.Name(), hex.EncodeToString(hasher.Sum(nil)), size, nil
+}
+
+// keepAttachmentFile moves a received file to its content-addressed path,
+// unless a file with the same content is already stored there. The caller
+// must hold attachmentFilesMu.
+func keepAttachmentFile(temp, hash string) error {
+	path := attachmentPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+// errAttachmentTooLarge is returned for files over config.MaxAttachmentSize
+var errAttachmentTooLarge = errors.New("attachment too large")
+
+// writeUploadError writes the response for an error reading an upload
+func writeUploadError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.Is(err, errAttachmentTooLarge) || errors.As(err, &maxBytesErr) {
+		config.WriteErrorResponse(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Files can be at most %d bytes", config.MaxAttachmentSize), nil)
+		return
+	}
+	config.WriteErrorResponse(w, http.StatusBadRequest, "Failed to read upload", err)
+}
+
+// taskAttachmentFiles returns the files attached to the tasks selected by
+// idQuery, so they can be removed with removeAttachmentFiles once the tasks
+// are deleted
+func taskAttachmentFiles(q querier, idQuery string, args ...any) ([]string, error) {
+	rows, err := q.Query("SELECT DISTINCT sha256 FROM task_attachments WHERE task_id IN ("+idQuery+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hashes := []string{}
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+// removeAttachmentFiles removes the files no attachment refers to anymore.
+// Failures are logged rather than returned since the rows are already gone.
+func removeAttachmentFiles(hashes []string) {
+	attachmentFilesMu.Lock()
+	defer attachmentFilesMu.Unlock()
+
+	for _, hash := range hashes {
+		var used bool
+		if err := config.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM task_attachments WHERE sha256 = ?)", hash).Scan(&used); err != nil {
+			log.Printf("Failed to check attachment %s: %v", hash, err)
+			continue
+		}
+		if used {
+			continue
+		}
+		if err := os.Remove(attachmentPath(hash)); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to remove attachment %s: %v", hash, err)
+		}
+	}
+}
+
+// scanAttachment scans a row selected with attachmentColumns
+func scanAttachment(row rowScanner) (models.AttachmentResponse, error) {
+	var attachment models.AttachmentResponse
+	err := row.Scan(
+		&attachment.ID,
+		&attachment.TaskID,
+		&attachment.Filename,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.SHA256,
+		&attachment.UploadedBy,
+		&attachment.CreatedAt,
+	)
+	return attachment, err
+}
+
+// getAttachment fetches an attachment of a task
+func getAttachment(q querier, taskID int, attachmentID any) (models.AttachmentResponse, error) {
+	return scanAttachment(q.QueryRow("SELECT "+attachmentColumns+" FROM task_attachments WHERE task_id = ? AND id = ?", taskID, attachmentID))
+}
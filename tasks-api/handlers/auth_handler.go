@@ -4,7 +4,9 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/eokwukwe/golearn/tasks/config"
@@ -13,10 +15,6 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-const (
-	SessionDuration = 24 * time.Hour // 24 hours
-)
-
 func Login(w http.ResponseWriter, r *http.Request) {
 	// Check that method is post
 	if r.Method != http.MethodPost {
@@ -43,75 +41,108 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Find user by email
+	// Refuse logins while the email is locked after failed attempts
+	email := loginEmail(req.Email)
+	wait, err := loginLockedFor(email)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to check login attempts", err)
+		return
+	}
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		config.WriteErrorResponse(w, http.StatusTooManyRequests, "Too many failed login attempts, try again later", nil)
+		return
+	}
+
+	// Find user by email; unknown emails are checked against a dummy hash so
+	// they fail the same way, and take as long, as a wrong password
 	var user models.User
-	err = config.DB.QueryRow("SELECT id, name, email, password, created_at FROM users WHERE email = ?", req.Email).Scan(
+	err = config.DB.QueryRow("SELECT id, name, email, password, created_at, email_verified_at, disabled_at FROM users WHERE email = ?", req.Email).Scan(
 		&user.ID,
 		&user.Name,
 		&user.Email,
 		&user.Password,
 		&user.CreatedAt,
+		&user.EmailVerifiedAt,
+		&user.DisabledAt,
 	)
+	passwordHash := []byte(user.Password)
 	if err != nil {
-		config.WriteErrorResponse(w, http.StatusUnauthorized, "Invalid credentials", nil)
-		return
+		passwordHash = dummyPasswordHash
 	}
 
 	// Verify password
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
-	if err != nil {
+	passwordErr := bcrypt.CompareHashAndPassword(passwordHash, []byte(req.Password))
+	if err != nil || passwordErr != nil {
+		if err := recordFailedLogin(email); err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to record login attempt", err)
+			return
+		}
 		config.WriteErrorResponse(w, http.StatusUnauthorized, "Invalid credentials", nil)
 		return
 	}
 
-	// Generate session token
-	token, err := generateToken()
+	if err := clearFailedLogins(email); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to record login attempt", err)
+		return
+	}
+
+	// Disabled accounts cannot log in, even with the right password
+	if user.DisabledAt != nil {
+		config.WriteErrorResponse(w, http.StatusForbidden, "Account is disabled", nil)
+		return
+	}
+
+	// Depending on the policy, users have to verify their email first
+	if user.EmailVerifiedAt == nil && config.UnverifiedUsers == config.UnverifiedNoLogin {
+		config.WriteErrorResponse(w, http.StatusForbidden, "Email address is not verified", nil)
+		return
+	}
+
+	// Start a new token family for this login
+	familyID, err := generateToken()
 	if err != nil {
 		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to generate token", err)
 		return
 	}
 
-	// Create new session
-	session := models.Session{
-		UserID:    user.ID,
-		Token:     token,
-		CreatedAt: time.Now(),
-		ExpiresAt: time.Now().Add(SessionDuration),
+	// Issue access and refresh tokens in a single transaction
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create session", err)
+		return
 	}
+	defer tx.Rollback()
 
-	// Insert session into database
-	result, err := config.DB.Exec(
-		"INSERT INTO sessions (user_id, token, created_at, expires_at) VALUES (?, ?, ?, ?)",
-		session.UserID,
-		session.Token,
-		session.CreatedAt,
-		session.ExpiresAt,
-	)
+	tokens, err := issueTokens(tx, user.ID, familyID, r)
 	if err != nil {
 		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create session", err)
 		return
 	}
 
-	// Get session ID
-	_, err = result.LastInsertId()
-	if err != nil {
-		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get session ID", err)
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create session", err)
 		return
 	}
 
 	// Prepare response
 	response := models.LoginResponse{
-		Token: token,
+		Token:                 tokens.Token,
+		ExpiresAt:             tokens.ExpiresAt,
+		RefreshToken:          tokens.RefreshToken,
+		RefreshTokenExpiresAt: tokens.RefreshTokenExpiresAt,
 		User: struct {
-			ID        int       `json:"id"`
-			Name      string    `json:"name"`
-			Email     string    `json:"email"`
-			CreatedAt time.Time `json:"created_at"`
+			ID            int       `json:"id"`
+			Name          string    `json:"name"`
+			Email         string    `json:"email"`
+			EmailVerified bool      `json:"email_verified"`
+			CreatedAt     time.Time `json:"created_at"`
 		}{
-			ID:        user.ID,
-			Name:      user.Name,
-			Email:     user.Email,
-			CreatedAt: user.CreatedAt,
+			ID:            user.ID,
+			Name:          user.Name,
+			Email:         user.Email,
+			EmailVerified: user.EmailVerifiedAt != nil,
+			CreatedAt:     user.CreatedAt,
 		},
 	}
 
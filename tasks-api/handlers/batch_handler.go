@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/go-playground/validator/v10"
+)
+
+// BatchTasks runs a list of task operations in a single transaction:
+// POST /api/v1/tasks/batch. In the default atomic mode the first failing
+// operation rolls the whole batch back; in continue mode only the failing
+// operations are undone.
+func BatchTasks(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Check if request body is empty
+	if r.ContentLength == 0 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Request body is required", nil)
+		return
+	}
+
+	// Parse request body, refusing bodies too large for a batch before they
+	// are read into memory
+	maxBodySize := int64(config.MaxBatchSize) * config.MaxBatchOperationSize
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+
+	var req models.BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			config.WriteErrorResponse(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Batch requests can be at most %d bytes", maxBodySize), nil)
+			return
+		}
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	// Validate input
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Validation failed", err)
+		return
+	}
+
+	if len(req.Operations) > config.MaxBatchSize {
+		config.WriteValidationErrorResponse(w, map[string]string{
+			"operations": "a batch can have at most " + strconv.Itoa(config.MaxBatchSize) + " operations",
+		})
+		return
+	}
+
+	if req.Mode == "" {
+		req.Mode = models.BatchModeAtomic
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to run batch", err)
+		return
+	}
+	defer tx.Rollback()
+
+	response := models.BatchResponse{Mode: req.Mode, Results: []models.BatchResult{}}
+	for i, op := range req.Operations {
+		// Failed operations of a continue batch are undone on their own
+		if req.Mode == models.BatchModeContinue {
+			if _, err := tx.Exec("SAVEPOINT batch_operation"); err != nil {
+				config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to run batch", err)
+				return
+			}
+		}
+
+		result := runBatchOperation(tx, membership, i, op)
+		response.Results = append(response.Results, result)
+
+		if result.Status < 400 {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+
+		if req.Mode == models.BatchModeAtomic && result.Status >= 400 {
+			break
+		}
+
+		if req.Mode == models.BatchModeContinue {
+			statement := "RELEASE batch_operation"
+			if result.Status >= 400 {
+				statement = "ROLLBACK TO batch_operation; RELEASE batch_operation"
+			}
+			if _, err := tx.Exec(statement); err != nil {
+				config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to run batch", err)
+				return
+			}
+		}
+	}
+
+	// An atomic batch with a failed operation changes nothing
+	if req.Mode == models.BatchModeAtomic && response.Failed > 0 {
+		resp := config.NewErrorResponse("Batch rolled back: operation "+strconv.Itoa(len(response.Results)-1)+" failed", nil)
+		resp.Data = response
+		config.WriteResponse(w, http.StatusUnprocessableEntity, resp)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to run batch", err)
+		return
+	}
+
+	response.Committed = true
+	config.WriteSuccessResponse(w, "Batch completed", response)
+}
+
+// runBatchOperation runs a single batch operation inside tx. The operations
+// share their helpers with the single task endpoints, which write errors to a
+// batchRecorder; the recorded response becomes the operation's result.
+func runBatchOperation(tx querier, membership models.WorkspaceMembership, index int, op models.BatchOperation) models.BatchResult {
+	result := models.BatchResult{Index: index, Op: op.Op}
+
+	rec := &batchRecorder{header: http.Header{}}
+	task, status, ok := applyBatchOperation(rec, tx, membership, op)
+	if !ok {
+		result.Status = rec.status
+		var resp config.Response
+		if err := json.Unmarshal(rec.body.Bytes(), &resp); err == nil {
+			result.Message = resp.Message
+			result.Errors = resp.Errors
+		}
+		return result
+	}
+
+	result.Status = status
+	if task != nil {
+		response, err := taskResponseWithDetails(tx, *task)
+		if err != nil {
+			result.Status = http.StatusInternalServerError
+			result.Message = "Failed to fetch task details"
+			return result
+		}
+		result.Task = &response
+	}
+	return result
+}
+
+// applyBatchOperation applies a single batch operation the way the matching
+// single task endpoint does. It returns the changed task, if any, with the
+// status of the operation, or writes the error response to w and returns false.
+func applyBatchOperation(w http.ResponseWriter, tx querier, membership models.WorkspaceMembership, op models.BatchOperation) (*models.Task, int, bool) {
+	switch op.Op {
+	case models.BatchOpCreate, models.BatchOpUpdate:
+		if op.Task == nil {
+			config.WriteValidationErrorResponse(w, map[string]string{"task": "task is required"})
+			return nil, 0, false
+		}
+
+		// Validate input
+		validate := validator.New()
+		if err := validate.Struct(op.Task); err != nil {
+			config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Validation failed", err)
+			return nil, 0, false
+		}
+
+		recurrence, errs := taskRecurrence(*op.Task)
+		if errs != nil {
+			config.WriteValidationErrorResponse(w, errs)
+			return nil, 0, false
+		}
+
+		if op.Op == models.BatchOpCreate {
+			task, ok := insertTask(w, tx, membership, *op.Task, recurrence)
+			return &task, http.StatusCreated, ok
+		}
+
+		// Shared users need the editor role
+		task, ok := authorizeTask(w, tx, membership, op.ID, models.RoleEditor)
+		if !ok {
+			return nil, 0, false
+		}
+
+		task, ok = saveTask(w, tx, membership.UserID, task, *op.Task, recurrence, nil)
+		return &task, http.StatusOK, ok
+
+	case models.BatchOpComplete, models.BatchOpReopen:
+		// Shared users need the editor role
+		task, ok := authorizeTask(w, tx, membership, op.ID, models.RoleEditor)
+		if !ok {
+			return nil, 0, false
+		}
+
+		// Recurring tasks repeat in the owner's timezone
+		loc, err := taskLocation(tx, task)
+		if err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load user timezone", err)
+			return nil, 0, false
+		}
+
+		task, _, ok = setTaskCompleted(w, tx, membership.UserID, task, op.Op == models.BatchOpComplete, nil, loc)
+		return &task, http.StatusOK, ok
+
+	case models.BatchOpDelete:
+		// Only the owner can delete a task
+		task, ok := authorizeTask(w, tx, membership, op.ID, models.RoleOwner)
+		if !ok {
+			return nil, 0, false
+		}
+
+		// Move the task to the trash like DeleteTask does
+		if _, err := trashTasks(tx, membership.UserID, "?", task.ID); err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete task", err)
+			return nil, 0, false
+		}
+		return nil, http.StatusOK, true
+	}
+
+	config.WriteValidationErrorResponse(w, map[string]string{"op": "op must be one of create update complete reopen delete"})
+	return nil, 0, false
+}
+
+// batchRecorder is the http.ResponseWriter batch operations write their
+// error responses to
+type batchRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *batchRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *batchRecorder) WriteHeader(status int) {
+	r.status = status
+}
+
+func (r *batchRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(data)
+}
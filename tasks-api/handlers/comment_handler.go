@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/go-playground/validator/v10"
+)
+
+// commentColumns lists the comment columns, with the author joined as users,
+// in the order scanComment expects them
+const commentColumns = "c.id, c.task_id, c.body, users.id, users.name, users.email, c.created_at, c.edited_at"
+
+// GetTaskComments lists the comments of a task, oldest first:
+// GET /api/v1/tasks/{id}/comments
+func GetTaskComments(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get task ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/tasks/")
+	if len(segments) != 2 || segments[1] != "comments" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Task ID is required", nil)
+		return
+	}
+
+	// Anyone who can see the task can read its comments
+	task, ok := authorizeTask(w, config.DB, membership, segments[0], models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	rows, err := config.DB.Query(
+		"SELECT "+commentColumns+" FROM task_comments c JOIN users ON users.id = c.user_id"+
+			" WHERE c.task_id = ? ORDER BY c.created_at, c.id",
+		task.ID,
+	)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch comments", err)
+		return
+	}
+
+	defer rows.Close()
+
+	comments := []models.CommentResponse{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to scan comment", err)
+			return
+		}
+		comments = append(comments, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch comments", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Comments retrieved successfully", comments)
+}
+
+// CreateTaskComment adds a comment to a task: POST /api/v1/tasks/{id}/comments.
+// Anyone who can see the task can comment on it.
+func CreateTaskComment(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get task ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/tasks/")
+	if len(segments) != 2 || segments[1] != "comments" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Task ID is required", nil)
+		return
+	}
+
+	req, ok := decodeCommentRequest(w, r)
+	if !ok {
+		return
+	}
+
+	task, ok := authorizeTask(w, config.DB, membership, segments[0], models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	result, err := config.DB.Exec(
+		"INSERT INTO task_comments (task_id, user_id, body) VALUES (?, ?, ?)",
+		task.ID,
+		membership.UserID,
+		req.Body,
+	)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create comment", err)
+		return
+	}
+
+	// Get the last inserted ID
+	lastID, err := result.LastInsertId()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get comment ID", err)
+		return
+	}
+
+	comment, err := getComment(config.DB, task.ID, lastID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch created comment", err)
+		return
+	}
+
+	config.WriteCreatedResponse(w, "Comment created successfully", comment)
+}
+
+// UpdateTaskComment edits the body of a comment and records edited_at:
+// PUT /api/v1/tasks/{id}/comments/{comment_id}. Only the author can edit it.
+func UpdateTaskComment(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get task ID and comment ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/tasks/")
+	if len(segments) != 3 || segments[1] != "comments" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Task ID and comment ID are required", nil)
+		return
+	}
+
+	req, ok := decodeCommentRequest(w, r)
+	if !ok {
+		return
+	}
+
+	comment, ok := authorizeComment(w, membership, segments[0], segments[2])
+	if !ok {
+		return
+	}
+
+	if _, err := config.DB.Exec(
+		"UPDATE task_comments SET body = ?, edited_at = CURRENT_TIMESTAMP WHERE id = ?",
+		req.Body,
+		comment.ID,
+	); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update comment", err)
+		return
+	}
+
+	comment, err := getComment(config.DB, comment.TaskID, comment.ID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch updated comment", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Comment updated successfully", comment)
+}
+
+// DeleteTaskComment deletes a comment: DELETE /api/v1/tasks/{id}/comments/{comment_id}.
+// Only the author can delete it.
+func DeleteTaskComment(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get task ID and comment ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/tasks/")
+	if len(segments) != 3 || segments[1] != "comments" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Task ID and comment ID are required", nil)
+		return
+	}
+
+	comment, ok := authorizeComment(w, membership, segments[0], segments[2])
+	if !ok {
+		return
+	}
+
+	if _, err := config.DB.Exec("DELETE FROM task_comments WHERE id = ?", comment.ID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete comment", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Comment deleted successfully", nil)
+}
+
+// authorizeComment fetches a comment of a task the user can see and checks
+// that the user wrote it. It writes a 404 when the task or comment cannot be
+// found and a 403 for someone else's comment, and returns false in both cases.
+func authorizeComment(w http.ResponseWriter, membership models.WorkspaceMembership, taskID, commentID string) (models.CommentResponse, bool) {
+	task, ok := authorizeTask(w, config.DB, membership, taskID, models.RoleViewer)
+	if !ok {
+		return models.CommentResponse{}, false
+	}
+
+	comment, err := getComment(config.DB, task.ID, commentID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			config.WriteErrorResponse(w, http.StatusNotFound, "Comment not found", nil)
+			return comment, false
+		}
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch comment", err)
+		return comment, false
+	}
+
+	if comment.Author.ID != membership.UserID {
+		config.WriteErrorResponse(w, http.StatusForbidden, "You can only change your own comments", nil)
+		return comment, false
+	}
+	return comment, true
+}
+
+// decodeCommentRequest parses and validates a comment request body, writing
+// the error response when it is invalid
+func decodeCommentRequest(w http.ResponseWriter, r *http.Request) (models.CommentRequest, bool) {
+	var req models.CommentRequest
+
+	// Check if request body is empty
+	if r.ContentLength == 0 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Request body is required", nil)
+		return req, false
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return req, false
+	}
+
+	// Validate input
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Validation failed", err)
+		return req, false
+	}
+
+	if strings.TrimSpace(req.Body) == "" {
+		config.WriteValidationErrorResponse(w, map[string]string{"body": "body cannot be blank"})
+		return req, false
+	}
+
+	return req, true
+}
+
+// scanComment scans a row selected with commentColumns
+func scanComment(row rowScanner) (models.CommentResponse, error) {
+	var comment models.CommentResponse
+	err := row.Scan(
+		&comment.ID,
+		&comment.TaskID,
+		&comment.Body,
+		&comment.Author.ID,
+		&comment.Author.Name,
+		&comment.Author.Email,
+		&comment.CreatedAt,
+		&comment.EditedAt,
+	)
+	return comment, err
+}
+
+// getComment fetches a comment of a task with its author
+func getComment(q querier, taskID int, commentID any) (models.CommentResponse, error) {
+	return scanComment(q.QueryRow(
+		"SELECT "+commentColumns+" FROM task_comments c JOIN users ON users.id = c.user_id WHERE c.task_id = ? AND c.id = ?",
+		taskID,
+		commentID,
+	))
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/go-playground/validator/v10"
+)
+
+// GetTaskDependencies lists the tasks blocking a task and the tasks it blocks:
+// GET /api/v1/tasks/{id}/dependencies
+func GetTaskDependencies(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get task ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/tasks/")
+	if len(segments) != 2 || segments[1] != "dependencies" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Task ID is required", nil)
+		return
+	}
+
+	// Shared users need at least the viewer role
+	task, ok := authorizeTask(w, config.DB, membership, segments[0], models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	blockedBy, err := dependencyTasks(task.WorkspaceID, "SELECT blocked_by_id FROM task_dependencies WHERE task_id = ?", task.ID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch dependencies", err)
+		return
+	}
+
+	blocking, err := dependencyTasks(task.WorkspaceID, "SELECT task_id FROM task_dependencies WHERE blocked_by_id = ?", task.ID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch dependencies", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Dependencies retrieved successfully", models.TaskDependenciesResponse{
+		BlockedBy: blockedBy,
+		Blocking:  blocking,
+	})
+}
+
+// AddTaskDependency marks a task as blocked by another task:
+// POST /api/v1/tasks/{id}/dependencies with {"blocked_by_id": 12}.
+// Edges that would create a cycle are rejected.
+func AddTaskDependency(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get task ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/tasks/")
+	if len(segments) != 2 || segments[1] != "dependencies" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Task ID is required", nil)
+		return
+	}
+
+	// Check if request body is empty
+	if r.ContentLength == 0 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Request body is required", nil)
+		return
+	}
+
+	// Parse request body
+	var req models.DependencyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	// Validate input
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Validation failed", err)
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to add dependency", err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Shared users need the editor role, and both tasks must be in the same workspace
+	task, ok := authorizeTask(w, tx, membership, segments[0], models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	blocker, err := getTask(tx, task.WorkspaceID, req.BlockedByID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			config.WriteValidationErrorResponse(w, map[string]string{"blocked_by_id": "blocked_by_id must be a task of the same workspace"})
+			return
+		}
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch task", err)
+		return
+	}
+
+	// Reject the edge when the blocker already depends on the task
+	cycle, err := dependencyCreatesCycle(tx, task.ID, blocker.ID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to check dependencies", err)
+		return
+	}
+
+	if cycle {
+		config.WriteErrorResponse(w, http.StatusConflict, "Dependency would create a cycle", nil)
+		return
+	}
+
+	if _, err := tx.Exec("INSERT OR IGNORE INTO task_dependencies (task_id, blocked_by_id) VALUES (?, ?)", task.ID, blocker.ID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to add dependency", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to add dependency", err)
+		return
+	}
+
+	config.WriteCreatedResponse(w, "Dependency added successfully", nil)
+}
+
+// RemoveTaskDependency removes a blocked-by edge:
+// DELETE /api/v1/tasks/{id}/dependencies/{blocked_by_id}
+func RemoveTaskDependency(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get task ID and blocker ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/tasks/")
+	if len(segments) != 3 || segments[1] != "dependencies" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Task ID and blocking task ID are required", nil)
+		return
+	}
+
+	// Shared users need the editor role
+	task, ok := authorizeTask(w, config.DB, membership, segments[0], models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	result, err := config.DB.Exec("DELETE FROM task_dependencies WHERE task_id = ? AND blocked_by_id = ?", task.ID, segments[2])
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to remove dependency", err)
+		return
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to remove dependency", err)
+		return
+	}
+
+	if affected == 0 {
+		config.WriteErrorResponse(w, http.StatusNotFound, "Dependency not found", nil)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Dependency removed successfully", nil)
+}
+
+// dependencyTasks fetches the workspace's tasks whose IDs are selected by idQuery
+func dependencyTasks(workspaceID int, idQuery string, taskID int) ([]models.TaskResponse, error) {
+	rows, err := config.DB.Query(
+		"SELECT "+taskColumns+" FROM tasks WHERE workspace_id = ? AND deleted_at IS NULL AND id IN ("+idQuery+") ORDER BY id",
+		workspaceID,
+		taskID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []models.TaskResponse{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, newTaskResponse(task))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tasks, loadTaskDetails(config.DB, taskResponsePointers(tasks))
+}
+
+// dependencyCreatesCycle reports whether making taskID blocked by blockerID
+// would create a cycle, i.e. whether blockerID is already (transitively)
+// blocked by taskID
+func dependencyCreatesCycle(q querier, taskID, blockerID int) (bool, error) {
+	if taskID == blockerID {
+		return true, nil
+	}
+
+	var cycle bool
+	err := q.QueryRow(
+		`WITH RECURSIVE blockers(id) AS (
+			SELECT ?
+			UNION
+			SELECT d.blocked_by_id FROM task_dependencies d JOIN blockers b ON d.task_id = b.id
+		)
+		SELECT EXISTS(SELECT 1 FROM blockers WHERE id = ?)`,
+		blockerID,
+		taskID,
+	).Scan(&cycle)
+	return cycle, err
+}
+
+// openBlockerIDs returns the IDs of the incomplete tasks blocking a task
+func openBlockerIDs(q querier, taskID any) ([]string, error) {
+	rows, err := q.Query(
+		"SELECT t.id FROM task_dependencies d JOIN tasks t ON t.id = d.blocked_by_id WHERE d.task_id = ? AND t.completed = FALSE AND t.deleted_at IS NULL ORDER BY t.id",
+		taskID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/models"
+)
+
+// taskETag returns the entity tag of the current version of a task
+func taskETag(task models.Task) string {
+	return `"` + strconv.Itoa(task.ID) + "-" + strconv.Itoa(task.Version) + `"`
+}
+
+// etagListed reports whether an If-Match or If-None-Match header lists etag
+// or is "*". Weak tags only count when weak comparison is allowed.
+func etagListed(header, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTaskIfMatch writes a 412 and returns false when the request has an
+// If-Match header that does not list the current version of the task
+func checkTaskIfMatch(w http.ResponseWriter, r *http.Request, task models.Task) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" || etagListed(header, taskETag(task), false) {
+		return true
+	}
+
+	w.Header().Set("ETag", taskETag(task))
+	config.WriteErrorResponse(w, http.StatusPreconditionFailed, "Task has been modified since it was fetched", nil)
+	return false
+}
+
+// ifMatchVersion returns the version of the task checked by checkTaskIfMatch,
+// or nil when the request has no If-Match header and any version may be
+// changed. Statements compare it with "(? IS NULL OR version = ?)" so a
+// concurrent change between the check and the update is not overwritten.
+func ifMatchVersion(r *http.Request, task models.Task) any {
+	if r.Header.Get("If-Match") == "" {
+		return nil
+	}
+	return task.Version
+}
+
+// taskNotModified writes a 304 and returns true when the request's
+// If-None-Match header lists the current version of the task
+func taskNotModified(w http.ResponseWriter, r *http.Request, task models.Task) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" || !etagListed(header, taskETag(task), true) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
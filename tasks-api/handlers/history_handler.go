@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+)
+
+const (
+	defaultActivityLimit = 50
+	maxActivityLimit     = 100
+)
+
+// deletedActorID is the actor of the events left by deleted accounts, which
+// keep their place in the history of tasks that outlive the account
+const deletedActorID = 0
+
+// taskEventColumns lists the event columns, with the actor left joined as
+// users, in the order scanTaskEvent expects them. Events of deleted accounts
+// have no user and show a "Deleted user" as the actor.
+const taskEventColumns = "e.id, e.task_id, e.task_title, e.workspace_id, e.action, COALESCE(users.id, 0), COALESCE(users.name, 'Deleted user'), COALESCE(users.email, ''), e.changes, e.created_at"
+
+// GetTaskHistory lists the events of a task, oldest first:
+// GET /api/v1/tasks/{id}/history
+func GetTaskHistory(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get task ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/tasks/")
+	if len(segments) != 2 || segments[1] != "history" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Task ID is required", nil)
+		return
+	}
+
+	// Anyone who can see the task can see its history
+	task, ok := authorizeTask(w, config.DB, membership, segments[0], models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	rows, err := config.DB.Query(
+		"SELECT "+taskEventColumns+" FROM task_events e LEFT JOIN users ON users.id = e.user_id WHERE e.task_id = ? ORDER BY e.id",
+		task.ID,
+	)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch history", err)
+		return
+	}
+
+	defer rows.Close()
+
+	events := []models.TaskEventResponse{}
+	for rows.Next() {
+		event, err := scanTaskEvent(rows)
+		if err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to scan event", err)
+			return
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch history", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "History retrieved successfully", events)
+}
+
+// GetActivity lists the changes the authenticated user made to tasks, newest
+// first, one page at a time: GET /api/v1/activity?limit=&cursor=
+func GetActivity(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	// Validate pagination parameters
+	values := r.URL.Query()
+	errs := map[string]string{}
+
+	limit := defaultActivityLimit
+	if raw := values.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxActivityLimit {
+			errs["limit"] = fmt.Sprintf("limit must be a number between 1 and %d", maxActivityLimit)
+		} else {
+			limit = parsed
+		}
+	}
+
+	where := "e.user_id = ?"
+	args := []any{userID}
+	if raw := values.Get("cursor"); raw != "" {
+		beforeID, err := decodeEventCursor(raw)
+		if err != nil {
+			errs["cursor"] = "cursor is invalid"
+		} else {
+			where += " AND e.id < ?"
+			args = append(args, beforeID)
+		}
+	}
+
+	if len(errs) > 0 {
+		config.WriteValidationErrorResponse(w, errs)
+		return
+	}
+
+	// Fetch one extra event to know whether there is a next page
+	rows, err := config.DB.Query(
+		"SELECT "+taskEventColumns+" FROM task_events e LEFT JOIN users ON users.id = e.user_id WHERE "+where+" ORDER BY e.id DESC LIMIT ?",
+		append(args, limit+1)...,
+	)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch activity", err)
+		return
+	}
+
+	defer rows.Close()
+
+	events := []models.TaskEventResponse{}
+	hasMore := false
+	for rows.Next() {
+		event, err := scanTaskEvent(rows)
+		if err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to scan event", err)
+			return
+		}
+		if len(events) == limit {
+			hasMore = true
+			break
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch activity", err)
+		return
+	}
+
+	// Only hand out a cursor when more events are available
+	nextCursor := ""
+	if hasMore {
+		nextCursor = encodeEventCursor(events[len(events)-1].ID)
+	}
+
+	config.WritePaginatedResponse(w, "Activity retrieved successfully", events, limit, nextCursor)
+}
+
+// taskSnapshot returns the audited fields of a task with their current values
+func taskSnapshot(q querier, task models.Task) (map[string]any, error) {
+	rows, err := q.Query("SELECT label_id FROM task_labels WHERE task_id = ? ORDER BY label_id", task.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	labelIDs := []int{}
+	for rows.Next() {
+		var labelID int
+		if err := rows.Scan(&labelID); err != nil {
+			return nil, err
+		}
+		labelIDs = append(labelIDs, labelID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"title":        task.Title,
+		"description":  task.Description,
+		"due_at":       task.DueAt,
+		"priority":     task.Priority,
+		"project_id":   task.ProjectID,
+		"parent_id":    task.ParentID,
+		"recurrence":   task.Recurrence,
+		"completed":    task.Completed,
+		"completed_at": task.CompletedAt,
+		"label_ids":    labelIDs,
+	}, nil
+}
+
+// recordTaskEvent stores an event with the fields that differ between the
+// before and after snapshots of a task. before is nil for created tasks and
+// after is nil for deleted ones. Updates that change nothing are not recorded.
+func recordTaskEvent(q querier, actorID int, action string, task models.Task, before, after map[string]any) error {
+	changes, err := diffTaskSnapshots(before, after)
+	if err != nil {
+		return err
+	}
+
+	if len(changes) == 0 && action == models.TaskEventUpdated {
+		return nil
+	}
+
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec(
+		"INSERT INTO task_events (task_id, task_title, workspace_id, user_id, action, changes) VALUES (?, ?, ?, ?, ?, ?)",
+		task.ID,
+		task.Title,
+		task.WorkspaceID,
+		actorID,
+		action,
+		string(data),
+	)
+	return err
+}
+
+// recordTaskSnapshotEvent records an event comparing before with the current
+// values of task
+func recordTaskSnapshotEvent(q querier, actorID int, action string, task models.Task, before map[string]any) error {
+	after, err := taskSnapshot(q, task)
+	if err != nil {
+		return err
+	}
+	return recordTaskEvent(q, actorID, action, task, before, after)
+}
+
+// diffTaskSnapshots returns the old and new value of every field that differs
+// between two snapshots, comparing their JSON encoding
+func diffTaskSnapshots(before, after map[string]any) (map[string]models.FieldChange, error) {
+	changes := map[string]models.FieldChange{}
+	for _, snapshot := range []map[string]any{before, after} {
+		for field := range snapshot {
+			if _, seen := changes[field]; seen {
+				continue
+			}
+
+			old, err := json.Marshal(before[field])
+			if err != nil {
+				return nil, err
+			}
+			updated, err := json.Marshal(after[field])
+			if err != nil {
+				return nil, err
+			}
+
+			if !bytes.Equal(old, updated) {
+				changes[field] = models.FieldChange{Old: json.RawMessage(old), New: json.RawMessage(updated)}
+			}
+		}
+	}
+	return changes, nil
+}
+
+// scanTaskEvent scans a row selected with taskEventColumns
+func scanTaskEvent(row rowScanner) (models.TaskEventResponse, error) {
+	var event models.TaskEventResponse
+	var changes string
+	err := row.Scan(
+		&event.ID,
+		&event.TaskID,
+		&event.TaskTitle,
+		&event.WorkspaceID,
+		&event.Action,
+		&event.Actor.ID,
+		&event.Actor.Name,
+		&event.Actor.Email,
+		&changes,
+		&event.CreatedAt,
+	)
+	if err != nil {
+		return event, err
+	}
+
+	err = json.Unmarshal([]byte(changes), &event.Changes)
+	return event, err
+}
+
+func encodeEventCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+func decodeEventCursor(raw string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(string(data))
+}
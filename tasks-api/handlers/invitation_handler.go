@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/go-playground/validator/v10"
+)
+
+// invitationColumns lists the invitation columns in the order scanInvitation expects them
+const invitationColumns = "id, email, role, invited_by, expires_at, created_at"
+
+// CreateInvitation invites someone to a workspace by email:
+// POST /api/v1/workspaces/{id}/invitations. The response contains the
+// invitation token, which is not shown again. Inviting the same email again
+// replaces the pending invitation.
+func CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	// Get workspace ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/workspaces/")
+	if len(segments) != 2 || segments[1] != "invitations" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Workspace ID is required", nil)
+		return
+	}
+
+	// Admins and the owner can invite
+	membership, ok := authorizeWorkspace(w, userID, segments[0], models.WorkspaceRoleAdmin)
+	if !ok {
+		return
+	}
+
+	if membership.Personal {
+		config.WriteErrorResponse(w, http.StatusConflict, "Personal workspaces cannot have other members", nil)
+		return
+	}
+
+	// Check if request body is empty
+	if r.ContentLength == 0 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Request body is required", nil)
+		return
+	}
+
+	// Parse request body
+	var req models.InvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	// Validate input
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Validation failed", err)
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	var isMember bool
+	if err := config.DB.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM workspace_members m JOIN users ON users.id = m.user_id WHERE m.workspace_id = ? AND users.email = ? COLLATE NOCASE)",
+		membership.WorkspaceID,
+		email,
+	).Scan(&isMember); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to check members", err)
+		return
+	}
+
+	if isMember {
+		config.WriteErrorResponse(w, http.StatusConflict, "User is already a member of the workspace", nil)
+		return
+	}
+
+	// Generate the token; only its hash is stored
+	token, err := generateToken()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to generate invitation token", err)
+		return
+	}
+	expiresAt := time.Now().Add(config.InvitationDuration)
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create invitation", err)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"DELETE FROM workspace_invitations WHERE workspace_id = ? AND email = ? AND accepted_at IS NULL",
+		membership.WorkspaceID,
+		email,
+	); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create invitation", err)
+		return
+	}
+
+	result, err := tx.Exec(
+		"INSERT INTO workspace_invitations (workspace_id, email, role, token_hash, invited_by, expires_at) VALUES (?, ?, ?, ?, ?, ?)",
+		membership.WorkspaceID,
+		email,
+		req.Role,
+		config.HashToken(token),
+		userID,
+		sqlTime(&expiresAt),
+	)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create invitation", err)
+		return
+	}
+
+	// Get the last inserted ID
+	lastID, err := result.LastInsertId()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get invitation ID", err)
+		return
+	}
+
+	invitation, err := scanInvitation(tx.QueryRow("SELECT "+invitationColumns+" FROM workspace_invitations WHERE id = ?", lastID))
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch created invitation", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create invitation", err)
+		return
+	}
+
+	invitation.Token = token
+	config.WriteCreatedResponse(w, "Invitation created successfully", invitation)
+}
+
+// GetInvitations lists the pending invitations of a workspace:
+// GET /api/v1/workspaces/{id}/invitations
+func GetInvitations(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	// Get workspace ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/workspaces/")
+	if len(segments) != 2 || segments[1] != "invitations" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Workspace ID is required", nil)
+		return
+	}
+
+	membership, ok := authorizeWorkspace(w, userID, segments[0], models.WorkspaceRoleAdmin)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	rows, err := config.DB.Query(
+		"SELECT "+invitationColumns+" FROM workspace_invitations"+
+			" WHERE workspace_id = ? AND accepted_at IS NULL AND expires_at > ? ORDER BY created_at, id",
+		membership.WorkspaceID,
+		sqlTime(&now),
+	)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch invitations", err)
+		return
+	}
+
+	defer rows.Close()
+
+	invitations := []models.InvitationResponse{}
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to scan invitation", err)
+			return
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	if err := rows.Err(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch invitations", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Invitations retrieved successfully", invitations)
+}
+
+// RevokeInvitation revokes a pending invitation:
+// DELETE /api/v1/workspaces/{id}/invitations/{invitation_id}
+func RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	// Get workspace ID and invitation ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/workspaces/")
+	if len(segments) != 3 || segments[1] != "invitations" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Workspace ID and invitation ID are required", nil)
+		return
+	}
+
+	membership, ok := authorizeWorkspace(w, userID, segments[0], models.WorkspaceRoleAdmin)
+	if !ok {
+		return
+	}
+
+	result, err := config.DB.Exec(
+		"DELETE FROM workspace_invitations WHERE workspace_id = ? AND id = ? AND accepted_at IS NULL",
+		membership.WorkspaceID,
+		segments[2],
+	)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to revoke invitation", err)
+		return
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to revoke invitation", err)
+		return
+	}
+
+	if affected == 0 {
+		config.WriteErrorResponse(w, http.StatusNotFound, "Invitation not found", nil)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Invitation revoked successfully", nil)
+}
+
+// AcceptInvitation adds the authenticated user to the workspace of an
+// invitation sent to their email: POST /api/v1/invitations/accept
+func AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	// Check if request body is empty
+	if r.ContentLength == 0 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Request body is required", nil)
+		return
+	}
+
+	// Parse request body
+	var req models.AcceptInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	// Validate input
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Validation failed", err)
+		return
+	}
+
+	var email string
+	if err := config.DB.QueryRow("SELECT email FROM users WHERE id = ?", userID).Scan(&email); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch user", err)
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to accept invitation", err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Look up the pending invitation by the hash of its token
+	var invitationID, workspaceID int
+	var invitedEmail, role string
+	var expiresAt time.Time
+	if err := tx.QueryRow(
+		"SELECT id, workspace_id, email, role, expires_at FROM workspace_invitations WHERE token_hash = ? AND accepted_at IS NULL",
+		config.HashToken(req.Token),
+	).Scan(&invitationID, &workspaceID, &invitedEmail, &role, &expiresAt); err != nil {
+		if err == sql.ErrNoRows {
+			config.WriteErrorResponse(w, http.StatusNotFound, "Invitation not found", nil)
+			return
+		}
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch invitation", err)
+		return
+	}
+
+	if expiresAt.Before(time.Now()) {
+		config.WriteErrorResponse(w, http.StatusGone, "Invitation has expired", nil)
+		return
+	}
+
+	if !strings.EqualFold(invitedEmail, email) {
+		config.WriteErrorResponse(w, http.StatusForbidden, "This invitation was sent to another email address", nil)
+		return
+	}
+
+	result, err := tx.Exec(
+		"INSERT OR IGNORE INTO workspace_members (workspace_id, user_id, role) VALUES (?, ?, ?)",
+		workspaceID,
+		userID,
+		role,
+	)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to accept invitation", err)
+		return
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to accept invitation", err)
+		return
+	} else if affected == 0 {
+		config.WriteErrorResponse(w, http.StatusConflict, "You are already a member of the workspace", nil)
+		return
+	}
+
+	if _, err := tx.Exec("UPDATE workspace_invitations SET accepted_at = CURRENT_TIMESTAMP WHERE id = ?", invitationID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to accept invitation", err)
+		return
+	}
+
+	workspace, err := getWorkspace(tx, models.WorkspaceMembership{WorkspaceID: workspaceID, UserID: userID})
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch workspace", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to accept invitation", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Invitation accepted successfully", workspace)
+}
+
+// scanInvitation scans a row selected with invitationColumns
+func scanInvitation(row rowScanner) (models.InvitationResponse, error) {
+	var invitation models.InvitationResponse
+	err := row.Scan(
+		&invitation.ID,
+		&invitation.Email,
+		&invitation.Role,
+		&invitation.InvitedBy,
+		&invitation.ExpiresAt,
+		&invitation.CreatedAt,
+	)
+	return invitation, err
+}
@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Media types of the patch formats PATCH accepts
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// errPatchTestFailed is returned when a JSON Patch "test" operation does not match
+var errPatchTestFailed = errors.New("test operation failed")
+
+// jsonPatchOp is a single operation of an RFC 6902 JSON Patch
+type jsonPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// applyMergePatch applies an RFC 7396 JSON Merge Patch to doc: members of
+// patch replace the members of doc, objects are merged recursively and null
+// removes a member
+func applyMergePatch(doc, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	docObject, ok := doc.(map[string]any)
+	if !ok {
+		docObject = map[string]any{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(docObject, key)
+		} else {
+			docObject[key] = applyMergePatch(docObject[key], value)
+		}
+	}
+	return docObject
+}
+
+// applyJSONPatch applies the operations of an RFC 6902 JSON Patch to doc in
+// order. It stops at the first operation that fails; a failed "test"
+// operation returns errPatchTestFailed.
+func applyJSONPatch(doc any, ops []jsonPatchOp) (any, error) {
+	for i, op := range ops {
+		var err error
+		doc, err = applyJSONPatchOp(doc, op)
+		if err != nil {
+			if errors.Is(err, errPatchTestFailed) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return doc, nil
+}
+
+// applyJSONPatchOp applies a single JSON Patch operation to doc
+func applyJSONPatchOp(doc any, op jsonPatchOp) (any, error) {
+	path, err := parseJSONPointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%s requires a value", op.Op)
+		}
+
+		var value any
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, fmt.Errorf("invalid value: %w", err)
+		}
+
+		if op.Op == "test" {
+			current, err := getJSONPointer(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, fmt.Errorf("%w: %s", errPatchTestFailed, op.Path)
+			}
+			return doc, nil
+		}
+
+		// Replacing is removing the old value and adding the new one in its place
+		if op.Op == "replace" {
+			if doc, _, err = removeJSONPointer(doc, path); err != nil {
+				return nil, err
+			}
+		}
+		return addJSONPointer(doc, path, value)
+
+	case "remove":
+		doc, _, err = removeJSONPointer(doc, path)
+		return doc, err
+
+	case "move", "copy":
+		from, err := parseJSONPointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+
+		var value any
+		if op.Op == "move" {
+			// A value cannot be moved into itself
+			if op.Path != op.From && strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, fmt.Errorf("cannot move %s into itself", op.From)
+			}
+			if doc, value, err = removeJSONPointer(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			if value, err = getJSONPointer(doc, from); err != nil {
+				return nil, err
+			}
+			// Copies must not share objects or arrays with the original
+			if value, err = copyJSON(value); err != nil {
+				return nil, err
+			}
+		}
+		return addJSONPointer(doc, path, value)
+	}
+	return nil, fmt.Errorf("unknown operation %q", op.Op)
+}
+
+// parseJSONPointer splits an RFC 6901 JSON Pointer into its unescaped
+// reference tokens. The empty pointer refers to the whole document.
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// getJSONPointer returns the value path refers to in doc
+func getJSONPointer(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path %q does not exist", token)
+			}
+			doc = value
+		case []any:
+			i, err := jsonArrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("path %q does not exist", token)
+		}
+	}
+	return doc, nil
+}
+
+// addJSONPointer adds value at path in doc: it sets an object member or
+// inserts into an array, where "-" appends
+func addJSONPointer(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return updateJSONPointerParent(doc, path, func(parent any, token string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			node[token] = value
+			return node, nil
+		case []any:
+			i := len(node)
+			if token != "-" {
+				var err error
+				if i, err = jsonArrayIndex(token, len(node)); err != nil {
+					return nil, err
+				}
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+		return nil, fmt.Errorf("path %q does not exist", token)
+	})
+}
+
+// removeJSONPointer removes the value at path from doc and returns it
+func removeJSONPointer(doc any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("cannot remove the whole document")
+	}
+
+	var removed any
+	doc, err := updateJSONPointerParent(doc, path, func(parent any, token string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path %q does not exist", token)
+			}
+			removed = value
+			delete(node, token)
+			return node, nil
+		case []any:
+			i, err := jsonArrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			removed = node[i]
+			return append(node[:i], node[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("path %q does not exist", token)
+	})
+	return doc, removed, err
+}
+
+// updateJSONPointerParent walks doc to the parent of the value path refers
+// to and replaces it with the result of update, which gets the last token of
+// path. Arrays are values in Go, so every level on the way is reassigned.
+func updateJSONPointerParent(doc any, path []string, update func(parent any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return update(doc, path[0])
+	}
+
+	switch node := doc.(type) {
+	case map[string]any:
+		child, ok := node[path[0]]
+		if !ok {
+			return nil, fmt.Errorf("path %q does not exist", path[0])
+		}
+		child, err := updateJSONPointerParent(child, path[1:], update)
+		if err != nil {
+			return nil, err
+		}
+		node[path[0]] = child
+		return node, nil
+	case []any:
+		i, err := jsonArrayIndex(path[0], len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		child, err := updateJSONPointerParent(node[i], path[1:], update)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = child
+		return node, nil
+	}
+	return nil, fmt.Errorf("path %q does not exist", path[0])
+}
+
+// jsonArrayIndex parses an array index token, which must be between 0 and max
+// and have no leading zeros
+func jsonArrayIndex(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || strconv.Itoa(i) != token {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if i > max {
+		return 0, fmt.Errorf("array index %d is out of range", i)
+	}
+	return i, nil
+}
+
+// copyJSON returns a deep copy of a decoded JSON value
+func copyJSON(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var copied any
+	err = json.Unmarshal(data, &copied)
+	return copied, err
+}
@@ -0,0 +1,499 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/go-playground/validator/v10"
+)
+
+// labelColumns lists the label columns in the order scanLabel expects them
+const labelColumns = "id, name, color, created_at, updated_at"
+
+// GetLabels lists the labels of the authenticated user
+func GetLabels(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	rows, err := config.DB.Query("SELECT "+labelColumns+" FROM labels WHERE user_id = ? ORDER BY name COLLATE NOCASE", userID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch labels", err)
+		return
+	}
+
+	defer rows.Close()
+
+	labels := []models.LabelResponse{}
+	for rows.Next() {
+		label, err := scanLabel(rows)
+		if err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to scan label", err)
+			return
+		}
+		labels = append(labels, label)
+	}
+
+	if err := rows.Err(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch labels", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Labels retrieved successfully", labels)
+}
+
+// GetOneLabel retrieves a single label of the authenticated user
+func GetOneLabel(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	// Get label ID from URL path
+	labelID := strings.TrimPrefix(r.URL.Path, "/api/v1/labels/")
+	if labelID == "" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Label ID is required", nil)
+		return
+	}
+
+	label, err := getLabel(config.DB, userID, labelID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			config.WriteErrorResponse(w, http.StatusNotFound, "Label not found", nil)
+			return
+		}
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch label", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Label retrieved successfully", label)
+}
+
+// CreateLabel creates a new label for the authenticated user
+func CreateLabel(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	req, ok := decodeLabelRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Label names are unique per user, ignoring case
+	taken, err := labelNameTaken(userID, req.Name, 0)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to check label name", err)
+		return
+	}
+
+	if taken {
+		config.WriteErrorResponse(w, http.StatusConflict, "Label already exists", nil)
+		return
+	}
+
+	// Insert label
+	result, err := config.DB.Exec(
+		"INSERT INTO labels (user_id, name, color) VALUES (?, ?, ?)",
+		userID,
+		req.Name,
+		labelColor(req.Color),
+	)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create label", err)
+		return
+	}
+
+	// Get the last inserted ID
+	lastID, err := result.LastInsertId()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get label ID", err)
+		return
+	}
+
+	label, err := getLabel(config.DB, userID, lastID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch created label", err)
+		return
+	}
+
+	config.WriteCreatedResponse(w, "Label created successfully", label)
+}
+
+// UpdateLabel renames or recolours a label of the authenticated user
+func UpdateLabel(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	// Get label ID from URL path
+	labelID, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/v1/labels/"))
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusNotFound, "Label not found", nil)
+		return
+	}
+
+	// Check if label exists
+	if _, err := getLabel(config.DB, userID, labelID); err != nil {
+		if err == sql.ErrNoRows {
+			config.WriteErrorResponse(w, http.StatusNotFound, "Label not found", nil)
+			return
+		}
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch label", err)
+		return
+	}
+
+	req, ok := decodeLabelRequest(w, r)
+	if !ok {
+		return
+	}
+
+	taken, err := labelNameTaken(userID, req.Name, labelID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to check label name", err)
+		return
+	}
+
+	if taken {
+		config.WriteErrorResponse(w, http.StatusConflict, "Label already exists", nil)
+		return
+	}
+
+	// Update label
+	if _, err := config.DB.Exec(
+		"UPDATE labels SET name = ?, color = ?, updated_at = CURRENT_TIMESTAMP WHERE user_id = ? AND id = ?",
+		req.Name,
+		labelColor(req.Color),
+		userID,
+		labelID,
+	); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update label", err)
+		return
+	}
+
+	label, err := getLabel(config.DB, userID, labelID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch updated label", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Label updated successfully", label)
+}
+
+// DeleteLabel deletes a label and detaches it from every task
+func DeleteLabel(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	// Get label ID from URL path
+	labelID := strings.TrimPrefix(r.URL.Path, "/api/v1/labels/")
+	if labelID == "" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Label ID is required", nil)
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete label", err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Delete the label
+	result, err := tx.Exec("DELETE FROM labels WHERE user_id = ? AND id = ?", userID, labelID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete label", err)
+		return
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete label", err)
+		return
+	}
+
+	if affected == 0 {
+		config.WriteErrorResponse(w, http.StatusNotFound, "Label not found", nil)
+		return
+	}
+
+	// Detach it from every task
+	if _, err := tx.Exec("DELETE FROM task_labels WHERE label_id = ?", labelID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to detach label from tasks", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete label", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Label deleted successfully", nil)
+}
+
+// AttachTaskLabel attaches a label to a task: POST /api/v1/tasks/{id}/labels/{label_id}
+func AttachTaskLabel(w http.ResponseWriter, r *http.Request) {
+	changeTaskLabel(w, r, "INSERT OR IGNORE INTO task_labels (task_id, label_id) VALUES (?, ?)", "Label attached successfully")
+}
+
+// DetachTaskLabel detaches a label from a task: DELETE /api/v1/tasks/{id}/labels/{label_id}
+func DetachTaskLabel(w http.ResponseWriter, r *http.Request) {
+	changeTaskLabel(w, r, "DELETE FROM task_labels WHERE task_id = ? AND label_id = ?", "Label detached successfully")
+}
+
+// changeTaskLabel runs statement with the task and label IDs from the URL
+// after checking that the user can edit the task and that the label belongs
+// to the task's owner
+func changeTaskLabel(w http.ResponseWriter, r *http.Request, statement, message string) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get task ID and label ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/tasks/")
+	if len(segments) != 3 || segments[1] != "labels" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Task ID and label ID are required", nil)
+		return
+	}
+	taskID, labelID := segments[0], segments[2]
+
+	// Editors can change the labels like any other field of the task
+	task, ok := authorizeTask(w, config.DB, membership, taskID, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	// Labels are resolved among the owner's labels, as when updating the task
+	label, err := getLabel(config.DB, task.UserID, labelID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			config.WriteErrorResponse(w, http.StatusNotFound, "Label not found", nil)
+			return
+		}
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch label", err)
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update task labels", err)
+		return
+	}
+	defer tx.Rollback()
+
+	before, err := taskSnapshot(tx, task)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to record task history", err)
+		return
+	}
+
+	result, err := tx.Exec(statement, task.ID, label.ID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update task labels", err)
+		return
+	}
+
+	// Changing the labels makes a new version of the task
+	if affected, err := result.RowsAffected(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update task labels", err)
+		return
+	} else if affected > 0 {
+		if _, err := tx.Exec("UPDATE tasks SET "+touchTaskSQL+" WHERE id = ?", task.ID); err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update task labels", err)
+			return
+		}
+	}
+
+	task, err = getTask(tx, task.WorkspaceID, task.ID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch task", err)
+		return
+	}
+
+	// The label change goes into the task history like label_ids on updates;
+	// attaching a label the task already has records nothing
+	if err := recordTaskSnapshotEvent(tx, membership.UserID, models.TaskEventUpdated, task, before); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to record task history", err)
+		return
+	}
+
+	response, err := taskResponseWithDetails(tx, task)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch task details", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update task labels", err)
+		return
+	}
+
+	w.Header().Set("ETag", taskETag(task))
+	config.WriteSuccessResponse(w, message, response)
+}
+
+// decodeLabelRequest parses and validates a label request body, writing the
+// error response when it is invalid
+func decodeLabelRequest(w http.ResponseWriter, r *http.Request) (models.LabelRequest, bool) {
+	var req models.LabelRequest
+
+	// Check if request body is empty
+	if r.ContentLength == 0 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Request body is required", nil)
+		return req, false
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return req, false
+	}
+
+	// Validate input
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Validation failed", err)
+		return req, false
+	}
+
+	return req, true
+}
+
+// scanLabel scans a row selected with labelColumns
+func scanLabel(row rowScanner) (models.LabelResponse, error) {
+	var label models.LabelResponse
+	err := row.Scan(&label.ID, &label.Name, &label.Color, &label.CreatedAt, &label.UpdatedAt)
+	return label, err
+}
+
+// getLabel fetches a single label of the user
+func getLabel(q querier, userID int, labelID any) (models.LabelResponse, error) {
+	return scanLabel(q.QueryRow("SELECT "+labelColumns+" FROM labels WHERE user_id = ? AND id = ?", userID, labelID))
+}
+
+// labelNameTaken reports whether the user has another label with the same name
+func labelNameTaken(userID int, name string, exceptID int) (bool, error) {
+	var exists bool
+	err := config.DB.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM labels WHERE user_id = ? AND name = ? COLLATE NOCASE AND id != ?)",
+		userID,
+		name,
+		exceptID,
+	).Scan(&exists)
+	return exists, err
+}
+
+// labelColor returns the colour to store, falling back to the default colour
+func labelColor(color string) string {
+	if color == "" {
+		return models.DefaultLabelColor
+	}
+	return strings.ToLower(color)
+}
+
+// setTaskLabels replaces the labels attached to a task. It returns a
+// validation error map when a label does not belong to the user.
+func setTaskLabels(q querier, userID, taskID int, labelIDs []int) (map[string]string, error) {
+	unique := map[int]bool{}
+	for _, id := range labelIDs {
+		unique[id] = true
+	}
+
+	if len(unique) > 0 {
+		placeholders, args := inClause(labelIDs)
+		var count int
+		if err := q.QueryRow(
+			"SELECT COUNT(*) FROM labels WHERE user_id = ? AND id IN ("+placeholders+")",
+			append([]any{userID}, args...)...,
+		).Scan(&count); err != nil {
+			return nil, err
+		}
+
+		if count != len(unique) {
+			return map[string]string{"label_ids": "label_ids must only contain your own labels"}, nil
+		}
+	}
+
+	if _, err := q.Exec("DELETE FROM task_labels WHERE task_id = ?", taskID); err != nil {
+		return nil, err
+	}
+
+	for id := range unique {
+		if _, err := q.Exec("INSERT INTO task_labels (task_id, label_id) VALUES (?, ?)", taskID, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// loadTaskLabels fills in the labels of every task with a single query
+func loadTaskLabels(q querier, tasks []*models.TaskResponse) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	byID := map[int]*models.TaskResponse{}
+	ids := []int{}
+	for _, task := range tasks {
+		task.Labels = []models.LabelResponse{}
+		byID[task.ID] = task
+		ids = append(ids, task.ID)
+	}
+
+	placeholders, args := inClause(ids)
+	rows, err := q.Query(
+		"SELECT tl.task_id, l.id, l.name, l.color, l.created_at, l.updated_at FROM task_labels tl JOIN labels l ON l.id = tl.label_id WHERE tl.task_id IN ("+placeholders+") ORDER BY l.name COLLATE NOCASE",
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID int
+		var label models.LabelResponse
+		if err := rows.Scan(&taskID, &label.ID, &label.Name, &label.Color, &label.CreatedAt, &label.UpdatedAt); err != nil {
+			return err
+		}
+		byID[taskID].Labels = append(byID[taskID].Labels, label)
+	}
+
+	return rows.Err()
+}
+
+// inClause returns "?, ?, ?" placeholders and the matching arguments
+func inClause(ids []int) (string, []any) {
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	return strings.Join(placeholders, ", "), args
+}
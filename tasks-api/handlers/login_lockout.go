@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyPasswordHash is compared with the password of logins to unknown
+// emails so they take as long as logins to existing accounts
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+
+// loginEmail normalises an email for counting failed logins
+func loginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// loginLockedFor returns how long logins to email stay locked, or zero when
+// they are allowed. Emails without an account are locked the same way.
+func loginLockedFor(email string) (time.Duration, error) {
+	var lockedUntil sql.NullTime
+	err := config.DB.QueryRow("SELECT locked_until FROM login_attempts WHERE email = ?", email).Scan(&lockedUntil)
+	if err == sql.ErrNoRows || (err == nil && !lockedUntil.Valid) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if wait := time.Until(lockedUntil.Time); wait > 0 {
+		return wait, nil
+	}
+	return 0, nil
+}
+
+// recordFailedLogin counts a failed login to email. From
+// config.LoginLockoutThreshold failures on, every failure locks the email for
+// config.LoginLockoutDuration, doubled for each failure past the threshold up
+// to config.LoginLockoutMaxDuration.
+func recordFailedLogin(email string) error {
+	now := time.Now().UTC()
+	cutoff := now.Add(-config.LoginFailureWindow)
+
+	// Count the failure in a single statement so parallel logins cannot lose
+	// increments. Failures older than the window no longer count, and neither
+	// does the lockout they caused.
+	var failures int
+	err := config.DB.QueryRow(
+		"INSERT INTO login_attempts (email, failures, last_failed_at) VALUES (?, 1, ?)"+
+			" ON CONFLICT (email) DO UPDATE SET"+
+			" failures = CASE WHEN last_failed_at >= ? THEN failures + 1 ELSE 1 END,"+
+			" locked_until = CASE WHEN last_failed_at >= ? THEN locked_until ELSE NULL END,"+
+			" last_failed_at = excluded.last_failed_at"+
+			" RETURNING failures",
+		email,
+		sqlTime(&now),
+		sqlTime(&cutoff),
+		sqlTime(&cutoff),
+	).Scan(&failures)
+	if err != nil {
+		return err
+	}
+
+	if failures < config.LoginLockoutThreshold {
+		return nil
+	}
+
+	lockout := config.LoginLockoutDuration
+	for i := config.LoginLockoutThreshold; i < failures && lockout < config.LoginLockoutMaxDuration; i++ {
+		lockout *= 2
+	}
+	lockout = min(lockout, config.LoginLockoutMaxDuration)
+
+	// Only the latest failure sets the lockout, so a slower parallel login
+	// cannot replace it with a shorter one
+	until := now.Add(lockout)
+	_, err = config.DB.Exec(
+		"UPDATE login_attempts SET locked_until = ? WHERE email = ? AND failures = ?",
+		sqlTime(&until),
+		email,
+		failures,
+	)
+	return err
+}
+
+// clearFailedLogins forgets the failed logins to email after a successful one
+func clearFailedLogins(email string) error {
+	_, err := config.DB.Exec("DELETE FROM login_attempts WHERE email = ?", email)
+	return err
+}
+
+// PurgeStaleLoginAttempts deletes the failed logins that no longer count
+// towards a lockout and whose lockout has ended
+func PurgeStaleLoginAttempts() (int64, error) {
+	now := time.Now().UTC()
+	cutoff := now.Add(-config.LoginFailureWindow)
+
+	result, err := config.DB.Exec(
+		"DELETE FROM login_attempts WHERE last_failed_at <= ? AND (locked_until IS NULL OR locked_until <= ?)",
+		sqlTime(&cutoff),
+		sqlTime(&now),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
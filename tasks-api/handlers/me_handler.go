@@ -0,0 +1,578 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// accountTasksQuery selects the tasks that go with an account: the ones the
+// user created and every task in the workspaces they own
+const accountTasksQuery = "SELECT id FROM tasks WHERE user_id = ? OR workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = ? AND role = 'owner')"
+
+// GetMe retrieves the account of the authenticated user: GET /api/v1/me
+func GetMe(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	user, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User not found in context", fmt.Errorf("user not found in context"))
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Profile retrieved successfully", newProfileResponse(user))
+}
+
+// UpdateMe changes the name, email or timezone of the authenticated user:
+// PATCH /api/v1/me. A new email has to be verified again.
+func UpdateMe(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	user, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User not found in context", fmt.Errorf("user not found in context"))
+		return
+	}
+
+	// Check if request body is empty
+	if r.ContentLength == 0 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Request body is required", nil)
+		return
+	}
+
+	var req models.UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	// Validate input
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Validation failed", err)
+		return
+	}
+
+	if req.Name != nil {
+		user.Name = *req.Name
+	}
+	if req.Timezone != nil {
+		user.Timezone = *req.Timezone
+	}
+
+	emailChanged := req.Email != nil && *req.Email != user.Email
+	if emailChanged {
+		// Check if email already exists
+		var count int
+		if err := config.DB.QueryRow("SELECT COUNT(*) FROM users WHERE email = ? AND id != ?", *req.Email, user.ID).Scan(&count); err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to check email", err)
+			return
+		}
+
+		if count > 0 {
+			config.WriteErrorResponse(w, http.StatusConflict, "Email already exists", nil)
+			return
+		}
+
+		user.Email = *req.Email
+		user.EmailVerifiedAt = nil
+	}
+
+	now := time.Now().UTC()
+	query := "UPDATE users SET name = ?, timezone = ? WHERE id = ?"
+	args := []any{user.Name, user.Timezone, user.ID}
+	if emailChanged {
+		query = "UPDATE users SET name = ?, timezone = ?, email = ?, email_verified_at = NULL, verification_sent_at = ? WHERE id = ?"
+		args = []any{user.Name, user.Timezone, user.Email, sqlTime(&now), user.ID}
+	}
+
+	if _, err := config.DB.Exec(query, args...); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update profile", err)
+		return
+	}
+
+	// Links sent to the old email stop working since they are bound to it
+	if emailChanged {
+		sendVerificationEmail(user)
+	}
+
+	config.WriteSuccessResponse(w, "Profile updated successfully", newProfileResponse(user))
+}
+
+// ChangePassword sets a new password for the authenticated user after
+// checking the current one: POST /api/v1/me/password. Every session and
+// personal access token is revoked and the response carries new tokens for
+// the client that made the change.
+func ChangePassword(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	// Check if request body is empty
+	if r.ContentLength == 0 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Request body is required", nil)
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	// Validate input
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Validation failed", err)
+		return
+	}
+
+	if _, ok := checkAccountPassword(w, userID, req.CurrentPassword); !ok {
+		return
+	}
+
+	hashedPassword, err := hashPassword(req.NewPassword)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to hash password", err)
+		return
+	}
+
+	// Start a new token family for the client that changed the password
+	familyID, err := generateToken()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to generate token", err)
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to change password", err)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE users SET password = ? WHERE id = ?", hashedPassword, userID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to change password", err)
+		return
+	}
+
+	// Reset links sent for the old password stop working
+	if _, err := tx.Exec("DELETE FROM password_resets WHERE user_id = ? AND used_at IS NULL", userID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to change password", err)
+		return
+	}
+
+	// Log the user out everywhere, including this session, and revoke the
+	// access tokens created with the old password
+	if _, err := revokeAllSessions(tx, userID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to revoke sessions", err)
+		return
+	}
+	if _, err := revokeAccessTokens(tx, userID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to revoke access tokens", err)
+		return
+	}
+
+	tokens, err := issueTokens(tx, userID, familyID, r)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create session", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to change password", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Password changed successfully", tokens)
+}
+
+// DeleteMe deletes the account of the authenticated user after checking the
+// password: DELETE /api/v1/me. The workspaces the user owns are deleted with
+// everything in them, as are the tasks and projects they created elsewhere
+// (tasks of deleted projects move to the inbox). Users who own a workspace
+// with other members have to delete it or remove the other members first.
+// The response is an export of the deleted data.
+func DeleteMe(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	// Check if request body is empty
+	if r.ContentLength == 0 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Request body is required", nil)
+		return
+	}
+
+	var req models.DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	// Validate input
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Validation failed", err)
+		return
+	}
+
+	user, ok := checkAccountPassword(w, userID, req.Password)
+	if !ok {
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete account", err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Workspaces shared with others must not lose their owner
+	shared, err := sharedOwnedWorkspaces(tx, userID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch workspaces", err)
+		return
+	}
+	if len(shared) > 0 {
+		config.WriteErrorResponse(w, http.StatusConflict, "Delete the workspaces you own with other members, or remove the members, first: "+strings.Join(shared, ", "), nil)
+		return
+	}
+
+	export, err := exportAccount(tx, user)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to export account", err)
+		return
+	}
+
+	files, err := deleteAccount(tx, userID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete account", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete account", err)
+		return
+	}
+
+	removeAttachmentFiles(files)
+	if err := clearFailedLogins(loginEmail(user.Email)); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to record login attempt", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Account deleted successfully", export)
+}
+
+// checkAccountPassword checks the password of the user before a change to
+// the account, writing the error response when it is wrong. Wrong passwords
+// count towards the login lockout so a stolen session cannot guess it.
+func checkAccountPassword(w http.ResponseWriter, userID int, password string) (models.User, bool) {
+	var user models.User
+	if err := config.DB.QueryRow(
+		"SELECT id, name, email, password, timezone, created_at, email_verified_at, role FROM users WHERE id = ?",
+		userID,
+	).Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.Timezone, &user.CreatedAt, &user.EmailVerifiedAt, &user.Role); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch user", err)
+		return user, false
+	}
+
+	email := loginEmail(user.Email)
+	wait, err := loginLockedFor(email)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to check login attempts", err)
+		return user, false
+	}
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		config.WriteErrorResponse(w, http.StatusTooManyRequests, "Too many failed login attempts, try again later", nil)
+		return user, false
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		if err := recordFailedLogin(email); err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to record login attempt", err)
+			return user, false
+		}
+		config.WriteErrorResponse(w, http.StatusForbidden, "Password is incorrect", nil)
+		return user, false
+	}
+
+	return user, true
+}
+
+// sharedOwnedWorkspaces returns the names of the workspaces the user owns
+// that have other members
+func sharedOwnedWorkspaces(q querier, userID int) ([]string, error) {
+	rows, err := q.Query(
+		"SELECT w.name FROM workspaces w JOIN workspace_members m ON m.workspace_id = w.id AND m.user_id = ? AND m.role = 'owner'"+
+			" WHERE (SELECT COUNT(*) FROM workspace_members c WHERE c.workspace_id = w.id) > 1 ORDER BY w.name COLLATE NOCASE",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// exportAccount collects the data of a user that is deleted with the account:
+// their workspaces, the projects and tasks that go with the account, their
+// labels and the comments they wrote
+func exportAccount(q querier, user models.User) (models.AccountExport, error) {
+	export := models.AccountExport{
+		User:       newProfileResponse(user),
+		Workspaces: []models.WorkspaceResponse{},
+		Projects:   []models.ProjectResponse{},
+		Tasks:      []models.TaskResponse{},
+		Labels:     []models.LabelResponse{},
+		Comments:   []models.CommentResponse{},
+		ExportedAt: time.Now().UTC(),
+	}
+
+	rows, err := q.Query(
+		"SELECT "+workspaceColumns+" FROM workspaces w JOIN workspace_members m ON m.workspace_id = w.id AND m.user_id = ? ORDER BY w.id",
+		user.ID,
+	)
+	if err != nil {
+		return export, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		workspace, err := scanWorkspace(rows)
+		if err != nil {
+			return export, err
+		}
+		export.Workspaces = append(export.Workspaces, workspace)
+	}
+	if err := rows.Err(); err != nil {
+		return export, err
+	}
+
+	rows, err = q.Query(
+		"SELECT "+projectColumns+" FROM projects WHERE user_id = ? OR workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = ? AND role = 'owner') ORDER BY id",
+		user.ID,
+		user.ID,
+	)
+	if err != nil {
+		return export, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return export, err
+		}
+		export.Projects = append(export.Projects, project)
+	}
+	if err := rows.Err(); err != nil {
+		return export, err
+	}
+
+	// Tasks in the trash are exported too
+	rows, err = q.Query("SELECT "+taskColumns+" FROM tasks WHERE id IN ("+accountTasksQuery+") ORDER BY id", user.ID, user.ID)
+	if err != nil {
+		return export, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return export, err
+		}
+		export.Tasks = append(export.Tasks, newTaskResponse(task))
+	}
+	if err := rows.Err(); err != nil {
+		return export, err
+	}
+
+	if err := loadTaskDetails(q, taskResponsePointers(export.Tasks)); err != nil {
+		return export, err
+	}
+
+	rows, err = q.Query("SELECT "+labelColumns+" FROM labels WHERE user_id = ? ORDER BY id", user.ID)
+	if err != nil {
+		return export, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		label, err := scanLabel(rows)
+		if err != nil {
+			return export, err
+		}
+		export.Labels = append(export.Labels, label)
+	}
+	if err := rows.Err(); err != nil {
+		return export, err
+	}
+
+	rows, err = q.Query("SELECT "+commentColumns+" FROM task_comments c JOIN users ON users.id = c.user_id WHERE c.user_id = ? ORDER BY c.id", user.ID)
+	if err != nil {
+		return export, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return export, err
+		}
+		export.Comments = append(export.Comments, comment)
+	}
+	return export, rows.Err()
+}
+
+// deleteAccount deletes a user with everything that goes with the account.
+// It returns the attachment files to remove with removeAttachmentFiles once
+// the transaction is committed.
+func deleteAccount(q querier, userID int) ([]string, error) {
+	// Delete the workspaces the user owns with everything in them
+	rows, err := q.Query("SELECT workspace_id FROM workspace_members WHERE user_id = ? AND role = 'owner'", userID)
+	if err != nil {
+		return nil, err
+	}
+
+	workspaceIDs := []int{}
+	for rows.Next() {
+		var workspaceID int
+		if err := rows.Scan(&workspaceID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		workspaceIDs = append(workspaceIDs, workspaceID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, workspaceID := range workspaceIDs {
+		// The history of the workspace goes with its tasks
+		if _, err := q.Exec("DELETE FROM task_events WHERE workspace_id = ?", workspaceID); err != nil {
+			return nil, err
+		}
+
+		workspaceFiles, err := deleteWorkspace(q, workspaceID)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, workspaceFiles...)
+	}
+
+	// Delete the tasks the user created in other workspaces, and the files
+	// they attached to other tasks
+	taskFiles, err := taskAttachmentFiles(q, "SELECT id FROM tasks WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, taskFiles...)
+
+	if _, err := q.Exec("DELETE FROM task_events WHERE task_id IN (SELECT id FROM tasks WHERE user_id = ?)", userID); err != nil {
+		return nil, err
+	}
+
+	if _, err := deleteTasks(q, "SELECT id FROM tasks WHERE user_id = ?", userID); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.Query("SELECT DISTINCT sha256 FROM task_attachments WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		files = append(files, hash)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Changes the user made to tasks that remain stay in their history, without
+	// saying who made them
+	if _, err := q.Exec("UPDATE task_events SET user_id = ? WHERE user_id = ?", deletedActorID, userID); err != nil {
+		return nil, err
+	}
+
+	// The tasks of projects the user created in other workspaces move to the inbox
+	if _, err := q.Exec("UPDATE tasks SET project_id = NULL, "+touchTaskSQL+" WHERE project_id IN (SELECT id FROM projects WHERE user_id = ?)", userID); err != nil {
+		return nil, err
+	}
+
+	statements := []string{
+		"DELETE FROM task_attachments WHERE user_id = ?",
+		"DELETE FROM project_shares WHERE project_id IN (SELECT id FROM projects WHERE user_id = ?)",
+		"DELETE FROM projects WHERE user_id = ?",
+		"DELETE FROM task_labels WHERE label_id IN (SELECT id FROM labels WHERE user_id = ?)",
+		"DELETE FROM labels WHERE user_id = ?",
+		"DELETE FROM task_comments WHERE user_id = ?",
+		"DELETE FROM task_shares WHERE user_id = ?",
+		"DELETE FROM task_shares WHERE shared_by = ?",
+		"DELETE FROM project_shares WHERE user_id = ?",
+		"DELETE FROM project_shares WHERE shared_by = ?",
+		"DELETE FROM workspace_invitations WHERE invited_by = ?",
+		"DELETE FROM workspace_members WHERE user_id = ?",
+		"DELETE FROM password_resets WHERE user_id = ?",
+		"DELETE FROM sessions WHERE user_id = ?",
+		"DELETE FROM refresh_tokens WHERE user_id = ?",
+		"DELETE FROM personal_access_tokens WHERE user_id = ?",
+		"DELETE FROM users WHERE id = ?",
+	}
+	for _, statement := range statements {
+		if _, err := q.Exec(statement, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := q.Exec("DELETE FROM idempotency_keys WHERE scope = ?", "user:"+strconv.Itoa(userID)); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// newProfileResponse converts a user to their profile
+func newProfileResponse(user models.User) models.ProfileResponse {
+	return models.ProfileResponse{
+		ID:            user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		Role:          user.Role,
+		Timezone:      user.Timezone,
+		CreatedAt:     user.CreatedAt,
+	}
+}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/mail"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/go-playground/validator/v10"
+)
+
+// forgotPasswordMessage is the response to every password reset request, so
+// it does not reveal which emails have an account
+const forgotPasswordMessage = "If an account with this email exists, a password reset link has been sent to it"
+
+// ForgotPassword emails a single use password reset token to the user with
+// the given email. Only the hash of the token is stored.
+func ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	// Check that method is post
+	if r.Method != http.MethodPost {
+		config.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed. Use POST", nil)
+		return
+	}
+
+	// Check if request body is empty
+	if r.ContentLength == 0 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Request body is empty", nil)
+		return
+	}
+
+	var req models.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	// Validate input
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Validation failed", err)
+		return
+	}
+
+	// Find user by email; unknown emails get the same response
+	var user models.User
+	err := config.DB.QueryRow("SELECT id, name, email FROM users WHERE email = ?", req.Email).Scan(&user.ID, &user.Name, &user.Email)
+	if err != nil && err != sql.ErrNoRows {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch user", err)
+		return
+	}
+
+	// The reset is created and emailed in the background, so existing accounts
+	// are answered as fast as unknown emails. Failures are only logged; the
+	// response must not differ from the one for unknown emails.
+	if err == nil {
+		config.SendInBackground(func() {
+			if err := sendPasswordReset(user); err != nil {
+				log.Printf("Failed to send password reset email to user %d: %v", user.ID, err)
+			}
+		})
+	}
+
+	config.WriteSuccessResponse(w, forgotPasswordMessage, nil)
+}
+
+// sendPasswordReset stores a new password reset token for user, replacing any
+// unused one, and emails it
+func sendPasswordReset(user models.User) error {
+	token, err := generateToken()
+	if err != nil {
+		return err
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Only the latest reset link works
+	if _, err := tx.Exec("DELETE FROM password_resets WHERE user_id = ? AND used_at IS NULL", user.ID); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(config.PasswordResetDuration)
+	if _, err := tx.Exec(
+		"INSERT INTO password_resets (user_id, token_hash, created_at, expires_at) VALUES (?, ?, ?, ?)",
+		user.ID,
+		config.HashToken(token),
+		sqlTime(&now),
+		sqlTime(&expiresAt),
+	); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return config.Mailer.Send(passwordResetMessage(user, token))
+}
+
+// ResetPassword sets a new password with a token from ForgotPassword. The
+// token can be used once, and every session and personal access token of the
+// user is revoked.
+func ResetPassword(w http.ResponseWriter, r *http.Request) {
+	// Check that method is post
+	if r.Method != http.MethodPost {
+		config.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed. Use POST", nil)
+		return
+	}
+
+	// Check if request body is empty
+	if r.ContentLength == 0 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Request body is empty", nil)
+		return
+	}
+
+	var req models.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	// Validate input
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Validation failed", err)
+		return
+	}
+
+	hashedPassword, err := hashPassword(req.Password)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to hash password", err)
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to reset password", err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Look up the unused reset by the hash of its token
+	var resetID, userID int
+	var expiresAt time.Time
+	err = tx.QueryRow(
+		"SELECT id, user_id, expires_at FROM password_resets WHERE token_hash = ? AND used_at IS NULL",
+		config.HashToken(req.Token),
+	).Scan(&resetID, &userID, &expiresAt)
+	if err == sql.ErrNoRows || (err == nil && expiresAt.Before(time.Now())) {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid or expired reset token", nil)
+		return
+	}
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to reset password", err)
+		return
+	}
+
+	// Use up the token; a concurrent reset with the same token loses the race
+	now := time.Now().UTC()
+	result, err := tx.Exec("UPDATE password_resets SET used_at = ? WHERE id = ? AND used_at IS NULL", sqlTime(&now), resetID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to reset password", err)
+		return
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to reset password", err)
+		return
+	}
+	if affected == 0 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid or expired reset token", nil)
+		return
+	}
+
+	var email string
+	if err := tx.QueryRow("SELECT email FROM users WHERE id = ?", userID).Scan(&email); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch user", err)
+		return
+	}
+
+	if _, err := tx.Exec("UPDATE users SET password = ? WHERE id = ?", hashedPassword, userID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to reset password", err)
+		return
+	}
+
+	// Links sent before this one stop working too
+	if _, err := tx.Exec("DELETE FROM password_resets WHERE user_id = ? AND used_at IS NULL", userID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to reset password", err)
+		return
+	}
+
+	// Log the user out everywhere and revoke their access tokens, in case
+	// someone else knew the old password
+	if _, err := revokeAllSessions(tx, userID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to revoke sessions", err)
+		return
+	}
+	if _, err := revokeAccessTokens(tx, userID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to revoke access tokens", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to reset password", err)
+		return
+	}
+
+	// A locked account can log in with its new password right away
+	if err := clearFailedLogins(loginEmail(email)); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to record login attempt", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Password reset successfully", nil)
+}
+
+// passwordResetMessage is the email with the password reset link for user
+func passwordResetMessage(user models.User, token string) mail.Message {
+	link := config.BaseURL + "/reset-password?token=" + url.QueryEscape(token)
+	return mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nSomeone asked to reset the password of your account. To choose a new password, open\n\n%s\n\nor use this token:\n\n%s\n\nThe link expires in %d minutes. If you did not ask for it, you can ignore this email.\n",
+			user.Name,
+			link,
+			token,
+			int(config.PasswordResetDuration.Minutes()),
+		),
+	}
+}
+
+// PurgeExpiredPasswordResets deletes the password reset tokens that have
+// expired or been used
+func PurgeExpiredPasswordResets() (int64, error) {
+	now := time.Now().UTC()
+	result, err := config.DB.Exec("DELETE FROM password_resets WHERE expires_at <= ? OR used_at IS NOT NULL", sqlTime(&now))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/go-playground/validator/v10"
+)
+
+// taskPatchFields maps the JSON names of the fields of models.TaskPatch to
+// their Go names, which validator.StructPartial expects
+var taskPatchFields = func() map[string]string {
+	fields := map[string]string{}
+	patchType := reflect.TypeOf(models.TaskPatch{})
+	for i := 0; i < patchType.NumField(); i++ {
+		field := patchType.Field(i)
+		fields[strings.Split(field.Tag.Get("json"), ",")[0]] = field.Name
+	}
+	return fields
+}()
+
+// PatchTask changes some of the fields of a task: PATCH /api/v1/tasks/{id}
+// with an application/merge-patch+json (or application/json) JSON Merge Patch
+// or an application/json-patch+json JSON Patch. The patch is applied to the
+// fields of models.TaskPatch and only the fields it touches are validated.
+func PatchTask(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get task ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/tasks/")
+	if len(segments) != 1 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Task ID is required", nil)
+		return
+	}
+
+	// Shared users need the editor role
+	task, ok := authorizeTask(w, config.DB, membership, segments[0], models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	// Refuse to patch a version of the task the client has not seen
+	if !checkTaskIfMatch(w, r, task) {
+		return
+	}
+
+	// Read the patch
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Request body is required", nil)
+		return
+	}
+
+	// The patch applies to the current values of the task
+	current, err := taskResponseWithDetails(config.DB, task)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch task details", err)
+		return
+	}
+
+	doc, err := taskPatchDocument(current)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to patch task", err)
+		return
+	}
+
+	// Apply the patch and remember which fields it touches
+	touched := map[string]bool{}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case mergePatchType, "application/json":
+		var patch any
+		if err := json.Unmarshal(body, &patch); err != nil {
+			config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+			return
+		}
+
+		patchObject, ok := patch.(map[string]any)
+		if !ok {
+			config.WriteErrorResponse(w, http.StatusBadRequest, "Merge patch must be a JSON object", nil)
+			return
+		}
+
+		for field := range patchObject {
+			touched[field] = true
+		}
+		doc = applyMergePatch(doc, patch)
+
+	case jsonPatchType:
+		var ops []jsonPatchOp
+		if err := json.Unmarshal(body, &ops); err != nil {
+			config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+			return
+		}
+
+		// Operations on the whole document touch every field
+		for _, op := range ops {
+			if op.Op == "test" {
+				continue
+			}
+			paths := []string{op.Path}
+			if op.Op == "move" {
+				paths = append(paths, op.From)
+			}
+			for _, path := range paths {
+				tokens, _ := parseJSONPointer(path)
+				if len(tokens) == 0 {
+					for field := range taskPatchFields {
+						touched[field] = true
+					}
+				} else {
+					touched[tokens[0]] = true
+				}
+			}
+		}
+
+		doc, err = applyJSONPatch(doc, ops)
+		if errors.Is(err, errPatchTestFailed) {
+			config.WriteErrorResponse(w, http.StatusConflict, "Patch test failed", err)
+			return
+		}
+		if err != nil {
+			config.WriteValidationErrorResponse(w, map[string]string{"patch": err.Error()})
+			return
+		}
+
+	default:
+		config.WriteErrorResponse(w, http.StatusUnsupportedMediaType, "Content-Type must be "+mergePatchType+" or "+jsonPatchType, nil)
+		return
+	}
+
+	// Only the fields of models.TaskPatch can be patched
+	fields := []string{}
+	errs := map[string]string{}
+	for field := range touched {
+		name, ok := taskPatchFields[field]
+		if !ok {
+			errs[field] = "unknown field"
+			continue
+		}
+		fields = append(fields, name)
+	}
+	if len(errs) > 0 {
+		config.WriteValidationErrorResponse(w, errs)
+		return
+	}
+
+	req, errs, err := decodeTaskPatch(doc)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to patch task", err)
+		return
+	}
+	if errs != nil {
+		config.WriteValidationErrorResponse(w, errs)
+		return
+	}
+
+	// Validate the fields the patch touched
+	validate := validator.New()
+	if err := validate.StructPartial(req, fields...); err != nil {
+		config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Validation failed", err)
+		return
+	}
+
+	// Check the recurrence again when it or the due date it repeats from changes
+	var recurrence any
+	if task.Recurrence != nil {
+		recurrence = *task.Recurrence
+	}
+	if touched["recurrence"] || touched["due_at"] {
+		rule := ""
+		if req.Recurrence != nil {
+			rule = *req.Recurrence
+		}
+		if recurrence, errs = taskRecurrence(models.TaskRequest{Recurrence: rule, DueAt: req.DueAt}); errs != nil {
+			config.WriteValidationErrorResponse(w, errs)
+			return
+		}
+	}
+
+	// Labels are only replaced when the patch touches them
+	labelIDs := req.LabelIDs
+	if !touched["label_ids"] {
+		labelIDs = nil
+	} else if labelIDs == nil {
+		labelIDs = []int{}
+	}
+
+	// Completing and reopening go through the same checks as the dedicated actions
+	changeFields := len(fields) > 1 || len(fields) == 1 && !touched["completed"]
+	changeCompleted := touched["completed"] && req.Completed != task.Completed
+
+	// Recurring tasks repeat in the owner's timezone
+	loc := time.UTC
+	if changeCompleted && req.Completed {
+		if loc, err = userLocation(config.DB, task.UserID); err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load user timezone", err)
+			return
+		}
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update task", err)
+		return
+	}
+	defer tx.Rollback()
+
+	version := ifMatchVersion(r, task)
+	if changeFields {
+		task, ok = saveTask(w, tx, membership.UserID, task, models.TaskRequest{
+			Title:       req.Title,
+			Description: req.Description,
+			DueAt:       req.DueAt,
+			Priority:    req.Priority,
+			LabelIDs:    labelIDs,
+			ProjectID:   req.ProjectID,
+			ParentID:    req.ParentID,
+		}, recurrence, version)
+		if !ok {
+			return
+		}
+
+		// The version has been checked, completing the task bumps it again
+		version = nil
+	}
+
+	if changeCompleted {
+		task, _, ok = setTaskCompleted(w, tx, membership.UserID, task, req.Completed, version, loc)
+		if !ok {
+			return
+		}
+	}
+
+	response, err := taskResponseWithDetails(tx, task)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch task details", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update task", err)
+		return
+	}
+
+	// Return success response with the complete task
+	w.Header().Set("ETag", taskETag(task))
+	config.WriteSuccessResponse(w, "Task updated successfully", response)
+}
+
+// taskPatchDocument returns the current values of the patchable fields of a
+// task as the decoded JSON document patches are applied to
+func taskPatchDocument(task models.TaskResponse) (any, error) {
+	labelIDs := make([]int, 0, len(task.Labels))
+	for _, label := range task.Labels {
+		labelIDs = append(labelIDs, label.ID)
+	}
+
+	data, err := json.Marshal(models.TaskPatch{
+		Title:       task.Title,
+		Description: task.Description,
+		DueAt:       task.DueAt,
+		Priority:    task.Priority,
+		LabelIDs:    labelIDs,
+		ProjectID:   task.ProjectID,
+		ParentID:    task.ParentID,
+		Recurrence:  task.Recurrence,
+		Completed:   task.Completed,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var doc any
+	err = json.Unmarshal(data, &doc)
+	return doc, err
+}
+
+// decodeTaskPatch decodes a patched document. Values of the wrong type are
+// returned as validation errors.
+func decodeTaskPatch(doc any) (models.TaskPatch, map[string]string, error) {
+	var req models.TaskPatch
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return req, nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&req)
+
+	var typeErr *json.UnmarshalTypeError
+	var timeErr *time.ParseError
+	switch {
+	case err == nil:
+		return req, nil, nil
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return req, map[string]string{typeErr.Field: "cannot be a " + typeErr.Value}, nil
+	case errors.As(err, &timeErr):
+		return req, map[string]string{"due_at": "must be an RFC 3339 timestamp"}, nil
+	}
+	return req, map[string]string{"patch": err.Error()}, nil
+}
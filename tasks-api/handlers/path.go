@@ -0,0 +1,13 @@
+package handlers
+
+import "strings"
+
+// pathSegments returns the segments of the URL path that follow prefix, so
+// "/api/v1/tasks/12/labels/3" with prefix "/api/v1/tasks/" gives ["12", "labels", "3"]
+func pathSegments(path, prefix string) []string {
+	rest := strings.Trim(strings.TrimPrefix(path, prefix), "/")
+	if rest == "" {
+		return nil
+	}
+	return strings.Split(rest, "/")
+}
@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/go-playground/validator/v10"
+)
+
+// projectColumns lists the project columns in the order scanProject expects them
+const projectColumns = "id, workspace_id, name, color, archived, position, created_at, updated_at, (SELECT COUNT(*) FROM tasks WHERE tasks.project_id = projects.id AND tasks.deleted_at IS NULL)"
+
+// GetProjects lists the projects of the current workspace in their display
+// order. Archived projects are only included with ?include_archived=true.
+func GetProjects(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	includeArchived := false
+	if raw := r.URL.Query().Get("include_archived"); raw != "" {
+		var err error
+		if includeArchived, err = strconv.ParseBool(raw); err != nil {
+			config.WriteValidationErrorResponse(w, map[string]string{"include_archived": "include_archived must be true or false"})
+			return
+		}
+	}
+
+	query := "SELECT " + projectColumns + " FROM projects WHERE workspace_id = ?"
+	if !includeArchived {
+		query += " AND archived = FALSE"
+	}
+
+	rows, err := config.DB.Query(query+" ORDER BY position, id", membership.WorkspaceID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch projects", err)
+		return
+	}
+
+	defer rows.Close()
+
+	projects := []models.ProjectResponse{}
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to scan project", err)
+			return
+		}
+		projects = append(projects, project)
+	}
+
+	if err := rows.Err(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch projects", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Projects retrieved successfully", projects)
+}
+
+// GetOneProject retrieves a single project of the current workspace
+func GetOneProject(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get project ID from URL path
+	projectID := strings.TrimPrefix(r.URL.Path, "/api/v1/projects/")
+	if projectID == "" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Project ID is required", nil)
+		return
+	}
+
+	// Shared users need at least the viewer role
+	project, ok := authorizeProject(w, config.DB, membership, projectID, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Project retrieved successfully", project)
+}
+
+// GetProjectTasks lists the tasks of a project. It accepts the same query
+// parameters as GET /api/v1/tasks.
+func GetProjectTasks(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get project ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/projects/")
+	if len(segments) != 2 || segments[1] != "tasks" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Project ID is required", nil)
+		return
+	}
+
+	// Shared users need at least the viewer role
+	project, ok := authorizeProject(w, config.DB, membership, segments[0], models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	values := r.URL.Query()
+	values.Set("project_id", strconv.Itoa(project.ID))
+	listTasks(w, membership.UserID, "workspace_id = ?", []any{project.WorkspaceID}, values)
+}
+
+// CreateProject creates a new project in the current workspace. Projects
+// without a position are added after the existing ones.
+func CreateProject(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	req, ok := decodeProjectRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Insert project
+	result, err := config.DB.Exec(
+		"INSERT INTO projects (user_id, workspace_id, name, color, archived, position) VALUES (?, ?, ?, ?, ?, COALESCE(?, (SELECT COALESCE(MAX(position) + 1, 0) FROM projects WHERE workspace_id = ?)))",
+		membership.UserID,
+		membership.WorkspaceID,
+		req.Name,
+		projectColor(req.Color),
+		req.Archived,
+		req.Position,
+		membership.WorkspaceID,
+	)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create project", err)
+		return
+	}
+
+	// Get the last inserted ID
+	lastID, err := result.LastInsertId()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get project ID", err)
+		return
+	}
+
+	project, err := getProject(config.DB, membership.WorkspaceID, lastID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch created project", err)
+		return
+	}
+
+	config.WriteCreatedResponse(w, "Project created successfully", project)
+}
+
+// UpdateProject updates the name, colour, archived flag and position of a
+// project. The position is kept when it is left out.
+func UpdateProject(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get project ID from URL path
+	projectID := strings.TrimPrefix(r.URL.Path, "/api/v1/projects/")
+	if projectID == "" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Project ID is required", nil)
+		return
+	}
+
+	// Shared users need the editor role
+	project, ok := authorizeProject(w, config.DB, membership, projectID, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	req, ok := decodeProjectRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Update project
+	if _, err := config.DB.Exec(
+		"UPDATE projects SET name = ?, color = ?, archived = ?, position = COALESCE(?, position), updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+		req.Name,
+		projectColor(req.Color),
+		req.Archived,
+		req.Position,
+		project.ID,
+	); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update project", err)
+		return
+	}
+
+	project, err := getProject(config.DB, project.WorkspaceID, project.ID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch updated project", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Project updated successfully", project)
+}
+
+// DeleteProject deletes a project. Its tasks are moved to the inbox (no
+// project) by default; ?tasks=delete deletes them along with the project.
+func DeleteProject(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get project ID from URL path
+	projectID := strings.TrimPrefix(r.URL.Path, "/api/v1/projects/")
+	if projectID == "" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Project ID is required", nil)
+		return
+	}
+
+	mode := r.URL.Query().Get("tasks")
+	if mode == "" {
+		mode = "move"
+	}
+	if mode != "move" && mode != "delete" {
+		config.WriteValidationErrorResponse(w, map[string]string{"tasks": "tasks must be move or delete"})
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete project", err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Only the owner can delete a project
+	project, ok := authorizeProject(w, tx, membership, projectID, models.RoleOwner)
+	if !ok {
+		return
+	}
+
+	// Move the tasks to the inbox or to the trash
+	var affected int64
+	if mode == "delete" {
+		affected, err = trashTasks(tx, membership.UserID, "SELECT id FROM tasks WHERE project_id = ? AND deleted_at IS NULL", project.ID)
+	} else {
+		var result sql.Result
+		if result, err = tx.Exec("UPDATE tasks SET project_id = NULL, "+touchTaskSQL+" WHERE project_id = ? AND deleted_at IS NULL", project.ID); err == nil {
+			affected, err = result.RowsAffected()
+		}
+	}
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update project tasks", err)
+		return
+	}
+
+	// Tasks in the trash are restored to the inbox
+	if _, err := tx.Exec("UPDATE tasks SET project_id = NULL, "+touchTaskSQL+" WHERE project_id = ?", project.ID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update project tasks", err)
+		return
+	}
+
+	// Delete the project and its shares
+	if _, err := tx.Exec("DELETE FROM project_shares WHERE project_id = ?", project.ID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete project", err)
+		return
+	}
+
+	if _, err := tx.Exec("DELETE FROM projects WHERE id = ?", project.ID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete project", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete project", err)
+		return
+	}
+
+	key := "tasks_moved"
+	if mode == "delete" {
+		key = "tasks_deleted"
+	}
+	config.WriteSuccessResponse(w, "Project deleted successfully", map[string]int64{key: affected})
+}
+
+// decodeProjectRequest parses and validates a project request body, writing
+// the error response when it is invalid
+func decodeProjectRequest(w http.ResponseWriter, r *http.Request) (models.ProjectRequest, bool) {
+	var req models.ProjectRequest
+
+	// Check if request body is empty
+	if r.ContentLength == 0 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Request body is required", nil)
+		return req, false
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return req, false
+	}
+
+	// Validate input
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Validation failed", err)
+		return req, false
+	}
+
+	return req, true
+}
+
+// scanProject scans a row selected with projectColumns. Any extra
+// destinations are scanned from the columns that follow.
+func scanProject(row rowScanner, extra ...any) (models.ProjectResponse, error) {
+	var project models.ProjectResponse
+	dest := []any{
+		&project.ID,
+		&project.WorkspaceID,
+		&project.Name,
+		&project.Color,
+		&project.Archived,
+		&project.Position,
+		&project.CreatedAt,
+		&project.UpdatedAt,
+		&project.TaskCount,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	return project, err
+}
+
+// getProject fetches a single project of the workspace
+func getProject(q querier, workspaceID int, projectID any) (models.ProjectResponse, error) {
+	return scanProject(q.QueryRow("SELECT "+projectColumns+" FROM projects WHERE workspace_id = ? AND id = ?", workspaceID, projectID))
+}
+
+// checkTaskProject returns a validation error map when projectID is set but
+// does not belong to the workspace
+func checkTaskProject(q querier, workspaceID int, projectID *int) (map[string]string, error) {
+	if projectID == nil {
+		return nil, nil
+	}
+
+	var exists bool
+	if err := q.QueryRow("SELECT EXISTS(SELECT 1 FROM projects WHERE workspace_id = ? AND id = ?)", workspaceID, *projectID).Scan(&exists); err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return map[string]string{"project_id": "project_id must be a project of the same workspace"}, nil
+	}
+	return nil, nil
+}
+
+// projectColor returns the colour to store, falling back to the default colour
+func projectColor(color string) string {
+	if color == "" {
+		return models.DefaultProjectColor
+	}
+	return strings.ToLower(color)
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence frequencies supported from the iCalendar RRULE specification
+const (
+	freqDaily   = "DAILY"
+	freqWeekly  = "WEEKLY"
+	freqMonthly = "MONTHLY"
+)
+
+// maxRecurrenceSearch bounds how many periods are searched for the next
+// occurrence, so rules such as BYMONTHDAY=31 with a long interval terminate
+const maxRecurrenceSearch = 1000
+
+var rruleWeekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// recurrenceRule is a parsed subset of an iCalendar RRULE: FREQ=DAILY, WEEKLY
+// (with BYDAY) or MONTHLY (with BYMONTHDAY), INTERVAL and either UNTIL or COUNT
+type recurrenceRule struct {
+	freq       string
+	interval   int
+	byDay      []time.Weekday
+	byMonthDay []int
+	until      *time.Time
+	count      int
+}
+
+// parseRecurrenceRule parses a rule such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;COUNT=10".
+// An optional "RRULE:" prefix is accepted.
+func parseRecurrenceRule(raw string) (*recurrenceRule, error) {
+	rule := &recurrenceRule{interval: 1}
+	raw = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(raw)), "RRULE:")
+	seen := map[string]bool{}
+
+	for _, part := range strings.Split(raw, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("recurrence must be a list of NAME=VALUE parts separated by ;")
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("recurrence must not repeat %s", name)
+		}
+		seen[name] = true
+
+		switch name {
+		case "FREQ":
+			if value != freqDaily && value != freqWeekly && value != freqMonthly {
+				return nil, fmt.Errorf("FREQ must be DAILY, WEEKLY or MONTHLY")
+			}
+			rule.freq = value
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 || interval > 366 {
+				return nil, fmt.Errorf("INTERVAL must be a number between 1 and 366")
+			}
+			rule.interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("COUNT must be a positive number")
+			}
+			rule.count = count
+		case "UNTIL":
+			until, err := parseRRuleTime(value)
+			if err != nil {
+				return nil, err
+			}
+			rule.until = &until
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				weekday, ok := rruleWeekdays[day]
+				if !ok {
+					return nil, fmt.Errorf("BYDAY must be a list of MO, TU, WE, TH, FR, SA and SU")
+				}
+				rule.byDay = append(rule.byDay, weekday)
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(day)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("BYMONTHDAY must be a list of days between 1 and 31, or -1 to -31 counting from the end of the month")
+				}
+				rule.byMonthDay = append(rule.byMonthDay, n)
+			}
+		default:
+			return nil, fmt.Errorf("recurrence part %s is not supported", name)
+		}
+	}
+
+	switch {
+	case rule.freq == "":
+		return nil, fmt.Errorf("recurrence requires FREQ")
+	case rule.count > 0 && rule.until != nil:
+		return nil, fmt.Errorf("recurrence must not have both COUNT and UNTIL")
+	case len(rule.byDay) > 0 && rule.freq != freqWeekly:
+		return nil, fmt.Errorf("BYDAY is only supported with FREQ=WEEKLY")
+	case len(rule.byMonthDay) > 0 && rule.freq != freqMonthly:
+		return nil, fmt.Errorf("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+
+	return rule, nil
+}
+
+// parseRRuleTime parses an UNTIL value, either a UTC date-time or a date
+func parseRRuleTime(value string) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("20060102", value); err == nil {
+		// A date includes the whole day
+		return t.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("UNTIL must be a UTC date-time such as 20251231T235959Z or a date such as 20251231")
+}
+
+// String returns the canonical form of the rule, which is what gets stored
+func (rule *recurrenceRule) String() string {
+	parts := []string{"FREQ=" + rule.freq}
+	if rule.interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(rule.interval))
+	}
+
+	if len(rule.byDay) > 0 {
+		days := []string{}
+		for _, name := range []string{"MO", "TU", "WE", "TH", "FR", "SA", "SU"} {
+			for _, weekday := range rule.byDay {
+				if rruleWeekdays[name] == weekday {
+					days = append(days, name)
+					break
+				}
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+
+	if len(rule.byMonthDay) > 0 {
+		days := []string{}
+		for _, day := range rule.byMonthDay {
+			days = append(days, strconv.Itoa(day))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+
+	if rule.until != nil {
+		parts = append(parts, "UNTIL="+rule.until.UTC().Format("20060102T150405Z"))
+	}
+	if rule.count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(rule.count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// occurrencesAfter returns up to n occurrences following current, which is
+// occurrence number index of the series. Occurrences keep the wall clock time
+// of current in loc, so they stay at the same local time across DST changes.
+func (rule *recurrenceRule) occurrencesAfter(current time.Time, index, n int, loc *time.Location) []time.Time {
+	start := current.In(loc)
+	occurrences := []time.Time{}
+
+	for period := 0; period < maxRecurrenceSearch && len(occurrences) < n; period++ {
+		for _, candidate := range rule.periodCandidates(start, period*rule.interval) {
+			if !candidate.After(start) {
+				continue
+			}
+			if rule.until != nil && candidate.After(*rule.until) {
+				return occurrences
+			}
+			if rule.count > 0 && index+len(occurrences) >= rule.count {
+				return occurrences
+			}
+			occurrences = append(occurrences, candidate)
+			if len(occurrences) == n {
+				break
+			}
+		}
+	}
+
+	return occurrences
+}
+
+// periodCandidates returns the occurrences, in order, of the day, week or month
+// that is offset periods away from the one containing start
+func (rule *recurrenceRule) periodCandidates(start time.Time, offset int) []time.Time {
+	year, month, day := start.Date()
+	hour, minute, second := start.Clock()
+	loc := start.Location()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hour, minute, second, 0, loc)
+	}
+
+	switch rule.freq {
+	case freqDaily:
+		return []time.Time{at(year, month, day+offset)}
+
+	case freqWeekly:
+		weekdays := rule.byDay
+		if len(weekdays) == 0 {
+			weekdays = []time.Weekday{start.Weekday()}
+		}
+
+		// Weeks start on Monday
+		monday := day - (int(start.Weekday())+6)%7 + 7*offset
+		candidates := []time.Time{}
+		for _, weekday := range weekdays {
+			candidates = append(candidates, at(year, month, monday+(int(weekday)+6)%7))
+		}
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+		return candidates
+
+	default:
+		monthDays := rule.byMonthDay
+		if len(monthDays) == 0 {
+			monthDays = []int{day}
+		}
+
+		first := time.Date(year, month+time.Month(offset), 1, 0, 0, 0, 0, loc)
+		daysInMonth := time.Date(first.Year(), first.Month()+1, 0, 0, 0, 0, 0, loc).Day()
+
+		// Days that do not exist in the month are skipped
+		seen := map[int]bool{}
+		days := []int{}
+		for _, d := range monthDays {
+			if d < 0 {
+				d = daysInMonth + d + 1
+			}
+			if d < 1 || d > daysInMonth || seen[d] {
+				continue
+			}
+			seen[d] = true
+			days = append(days, d)
+		}
+		sort.Ints(days)
+
+		candidates := []time.Time{}
+		for _, d := range days {
+			candidates = append(candidates, at(first.Year(), first.Month(), d))
+		}
+		return candidates
+	}
+}
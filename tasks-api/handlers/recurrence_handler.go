@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+)
+
+const (
+	defaultOccurrencePreview = 5
+	maxOccurrencePreview     = 50
+)
+
+// GetTaskOccurrences previews the next occurrences of a recurring task:
+// GET /api/v1/tasks/{id}/occurrences?count=5
+func GetTaskOccurrences(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get task ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/tasks/")
+	if len(segments) != 2 || segments[1] != "occurrences" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Task ID is required", nil)
+		return
+	}
+
+	count := defaultOccurrencePreview
+	if raw := r.URL.Query().Get("count"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxOccurrencePreview {
+			config.WriteValidationErrorResponse(w, map[string]string{"count": fmt.Sprintf("count must be a number between 1 and %d", maxOccurrencePreview)})
+			return
+		}
+		count = n
+	}
+
+	// Shared users need at least the viewer role
+	task, ok := authorizeTask(w, config.DB, membership, segments[0], models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	if task.Recurrence == nil || task.DueAt == nil {
+		config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Task does not recur", nil)
+		return
+	}
+
+	rule, err := parseRecurrenceRule(*task.Recurrence)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to parse recurrence", err)
+		return
+	}
+
+	// Occurrences follow the owner's timezone
+	loc, err := userLocation(config.DB, task.UserID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load user timezone", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Occurrences retrieved successfully", models.TaskOccurrencesResponse{
+		Recurrence:  rule.String(),
+		Timezone:    loc.String(),
+		Occurrences: rule.occurrencesAfter(*task.DueAt, task.Occurrence, count, loc),
+	})
+}
+
+// taskRecurrence validates the recurrence of a task request and returns the
+// canonical rule to store, or nil when the task does not recur
+func taskRecurrence(req models.TaskRequest) (any, map[string]string) {
+	if req.Recurrence == "" {
+		return nil, nil
+	}
+
+	rule, err := parseRecurrenceRule(req.Recurrence)
+	if err != nil {
+		return nil, map[string]string{"recurrence": err.Error()}
+	}
+
+	// Occurrences repeat from the due date
+	if req.DueAt == nil {
+		return nil, map[string]string{"recurrence": "recurring tasks require due_at"}
+	}
+	return rule.String(), nil
+}
+
+// createNextOccurrence creates the occurrence that follows a recurring task,
+// copying its owner, workspace, details and labels. Occurrences are computed in
+// loc, the owner's timezone. It returns nil when the series has ended.
+func createNextOccurrence(q querier, task models.Task, loc *time.Location) (*models.TaskResponse, error) {
+	if task.Recurrence == nil || task.DueAt == nil {
+		return nil, nil
+	}
+
+	rule, err := parseRecurrenceRule(*task.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+
+	next := rule.occurrencesAfter(*task.DueAt, task.Occurrence, 1, loc)
+	if len(next) == 0 {
+		return nil, nil
+	}
+
+	result, err := q.Exec(
+		"INSERT INTO tasks (user_id, workspace_id, title, description, due_at, priority, project_id, parent_id, recurrence, occurrence) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		task.UserID,
+		task.WorkspaceID,
+		task.Title,
+		task.Description,
+		sqlTime(&next[0]),
+		task.Priority,
+		task.ProjectID,
+		task.ParentID,
+		task.Recurrence,
+		task.Occurrence+1,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	nextID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := q.Exec("INSERT INTO task_labels (task_id, label_id) SELECT ?, label_id FROM task_labels WHERE task_id = ?", nextID, task.ID); err != nil {
+		return nil, err
+	}
+
+	nextTask, err := getTask(q, task.WorkspaceID, nextID)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := taskResponseWithDetails(q, nextTask)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"encoding/binary"
+	"fmt"
+	"html"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	maxSearchTerms     = 10
+
+	// Highlight markers passed to SQLite; they are swapped for <mark> tags
+	// after the rest of the text has been HTML escaped
+	highlightStart = "\x02"
+	highlightEnd   = "\x03"
+	snippetTokens  = 16
+)
+
+// searchColumnWeights weights title matches above description matches
+var searchColumnWeights = []float64{2.0, 1.0}
+
+// SearchTasks runs a full-text search over the titles and descriptions of the
+// tasks of the current workspace. Every search term matches as a prefix and the
+// results are ordered by relevance.
+func SearchTasks(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Validate query parameters
+	errs := map[string]string{}
+	match := buildMatchQuery(r.URL.Query().Get("q"))
+	if match == "" {
+		errs["q"] = "q must contain at least one letter or digit"
+	}
+
+	limit := defaultSearchLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			errs["limit"] = fmt.Sprintf("limit must be a number between 1 and %d", maxSearchLimit)
+		} else {
+			limit = n
+		}
+	}
+
+	if len(errs) > 0 {
+		config.WriteValidationErrorResponse(w, errs)
+		return
+	}
+
+	// The index uses FTS5 when the driver was built with it and FTS4 otherwise
+	var definition string
+	if err := config.DB.QueryRow("SELECT sql FROM sqlite_master WHERE name = 'tasks_fts'").Scan(&definition); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to search tasks", err)
+		return
+	}
+
+	var results []models.TaskSearchResult
+	var err error
+	if strings.Contains(strings.ToLower(definition), "using fts5") {
+		results, err = searchTasksFTS5(membership.WorkspaceID, match, limit)
+	} else {
+		results, err = searchTasksFTS4(membership.WorkspaceID, match, limit)
+	}
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to search tasks", err)
+		return
+	}
+
+	// Attach the labels and progress of every matching task
+	tasks := make([]*models.TaskResponse, len(results))
+	for i := range results {
+		tasks[i] = &results[i].TaskResponse
+	}
+	if err := loadTaskDetails(config.DB, tasks); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch task details", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Tasks retrieved successfully", results)
+}
+
+// searchTasksFTS5 ranks matches with the built-in bm25 function
+func searchTasksFTS5(workspaceID int, match string, limit int) ([]models.TaskSearchResult, error) {
+	rows, err := config.DB.Query(
+		`SELECT `+qualifiedTaskColumns("t")+`,
+			-bm25(tasks_fts, ?, ?),
+			highlight(tasks_fts, 0, ?, ?),
+			snippet(tasks_fts, 1, ?, ?, '…', ?)
+		FROM tasks_fts
+		JOIN tasks t ON t.id = tasks_fts.rowid
+		WHERE tasks_fts MATCH ? AND t.workspace_id = ? AND t.deleted_at IS NULL
+		ORDER BY bm25(tasks_fts, ?, ?)
+		LIMIT ?`,
+		searchColumnWeights[0], searchColumnWeights[1],
+		highlightStart, highlightEnd,
+		highlightStart, highlightEnd, snippetTokens,
+		match, workspaceID,
+		searchColumnWeights[0], searchColumnWeights[1],
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.TaskSearchResult{}
+	for rows.Next() {
+		var result models.TaskSearchResult
+		task, err := scanTask(rows, &result.Rank, &result.TitleHighlight, &result.DescriptionSnippet)
+		if err != nil {
+			return nil, err
+		}
+		result.TaskResponse = newTaskResponse(task)
+		result.TitleHighlight = markHighlights(result.TitleHighlight)
+		result.DescriptionSnippet = markHighlights(result.DescriptionSnippet)
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+// searchTasksFTS4 ranks matches in Go because FTS4 has no ranking function;
+// matchinfo provides the statistics needed to compute bm25
+func searchTasksFTS4(workspaceID int, match string, limit int) ([]models.TaskSearchResult, error) {
+	rows, err := config.DB.Query(
+		`SELECT `+qualifiedTaskColumns("t")+`,
+			matchinfo(tasks_fts, 'pcnalx'),
+			snippet(tasks_fts, ?, ?, '…', 0, 64),
+			snippet(tasks_fts, ?, ?, '…', 1, ?)
+		FROM tasks_fts
+		JOIN tasks t ON t.id = tasks_fts.docid
+		WHERE tasks_fts MATCH ? AND t.workspace_id = ? AND t.deleted_at IS NULL`,
+		highlightStart, highlightEnd,
+		highlightStart, highlightEnd, snippetTokens,
+		match, workspaceID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.TaskSearchResult{}
+	for rows.Next() {
+		var result models.TaskSearchResult
+		var matchinfo []byte
+		task, err := scanTask(rows, &matchinfo, &result.TitleHighlight, &result.DescriptionSnippet)
+		if err != nil {
+			return nil, err
+		}
+		result.TaskResponse = newTaskResponse(task)
+		result.Rank = bm25FromMatchinfo(matchinfo, searchColumnWeights)
+		result.TitleHighlight = markHighlights(result.TitleHighlight)
+		result.DescriptionSnippet = markHighlights(result.DescriptionSnippet)
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Rank > results[j].Rank
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// bm25FromMatchinfo computes the Okapi BM25 score of a row from the output of
+// matchinfo(tbl, 'pcnalx'), using the same constants as FTS5
+func bm25FromMatchinfo(blob []byte, weights []float64) float64 {
+	const k1, b = 1.2, 0.75
+
+	info := make([]uint32, len(blob)/4)
+	for i := range info {
+		info[i] = binary.NativeEndian.Uint32(blob[i*4:])
+	}
+	if len(info) < 3 {
+		return 0
+	}
+
+	phrases, columns, rows := int(info[0]), int(info[1]), float64(info[2])
+	avgLength := info[3 : 3+columns]
+	length := info[3+columns : 3+2*columns]
+	hits := info[3+2*columns:]
+
+	score := 0.0
+	for p := 0; p < phrases; p++ {
+		// Number of rows containing the phrase in any column
+		containing := 0.0
+		for c := 0; c < columns; c++ {
+			containing = math.Max(containing, float64(hits[3*(p*columns+c)+2]))
+		}
+		idf := math.Max(math.Log((rows-containing+0.5)/(containing+0.5)), 1e-6)
+
+		for c := 0; c < columns && c < len(weights); c++ {
+			tf := float64(hits[3*(p*columns+c)])
+			if tf == 0 || avgLength[c] == 0 {
+				continue
+			}
+			norm := 1 - b + b*float64(length[c])/float64(avgLength[c])
+			score += weights[c] * idf * tf * (k1 + 1) / (tf + k1*norm)
+		}
+	}
+	return score
+}
+
+// buildMatchQuery turns free text into a match expression where every term
+// is a prefix query. FTS syntax characters in the input are dropped.
+func buildMatchQuery(q string) string {
+	terms := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+
+	for i, term := range terms {
+		terms[i] = term + "*"
+	}
+	return strings.Join(terms, " ")
+}
+
+// markHighlights HTML escapes text and wraps the highlighted terms in <mark> tags
+func markHighlights(text string) string {
+	text = html.EscapeString(text)
+	text = strings.ReplaceAll(text, highlightStart, "<mark>")
+	return strings.ReplaceAll(text, highlightEnd, "</mark>")
+}
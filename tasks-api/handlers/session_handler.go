@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+)
+
+// Logout revokes the session used to authenticate the request
+func Logout(w http.ResponseWriter, r *http.Request) {
+	// Check that method is post
+	if r.Method != http.MethodPost {
+		config.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed. Use POST", nil)
+		return
+	}
+
+	// Get session ID from context
+	sessionID, ok := middleware.GetSessionIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Session ID not found in context", fmt.Errorf("session id not found in context"))
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	// Delete the current session together with its refresh tokens
+	if _, err := revokeSession(userID, sessionID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to log out", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Logout successful", nil)
+}
+
+// GetSessions lists the active sessions of the authenticated user
+func GetSessions(w http.ResponseWriter, r *http.Request) {
+	// Get user ID and session ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+	currentSessionID, _ := middleware.GetSessionIDFromContext(r)
+
+	// Fetch sessions that have not yet expired
+	rows, err := config.DB.Query(
+		"SELECT id, created_at, expires_at, last_used_at, user_agent, ip_address FROM sessions WHERE user_id = ? AND expires_at > ? ORDER BY created_at DESC",
+		userID,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch sessions", err)
+		return
+	}
+
+	defer rows.Close()
+
+	sessions := []models.SessionResponse{}
+	for rows.Next() {
+		var session models.SessionResponse
+		if err := rows.Scan(&session.ID, &session.CreatedAt, &session.ExpiresAt, &session.LastUsedAt, &session.UserAgent, &session.IPAddress); err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to scan session", err)
+			return
+		}
+		session.Current = session.ID == currentSessionID
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch sessions", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Sessions retrieved successfully", sessions)
+}
+
+// DeleteSession revokes a single session belonging to the authenticated user
+func DeleteSession(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	// Get session ID from URL path
+	path := r.URL.Path
+	sessionID := strings.TrimPrefix(path, "/api/v1/sessions/")
+	if sessionID == "" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Session ID is required", nil)
+		return
+	}
+
+	id, err := strconv.Atoi(sessionID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusNotFound, "Session not found", nil)
+		return
+	}
+
+	// Delete the session together with its refresh tokens
+	found, err := revokeSession(userID, id)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to revoke session", err)
+		return
+	}
+
+	if !found {
+		config.WriteErrorResponse(w, http.StatusNotFound, "Session not found", nil)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Session revoked successfully", nil)
+}
+
+// DeleteAllSessions revokes every session of the authenticated user ("log out everywhere")
+func DeleteAllSessions(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	// Delete all sessions and refresh tokens of the user
+	affected, err := revokeAllSessions(config.DB, userID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to revoke sessions", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "All sessions revoked successfully", map[string]int64{"revoked": affected})
+}
+
+// CleanupExpiredSessions deletes every session and refresh token whose expiry has passed
+func CleanupExpiredSessions() (int64, error) {
+	now := time.Now().UTC()
+
+	result, err := config.DB.Exec("DELETE FROM sessions WHERE expires_at <= ?", now)
+	if err != nil {
+		return 0, err
+	}
+
+	sessions, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	result, err = config.DB.Exec("DELETE FROM refresh_tokens WHERE expires_at <= ?", now)
+	if err != nil {
+		return 0, err
+	}
+
+	refreshTokens, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return sessions + refreshTokens, nil
+}
+
+// revokeSession deletes a session of the user and revokes its token family.
+// It reports whether the session existed.
+func revokeSession(userID, sessionID int) (bool, error) {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var familyID string
+	err = tx.QueryRow("SELECT family_id FROM sessions WHERE user_id = ? AND id = ?", userID, sessionID).Scan(&familyID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM sessions WHERE id = ?", sessionID); err != nil {
+		return false, err
+	}
+
+	if err := revokeTokenFamily(tx, familyID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// revokeAllSessions deletes every session of the user and revokes all of its
+// refresh tokens. It returns the number of sessions that were deleted.
+func revokeAllSessions(q querier, userID int) (int64, error) {
+	result, err := q.Exec("DELETE FROM sessions WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := q.Exec(
+		"UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL",
+		time.Now().UTC(),
+		userID,
+	); err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/go-playground/validator/v10"
+)
+
+// shareTarget describes a resource that can be shared: its shares table, the
+// column referencing it and how to check the caller's role on it
+type shareTarget struct {
+	name       string
+	pathPrefix string
+	table      string
+	column     string
+	authorize  func(w http.ResponseWriter, q querier, membership models.WorkspaceMembership, id any, minRole string) (int, bool)
+}
+
+var taskShareTarget = shareTarget{
+	name:       "Task",
+	pathPrefix: "/api/v1/tasks/",
+	table:      "task_shares",
+	column:     "task_id",
+	authorize: func(w http.ResponseWriter, q querier, membership models.WorkspaceMembership, id any, minRole string) (int, bool) {
+		task, ok := authorizeTask(w, q, membership, id, minRole)
+		return task.ID, ok
+	},
+}
+
+var projectShareTarget = shareTarget{
+	name:       "Project",
+	pathPrefix: "/api/v1/projects/",
+	table:      "project_shares",
+	column:     "project_id",
+	authorize: func(w http.ResponseWriter, q querier, membership models.WorkspaceMembership, id any, minRole string) (int, bool) {
+		project, ok := authorizeProject(w, q, membership, id, minRole)
+		return project.ID, ok
+	},
+}
+
+// ShareTask shares a task with another user: POST /api/v1/tasks/{id}/shares
+func ShareTask(w http.ResponseWriter, r *http.Request) {
+	createShare(w, r, taskShareTarget)
+}
+
+// GetTaskShares lists who a task is shared with: GET /api/v1/tasks/{id}/shares
+func GetTaskShares(w http.ResponseWriter, r *http.Request) {
+	listShares(w, r, taskShareTarget)
+}
+
+// RevokeTaskShare revokes a user's access to a task:
+// DELETE /api/v1/tasks/{id}/shares/{user_id}
+func RevokeTaskShare(w http.ResponseWriter, r *http.Request) {
+	revokeShare(w, r, taskShareTarget)
+}
+
+// ShareProject shares a project, and with it all of its tasks, with another
+// user: POST /api/v1/projects/{id}/shares
+func ShareProject(w http.ResponseWriter, r *http.Request) {
+	createShare(w, r, projectShareTarget)
+}
+
+// GetProjectShares lists who a project is shared with: GET /api/v1/projects/{id}/shares
+func GetProjectShares(w http.ResponseWriter, r *http.Request) {
+	listShares(w, r, projectShareTarget)
+}
+
+// RevokeProjectShare revokes a user's access to a project:
+// DELETE /api/v1/projects/{id}/shares/{user_id}
+func RevokeProjectShare(w http.ResponseWriter, r *http.Request) {
+	revokeShare(w, r, projectShareTarget)
+}
+
+// GetSharedTasks lists the tasks other users have shared with the authenticated
+// user, directly or through a project. It accepts the same query parameters
+// as GET /api/v1/tasks.
+func GetSharedTasks(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	scope := "user_id != ? AND (id IN (SELECT task_id FROM task_shares WHERE user_id = ?) OR project_id IN (SELECT project_id FROM project_shares WHERE user_id = ?))"
+	listTasks(w, userID, scope, []any{userID, userID, userID}, r.URL.Query())
+}
+
+// GetSharedProjects lists the projects other users have shared with the
+// authenticated user
+func GetSharedProjects(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	rows, err := config.DB.Query(
+		"SELECT "+projectColumns+","+
+			" (SELECT role FROM project_shares WHERE project_shares.project_id = projects.id AND project_shares.user_id = ?),"+
+			" (SELECT name FROM users WHERE users.id = projects.user_id),"+
+			" (SELECT email FROM users WHERE users.id = projects.user_id)"+
+			" FROM projects WHERE id IN (SELECT project_id FROM project_shares WHERE user_id = ?) ORDER BY name COLLATE NOCASE, id",
+		userID,
+		userID,
+	)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch shared projects", err)
+		return
+	}
+
+	defer rows.Close()
+
+	projects := []models.SharedProjectResponse{}
+	for rows.Next() {
+		var shared models.SharedProjectResponse
+		project, err := scanProject(rows, &shared.Role, &shared.OwnerName, &shared.OwnerEmail)
+		if err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to scan project", err)
+			return
+		}
+		shared.ProjectResponse = project
+		projects = append(projects, shared)
+	}
+
+	if err := rows.Err(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch shared projects", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Shared projects retrieved successfully", projects)
+}
+
+// createShare shares the target with the user registered under the request
+// email. Sharing again with the same user changes their role.
+func createShare(w http.ResponseWriter, r *http.Request, target shareTarget) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get resource ID from URL path
+	segments := pathSegments(r.URL.Path, target.pathPrefix)
+	if len(segments) != 2 || segments[1] != "shares" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, target.name+" ID is required", nil)
+		return
+	}
+
+	// Only the owner can share
+	resourceID, ok := target.authorize(w, config.DB, membership, segments[0], models.RoleOwner)
+	if !ok {
+		return
+	}
+
+	// Check if request body is empty
+	if r.ContentLength == 0 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Request body is required", nil)
+		return
+	}
+
+	// Parse request body
+	var req models.ShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	// Validate input
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Validation failed", err)
+		return
+	}
+
+	// Find the user to share with. Unknown emails and the owner's own email
+	// get the same answer, so sharing cannot be used to find out whether an
+	// email has an account.
+	var share models.ShareResponse
+	err := config.DB.QueryRow("SELECT id, name, email FROM users WHERE email = ? COLLATE NOCASE", strings.TrimSpace(req.Email)).
+		Scan(&share.UserID, &share.Name, &share.Email)
+	if err != nil && err != sql.ErrNoRows {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch user", err)
+		return
+	}
+
+	if err == sql.ErrNoRows || share.UserID == membership.UserID {
+		config.WriteValidationErrorResponse(w, map[string]string{"email": "Cannot share with this email"})
+		return
+	}
+
+	if _, err := config.DB.Exec(
+		"INSERT INTO "+target.table+" ("+target.column+", user_id, role, shared_by) VALUES (?, ?, ?, ?)"+
+			" ON CONFLICT ("+target.column+", user_id) DO UPDATE SET role = excluded.role",
+		resourceID,
+		share.UserID,
+		req.Role,
+		membership.UserID,
+	); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to share "+strings.ToLower(target.name), err)
+		return
+	}
+
+	if err := config.DB.QueryRow(
+		"SELECT role, created_at FROM "+target.table+" WHERE "+target.column+" = ? AND user_id = ?",
+		resourceID,
+		share.UserID,
+	).Scan(&share.Role, &share.CreatedAt); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch share", err)
+		return
+	}
+
+	config.WriteCreatedResponse(w, target.name+" shared successfully", share)
+}
+
+// listShares lists the users the target is shared with
+func listShares(w http.ResponseWriter, r *http.Request, target shareTarget) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get resource ID from URL path
+	segments := pathSegments(r.URL.Path, target.pathPrefix)
+	if len(segments) != 2 || segments[1] != "shares" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, target.name+" ID is required", nil)
+		return
+	}
+
+	// Only the owner can see who has access
+	resourceID, ok := target.authorize(w, config.DB, membership, segments[0], models.RoleOwner)
+	if !ok {
+		return
+	}
+
+	rows, err := config.DB.Query(
+		"SELECT users.id, users.name, users.email, s.role, s.created_at FROM "+target.table+" s"+
+			" JOIN users ON users.id = s.user_id WHERE s."+target.column+" = ? ORDER BY s.created_at, users.id",
+		resourceID,
+	)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch shares", err)
+		return
+	}
+
+	defer rows.Close()
+
+	shares := []models.ShareResponse{}
+	for rows.Next() {
+		var share models.ShareResponse
+		if err := rows.Scan(&share.UserID, &share.Name, &share.Email, &share.Role, &share.CreatedAt); err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to scan share", err)
+			return
+		}
+		shares = append(shares, share)
+	}
+
+	if err := rows.Err(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch shares", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Shares retrieved successfully", shares)
+}
+
+// revokeShare removes a user's access to the target. The owner can revoke
+// anyone's access and shared users can remove their own.
+func revokeShare(w http.ResponseWriter, r *http.Request, target shareTarget) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get resource ID and user ID from URL path
+	segments := pathSegments(r.URL.Path, target.pathPrefix)
+	if len(segments) != 3 || segments[1] != "shares" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, target.name+" ID and user ID are required", nil)
+		return
+	}
+
+	minRole := models.RoleOwner
+	if segments[2] == fmt.Sprint(membership.UserID) {
+		minRole = models.RoleViewer
+	}
+
+	resourceID, ok := target.authorize(w, config.DB, membership, segments[0], minRole)
+	if !ok {
+		return
+	}
+
+	result, err := config.DB.Exec("DELETE FROM "+target.table+" WHERE "+target.column+" = ? AND user_id = ?", resourceID, segments[2])
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to revoke share", err)
+		return
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to revoke share", err)
+		return
+	}
+
+	if affected == 0 {
+		config.WriteErrorResponse(w, http.StatusNotFound, "Share not found", nil)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Access revoked successfully", nil)
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+)
+
+// GetTaskSubtree returns a task with all of its subtasks, nested to any depth:
+// GET /api/v1/tasks/{id}/subtree
+func GetTaskSubtree(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get task ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/tasks/")
+	if len(segments) != 2 || segments[1] != "subtree" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Task ID is required", nil)
+		return
+	}
+
+	// Shared users need at least the viewer role
+	root, ok := authorizeTask(w, config.DB, membership, segments[0], models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	// Fetch every descendant in one recursive query
+	rows, err := config.DB.Query(
+		`WITH RECURSIVE subtree(id) AS (
+			SELECT id FROM tasks WHERE parent_id = ?
+			UNION
+			SELECT t.id FROM tasks t JOIN subtree s ON t.parent_id = s.id
+		)
+		SELECT `+taskColumns+` FROM tasks
+		WHERE workspace_id = ? AND deleted_at IS NULL AND id IN (SELECT id FROM subtree)
+		ORDER BY created_at, id`,
+		root.ID,
+		root.WorkspaceID,
+	)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch subtasks", err)
+		return
+	}
+
+	defer rows.Close()
+
+	tasks := []models.TaskResponse{newTaskResponse(root)}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to scan task", err)
+			return
+		}
+		tasks = append(tasks, newTaskResponse(task))
+	}
+
+	if err := rows.Err(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch subtasks", err)
+		return
+	}
+
+	if err := loadTaskDetails(config.DB, taskResponsePointers(tasks)); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch task details", err)
+		return
+	}
+
+	// Link every task to its parent
+	nodes := map[int]*models.TaskTreeNode{}
+	for _, task := range tasks {
+		nodes[task.ID] = &models.TaskTreeNode{TaskResponse: task, Subtasks: []*models.TaskTreeNode{}}
+	}
+	for _, task := range tasks[1:] {
+		if parent, ok := nodes[*task.ParentID]; ok {
+			parent.Subtasks = append(parent.Subtasks, nodes[task.ID])
+		}
+	}
+
+	config.WriteSuccessResponse(w, "Task subtree retrieved successfully", nodes[root.ID])
+}
+
+// checkTaskParent returns a validation error map when parentID is set but is
+// not a task of the workspace, or would make the task its own ancestor.
+// taskID is 0 for tasks that do not exist yet.
+func checkTaskParent(q querier, workspaceID, taskID int, parentID *int) (map[string]string, error) {
+	if parentID == nil {
+		return nil, nil
+	}
+
+	var exists bool
+	if err := q.QueryRow("SELECT EXISTS(SELECT 1 FROM tasks WHERE workspace_id = ? AND id = ? AND deleted_at IS NULL)", workspaceID, *parentID).Scan(&exists); err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return map[string]string{"parent_id": "parent_id must be a task of the same workspace"}, nil
+	}
+
+	if taskID == 0 {
+		return nil, nil
+	}
+
+	// Walk up from the new parent; reaching the task means a cycle
+	var cycle bool
+	if err := q.QueryRow(
+		`WITH RECURSIVE ancestors(id) AS (
+			SELECT ?
+			UNION
+			SELECT t.parent_id FROM tasks t JOIN ancestors a ON t.id = a.id WHERE t.parent_id IS NOT NULL
+		)
+		SELECT EXISTS(SELECT 1 FROM ancestors WHERE id = ?)`,
+		*parentID,
+		taskID,
+	).Scan(&cycle); err != nil {
+		return nil, err
+	}
+
+	if cycle {
+		return map[string]string{"parent_id": "parent_id must not be the task itself or one of its subtasks"}, nil
+	}
+	return nil, nil
+}
+
+// loadTaskProgress fills in the subtask progress of every task with a single query
+func loadTaskProgress(q querier, tasks []*models.TaskResponse) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	byID := map[int]*models.TaskResponse{}
+	ids := []int{}
+	for _, task := range tasks {
+		task.Progress = nil
+		byID[task.ID] = task
+		ids = append(ids, task.ID)
+	}
+
+	placeholders, args := inClause(ids)
+	rows, err := q.Query(
+		"SELECT parent_id, SUM(CASE WHEN completed THEN 1 ELSE 0 END), COUNT(*) FROM tasks WHERE parent_id IN ("+placeholders+") AND deleted_at IS NULL GROUP BY parent_id",
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var parentID int
+		var progress models.TaskProgress
+		if err := rows.Scan(&parentID, &progress.Completed, &progress.Total); err != nil {
+			return err
+		}
+		byID[parentID].Progress = &progress
+	}
+
+	return rows.Err()
+}
@@ -1,11 +1,12 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/eokwukwe/golearn/tasks/config"
 	"github.com/eokwukwe/golearn/tasks/middleware"
@@ -13,17 +14,57 @@ import (
 	"github.com/go-playground/validator/v10"
 )
 
-// GetTasks retrieves all tasks for the authenticated user
+// GetTasks retrieves a page of tasks of the current workspace.
+// Supports ?limit=&cursor= pagination, completed/created_before/created_after/
+// updated_since filters and ?sort=title,-updated_at ordering.
 func GetTasks(w http.ResponseWriter, r *http.Request) {
-	// Get user ID from context
-	userID, ok := middleware.GetUserIDFromContext(r)
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
 	if !ok {
-		config.WriteErrorResponse(w, http.StatusInternalServerError, "user ID not found in context", fmt.Errorf("user id not found in context"))
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
 		return
 	}
 
-	// Fetch tasks for the user
-	rows, err := config.DB.Query("SELECT id, title, description, created_at, updated_at, completed FROM tasks WHERE user_id = ? ORDER BY created_at DESC", userID)
+	listTasks(w, membership.UserID, "workspace_id = ?", []any{membership.WorkspaceID}, r.URL.Query())
+}
+
+// listTasks writes the page of tasks described by the query parameters,
+// limited to the tasks matching scope. Due views use userID's timezone.
+func listTasks(w http.ResponseWriter, userID int, scope string, scopeArgs []any, values url.Values) {
+	// The due views need the user's timezone
+	loc := time.UTC
+	if values.Get("due") != "" {
+		var err error
+		if loc, err = userLocation(config.DB, userID); err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load user timezone", err)
+			return
+		}
+	}
+
+	// Validate pagination, filter and sort parameters
+	query, errs := parseTaskListQuery(values, time.Now(), loc)
+	if errs != nil {
+		config.WriteValidationErrorResponse(w, errs)
+		return
+	}
+
+	// Build the query; tasks in the trash are never listed
+	where := append([]string{scope, "deleted_at IS NULL"}, query.where...)
+	args := append(append([]any{}, scopeArgs...), query.args...)
+	if query.cursor != nil {
+		cond, condArgs := query.keysetCondition()
+		where = append(where, cond)
+		args = append(args, condArgs...)
+	}
+	args = append(args, query.limit+1)
+
+	// Fetch one extra task to know whether there is a next page
+	rows, err := config.DB.Query(
+		"SELECT "+taskColumns+", "+query.sortKeyColumns()+
+			" FROM tasks WHERE "+strings.Join(where, " AND ")+
+			" ORDER BY "+query.orderBy()+" LIMIT ?",
+		args...,
+	)
 	if err != nil {
 		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch tasks", err)
 		return
@@ -31,14 +72,26 @@ func GetTasks(w http.ResponseWriter, r *http.Request) {
 
 	defer rows.Close()
 
-	tasks := []models.Task{}
+	tasks := []models.TaskResponse{}
+	var lastKeys []string
+	hasMore := false
 	for rows.Next() {
-		var task models.Task
-		if err := rows.Scan(&task.ID, &task.Title, &task.Description, &task.CreatedAt, &task.UpdatedAt, &task.Completed); err != nil {
+		keys := make([]string, len(query.fields))
+		dest := []any{}
+		for i := range keys {
+			dest = append(dest, &keys[i])
+		}
+		task, err := scanTask(rows, dest...)
+		if err != nil {
 			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to scan task", err)
 			return
 		}
-		tasks = append(tasks, task)
+		if len(tasks) == query.limit {
+			hasMore = true
+			break
+		}
+		tasks = append(tasks, newTaskResponse(task))
+		lastKeys = keys
 	}
 
 	if err := rows.Err(); err != nil {
@@ -46,15 +99,31 @@ func GetTasks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	config.WriteSuccessResponse(w, "Tasks retrieved successfully", tasks)
+	// Attach the labels and progress of every task on the page
+	if err := loadTaskDetails(config.DB, taskResponsePointers(tasks)); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch task details", err)
+		return
+	}
+
+	// Only hand out a cursor when more tasks are available
+	nextCursor := ""
+	if hasMore {
+		nextCursor = encodeTaskCursor(taskCursor{
+			Sort: query.sort,
+			Keys: lastKeys,
+			ID:   tasks[len(tasks)-1].ID,
+		})
+	}
+
+	config.WritePaginatedResponse(w, "Tasks retrieved successfully", tasks, query.limit, nextCursor)
 }
 
 // GetOneTask retrieves a single task for the authenticated user
 func GetOneTask(w http.ResponseWriter, r *http.Request) {
-	// Get user ID from context
-	userID, ok := middleware.GetUserIDFromContext(r)
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
 	if !ok {
-		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
 		return
 	}
 
@@ -66,43 +135,34 @@ func GetOneTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Fetch the task
-	var task models.Task
-	err := config.DB.QueryRow("SELECT id, title, description, created_at, updated_at, completed FROM tasks WHERE user_id = ? AND id = ?", userID, taskID).Scan(
-		&task.ID,
-		&task.Title,
-		&task.Description,
-		&task.CreatedAt,
-		&task.UpdatedAt,
-		&task.Completed,
-	)
+	// Fetch the task; shared users need at least the viewer role
+	task, ok := authorizeTask(w, config.DB, membership, taskID, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	// Let clients skip downloading a version of the task they already have
+	w.Header().Set("ETag", taskETag(task))
+	if taskNotModified(w, r, task) {
+		return
+	}
 
+	response, err := taskResponseWithDetails(config.DB, task)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			config.WriteErrorResponse(w, http.StatusNotFound, "Task not found", nil)
-			return
-		}
-		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch task", err)
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch task details", err)
 		return
 	}
 
 	// Return success response
-	config.WriteSuccessResponse(w, "Task retrieved successfully", models.TaskResponse{
-		ID:          task.ID,
-		Title:       task.Title,
-		Description: task.Description,
-		CreatedAt:   task.CreatedAt,
-		UpdatedAt:   task.UpdatedAt,
-		Completed:   task.Completed,
-	})
+	config.WriteSuccessResponse(w, "Task retrieved successfully", response)
 }
 
-// DeleteTask deletes a single task for the authenticated user
+// DeleteTask moves a single task to the trash
 func DeleteTask(w http.ResponseWriter, r *http.Request) {
-	// Get user ID from context
-	userID, ok := middleware.GetUserIDFromContext(r)
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
 	if !ok {
-		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
 		return
 	}
 
@@ -114,22 +174,26 @@ func DeleteTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if task exists
-	var exists bool
-	err := config.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM tasks WHERE user_id = ? AND id = ?)", userID, taskID).Scan(&exists)
+	// Only the owner can delete a task
+	task, ok := authorizeTask(w, config.DB, membership, taskID, models.RoleOwner)
+	if !ok {
+		return
+	}
+
+	tx, err := config.DB.Begin()
 	if err != nil {
-		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to check task existence", err)
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete task", err)
 		return
 	}
+	defer tx.Rollback()
 
-	if !exists {
-		config.WriteErrorResponse(w, http.StatusNotFound, "Task not found", nil)
+	// Move the task to the trash; everything attached to it is kept until it is purged
+	if _, err := trashTasks(tx, membership.UserID, "?", task.ID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete task", err)
 		return
 	}
 
-	// Delete the task
-	_, err = config.DB.Exec("DELETE FROM tasks WHERE user_id = ? AND id = ?", userID, taskID)
-	if err != nil {
+	if err := tx.Commit(); err != nil {
 		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete task", err)
 		return
 	}
@@ -140,10 +204,10 @@ func DeleteTask(w http.ResponseWriter, r *http.Request) {
 
 // UpdateTask updates a task for the authenticated user
 func UpdateTask(w http.ResponseWriter, r *http.Request) {
-	// Get user ID from context
-	userID, ok := middleware.GetUserIDFromContext(r)
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
 	if !ok {
-		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
 		return
 	}
 
@@ -155,16 +219,14 @@ func UpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if task exists
-	var exists bool
-	err := config.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM tasks WHERE user_id = ? AND id = ?)", userID, taskID).Scan(&exists)
-	if err != nil {
-		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to check task existence", err)
+	// Shared users need the editor role
+	task, ok := authorizeTask(w, config.DB, membership, taskID, models.RoleEditor)
+	if !ok {
 		return
 	}
 
-	if !exists {
-		config.WriteErrorResponse(w, http.StatusNotFound, "Task not found", nil)
+	// Refuse to overwrite changes the client has not seen
+	if !checkTaskIfMatch(w, r, task) {
 		return
 	}
 
@@ -188,95 +250,325 @@ func UpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recurrence, errs := taskRecurrence(req)
+	if errs != nil {
+		config.WriteValidationErrorResponse(w, errs)
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update task", err)
+		return
+	}
+	defer tx.Rollback()
+
+	task, ok = saveTask(w, tx, membership.UserID, task, req, recurrence, ifMatchVersion(r, task))
+	if !ok {
+		return
+	}
+
+	response, err := taskResponseWithDetails(tx, task)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch task details", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update task", err)
+		return
+	}
+
+	// Return success response with the complete task
+	w.Header().Set("ETag", taskETag(task))
+	config.WriteSuccessResponse(w, "Task updated successfully", response)
+}
+
+// saveTask replaces the fields of a task with req inside tx and records the
+// change in the task's history. version limits the update to that version of
+// the task, see ifMatchVersion. It returns the updated task, or writes the
+// error response and returns false.
+func saveTask(w http.ResponseWriter, tx querier, actorID int, task models.Task, req models.TaskRequest, recurrence any, version any) (models.Task, bool) {
+	// Make sure the project belongs to the workspace
+	if errs, err := checkTaskProject(tx, task.WorkspaceID, req.ProjectID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to check project", err)
+		return task, false
+	} else if errs != nil {
+		config.WriteValidationErrorResponse(w, errs)
+		return task, false
+	}
+
+	// Make sure the parent task belongs to the workspace and is not a subtask of this task
+	if errs, err := checkTaskParent(tx, task.WorkspaceID, task.ID, req.ParentID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to check parent task", err)
+		return task, false
+	} else if errs != nil {
+		config.WriteValidationErrorResponse(w, errs)
+		return task, false
+	}
+
+	// Remember the current values for the task's history
+	before, err := taskSnapshot(tx, task)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to record task history", err)
+		return task, false
+	}
+
 	// Update task
-	_, err = config.DB.Exec(
-		"UPDATE tasks SET title = ?, description = ? WHERE user_id = ? AND id = ?",
+	result, err := tx.Exec(
+		"UPDATE tasks SET title = ?, description = ?, due_at = ?, priority = ?, project_id = ?, parent_id = ?, recurrence = ?, occurrence = CASE WHEN recurrence IS ? THEN occurrence ELSE 1 END, "+touchTaskSQL+" WHERE id = ? AND (? IS NULL OR version = ?)",
 		req.Title,
 		req.Description,
-		userID,
-		taskID,
+		sqlTime(req.DueAt),
+		taskPriority(req.Priority),
+		req.ProjectID,
+		req.ParentID,
+		recurrence,
+		recurrence,
+		task.ID,
+		version,
+		version,
 	)
 	if err != nil {
 		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update task", err)
-		return
+		return task, false
 	}
 
-	// Fetch the created task
-	var task models.Task
-	if err := config.DB.QueryRow("SELECT id, title, description, created_at, updated_at, completed FROM tasks WHERE id = ?", taskID).Scan(
-		&task.ID,
-		&task.Title,
-		&task.Description,
-		&task.CreatedAt,
-		&task.UpdatedAt,
-		&task.Completed,
-	); err != nil {
-		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch created task", err)
-		return
+	// The task changed after the If-Match check
+	if affected, err := result.RowsAffected(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update task", err)
+		return task, false
+	} else if affected == 0 {
+		config.WriteErrorResponse(w, http.StatusPreconditionFailed, "Task has been modified since it was fetched", nil)
+		return task, false
 	}
 
-	// Return success response with the complete task
-	config.WriteSuccessResponse(w, "Task updated successfully", models.TaskResponse{
-		ID:          task.ID,
-		Title:       task.Title,
-		Description: task.Description,
-		CreatedAt:   task.CreatedAt,
-		UpdatedAt:   task.UpdatedAt,
-		Completed:   task.Completed,
-	})
+	// Labels are resolved among the owner's labels
+	ownerID := task.UserID
+
+	// Fetch the task
+	task, err = getTask(tx, task.WorkspaceID, task.ID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch updated task", err)
+		return task, false
+	}
+
+	// Replace the labels when label_ids is present; leave them alone otherwise
+	if req.LabelIDs != nil {
+		errs, err := setTaskLabels(tx, ownerID, task.ID, req.LabelIDs)
+		if err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update task labels", err)
+			return task, false
+		}
+		if errs != nil {
+			config.WriteValidationErrorResponse(w, errs)
+			return task, false
+		}
+	}
+
+	// Record the changed fields in the task's history
+	if err := recordTaskSnapshotEvent(tx, actorID, models.TaskEventUpdated, task, before); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to record task history", err)
+		return task, false
+	}
+
+	return task, true
 }
 
-// CompleteTask marks a task as completed for the authenticated user
+// CompleteTask marks a task as completed for the authenticated user:
+// POST /api/v1/tasks/{id}/complete, or PATCH /api/v1/tasks/{id} without a body
 func CompleteTask(w http.ResponseWriter, r *http.Request) {
-	// Get user ID from context
-	userID, ok := middleware.GetUserIDFromContext(r)
+	changeTaskCompletion(w, r, true)
+}
+
+// ReopenTask marks a completed task as not completed: POST /api/v1/tasks/{id}/reopen
+func ReopenTask(w http.ResponseWriter, r *http.Request) {
+	changeTaskCompletion(w, r, false)
+}
+
+// changeTaskCompletion completes or reopens the task from the URL
+func changeTaskCompletion(w http.ResponseWriter, r *http.Request, completed bool) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
 	if !ok {
-		http.Error(w, "User ID not found in context", http.StatusInternalServerError)
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
 		return
 	}
 
-	// Get task ID from URL path
-	path := r.URL.Path
-	taskID := strings.TrimPrefix(path, "/api/v1/tasks/")
-	if taskID == "" {
+	// Get task ID from URL path; the action segment is optional
+	segments := pathSegments(r.URL.Path, "/api/v1/tasks/")
+	if len(segments) == 0 || len(segments) > 2 {
 		config.WriteErrorResponse(w, http.StatusBadRequest, "Task ID is required", nil)
 		return
 	}
 
-	// Check if task exists
-	var exists bool
-	err := config.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM tasks WHERE user_id = ? AND id = ?)", userID, taskID).Scan(&exists)
+	// Shared users need the editor role
+	task, ok := authorizeTask(w, config.DB, membership, segments[0], models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	// Refuse to change a version of the task the client has not seen
+	if !checkTaskIfMatch(w, r, task) {
+		return
+	}
+
+	// Recurring tasks repeat in the owner's timezone
+	loc, err := taskLocation(config.DB, task)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load user timezone", err)
+		return
+	}
+
+	tx, err := config.DB.Begin()
 	if err != nil {
-		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to check task existence", err)
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update task", err)
+		return
+	}
+	defer tx.Rollback()
+
+	task, next, ok := setTaskCompleted(w, tx, membership.UserID, task, completed, ifMatchVersion(r, task), loc)
+	if !ok {
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update task", err)
 		return
 	}
 
-	if !exists {
-		config.WriteErrorResponse(w, http.StatusNotFound, "Task not found", nil)
+	w.Header().Set("ETag", taskETag(task))
+	if !completed {
+		config.WriteSuccessResponse(w, "Task reopened successfully", nil)
 		return
 	}
 
-	// Mark task as completed
-	_, err = config.DB.Exec(
-		"UPDATE tasks SET completed = ? WHERE user_id = ? AND id = ?",
-		true,
-		userID,
-		taskID,
+	var data any
+	if next != nil {
+		data = map[string]any{"next_task": next}
+	}
+	config.WriteSuccessResponse(w, "Task marked as completed successfully", data)
+}
+
+// setTaskCompleted marks a task as completed, or reopens it, inside tx and
+// records the change in the task's history. A task cannot be completed while
+// it is blocked by open tasks, and completing a recurring task creates its
+// next occurrence in loc. Completing or reopening a task again changes
+// nothing. version limits the change to that version of the task, see
+// ifMatchVersion. It returns the updated task and the next occurrence, or
+// writes the error response and returns false.
+func setTaskCompleted(w http.ResponseWriter, tx querier, actorID int, task models.Task, completed bool, version any, loc *time.Location) (models.Task, *models.TaskResponse, bool) {
+	if completed {
+		blockers, err := openBlockerIDs(tx, task.ID)
+		if err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to check dependencies", err)
+			return task, nil, false
+		}
+
+		if len(blockers) > 0 {
+			config.WriteErrorResponse(w, http.StatusConflict, "Task is blocked by open tasks: "+strings.Join(blockers, ", "), nil)
+			return task, nil, false
+		}
+	}
+
+	// Remember the current values for the task's history
+	before, err := taskSnapshot(tx, task)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to record task history", err)
+		return task, nil, false
+	}
+
+	// Completing a task again keeps the original completion time
+	completedAt := "CURRENT_TIMESTAMP"
+	if !completed {
+		completedAt = "NULL"
+	}
+	result, err := tx.Exec(
+		"UPDATE tasks SET completed = ?, completed_at = "+completedAt+", "+touchTaskSQL+" WHERE id = ? AND completed = ? AND (? IS NULL OR version = ?)",
+		completed,
+		task.ID,
+		!completed,
+		version,
+		version,
 	)
 	if err != nil {
-		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to mark task as completed", err)
-		return
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update task", err)
+		return task, nil, false
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update task", err)
+		return task, nil, false
+	}
+
+	updated, err := getTask(tx, task.WorkspaceID, task.ID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch updated task", err)
+		return task, nil, false
+	}
+
+	// Nothing changed because the task changed after the If-Match check
+	if affected == 0 && version != nil && updated.Version != task.Version {
+		config.WriteErrorResponse(w, http.StatusPreconditionFailed, "Task has been modified since it was fetched", nil)
+		return task, nil, false
+	}
+
+	if affected == 0 {
+		return updated, nil, true
+	}
+
+	// Record the completion, or the reopening as an update
+	action := models.TaskEventCompleted
+	if !completed {
+		action = models.TaskEventUpdated
+	}
+	if err := recordTaskSnapshotEvent(tx, actorID, action, updated, before); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to record task history", err)
+		return task, nil, false
+	}
+
+	// Completing a recurring task creates its next occurrence
+	if !completed || updated.Recurrence == nil {
+		return updated, nil, true
+	}
+
+	next, err := createNextOccurrence(tx, updated, loc)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create next occurrence", err)
+		return task, nil, false
+	}
+
+	if next != nil {
+		nextTask, err := getTask(tx, updated.WorkspaceID, next.ID)
+		if err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch next occurrence", err)
+			return task, nil, false
+		}
+
+		if err := recordTaskSnapshotEvent(tx, actorID, models.TaskEventCreated, nextTask, nil); err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to record task history", err)
+			return task, nil, false
+		}
 	}
+	return updated, next, true
+}
 
-	config.WriteSuccessResponse(w, "Task marked as completed successfully", nil)
+// taskLocation returns the timezone the occurrences of a recurring task are
+// computed in, its owner's. Other tasks use UTC.
+func taskLocation(q querier, task models.Task) (*time.Location, error) {
+	if task.Recurrence == nil {
+		return time.UTC, nil
+	}
+	return userLocation(q, task.UserID)
 }
 
-// CreateTask creates a new task for the authenticated user
+// CreateTask creates a new task in the current workspace
 func CreateTask(w http.ResponseWriter, r *http.Request) {
-	// Get user ID from context
-	userID, ok := middleware.GetUserIDFromContext(r)
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
 	if !ok {
-		http.Error(w, "User ID not found in context", http.StatusInternalServerError)
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
 		return
 	}
 
@@ -300,46 +592,114 @@ func CreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recurrence, errs := taskRecurrence(req)
+	if errs != nil {
+		config.WriteValidationErrorResponse(w, errs)
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create task", err)
+		return
+	}
+	defer tx.Rollback()
+
+	task, ok := insertTask(w, tx, membership, req, recurrence)
+	if !ok {
+		return
+	}
+
+	response, err := taskResponseWithDetails(tx, task)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch task details", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create task", err)
+		return
+	}
+
+	// Return success response with the complete task
+	w.Header().Set("ETag", taskETag(task))
+	config.WriteCreatedResponse(w, "Task created successfully", response)
+}
+
+// insertTask creates a task from req in the workspace of membership inside tx
+// and records its creation in the task's history. It returns the new task, or
+// writes the error response and returns false.
+func insertTask(w http.ResponseWriter, tx querier, membership models.WorkspaceMembership, req models.TaskRequest, recurrence any) (models.Task, bool) {
+	var task models.Task
+
+	// Make sure the project belongs to the workspace
+	if errs, err := checkTaskProject(tx, membership.WorkspaceID, req.ProjectID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to check project", err)
+		return task, false
+	} else if errs != nil {
+		config.WriteValidationErrorResponse(w, errs)
+		return task, false
+	}
+
+	// Make sure the parent task belongs to the workspace
+	if errs, err := checkTaskParent(tx, membership.WorkspaceID, 0, req.ParentID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to check parent task", err)
+		return task, false
+	} else if errs != nil {
+		config.WriteValidationErrorResponse(w, errs)
+		return task, false
+	}
+
 	// Insert task
-	result, err := config.DB.Exec(
-		"INSERT INTO tasks (user_id, title, description) VALUES (?, ?, ?)",
-		userID,
+	result, err := tx.Exec(
+		"INSERT INTO tasks (user_id, workspace_id, title, description, due_at, priority, project_id, parent_id, recurrence) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		membership.UserID,
+		membership.WorkspaceID,
 		req.Title,
 		req.Description,
+		sqlTime(req.DueAt),
+		taskPriority(req.Priority),
+		req.ProjectID,
+		req.ParentID,
+		recurrence,
 	)
 	if err != nil {
 		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create task", err)
-		return
+		return task, false
 	}
 
 	// Get the last inserted ID
 	lastID, err := result.LastInsertId()
 	if err != nil {
 		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get task ID", err)
-		return
+		return task, false
 	}
 
-	// Fetch the created task
-	var task models.Task
-	if err := config.DB.QueryRow("SELECT id, title, description, created_at, updated_at, completed FROM tasks WHERE id = ?", lastID).Scan(
-		&task.ID,
-		&task.Title,
-		&task.Description,
-		&task.CreatedAt,
-		&task.UpdatedAt,
-		&task.Completed,
-	); err != nil {
+	// Attach the requested labels
+	if len(req.LabelIDs) > 0 {
+		errs, err := setTaskLabels(tx, membership.UserID, int(lastID), req.LabelIDs)
+		if err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to attach task labels", err)
+			return task, false
+		}
+		if errs != nil {
+			config.WriteValidationErrorResponse(w, errs)
+			return task, false
+		}
+	}
+
+	// Fetch the task
+	task, err = getTask(tx, membership.WorkspaceID, lastID)
+	if err != nil {
 		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch created task", err)
-		return
+		return task, false
 	}
 
-	// Return success response with the complete task
-	config.WriteCreatedResponse(w, "Task created successfully", models.TaskResponse{
-		ID:          task.ID,
-		Title:       task.Title,
-		Description: task.Description,
-		CreatedAt:   task.CreatedAt,
-		UpdatedAt:   task.UpdatedAt,
-		Completed:   task.Completed,
-	})
+	// Record the creation in the task's history
+	if err := recordTaskSnapshotEvent(tx, membership.UserID, models.TaskEventCreated, task, nil); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to record task history", err)
+		return task, false
+	}
+
+	return task, true
 }
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"strings"
+	"time"
+	_ "time/tzdata" // embed the timezone database for user timezones
+
+	"github.com/eokwukwe/golearn/tasks/models"
+)
+
+// taskColumns lists the task columns in the order scanTask expects them
+const taskColumns = "id, user_id, title, description, created_at, updated_at, completed, due_at, priority, completed_at, project_id, parent_id, recurrence, occurrence, workspace_id, deleted_at, version"
+
+// touchTaskSQL is part of the SET clause of every statement that changes
+// tasks, so their updated_at and version move forward
+const touchTaskSQL = "updated_at = CURRENT_TIMESTAMP, version = version + 1"
+
+// qualifiedTaskColumns returns taskColumns prefixed with a table alias
+func qualifiedTaskColumns(alias string) string {
+	columns := strings.Split(taskColumns, ", ")
+	for i, column := range columns {
+		columns[i] = alias + "." + column
+	}
+	return strings.Join(columns, ", ")
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanTask scans a row selected with taskColumns. Any extra destinations are
+// scanned from the columns that follow.
+func scanTask(row rowScanner, extra ...any) (models.Task, error) {
+	var task models.Task
+	dest := []any{
+		&task.ID,
+		&task.UserID,
+		&task.Title,
+		&task.Description,
+		&task.CreatedAt,
+		&task.UpdatedAt,
+		&task.Completed,
+		&task.DueAt,
+		&task.Priority,
+		&task.CompletedAt,
+		&task.ProjectID,
+		&task.ParentID,
+		&task.Recurrence,
+		&task.Occurrence,
+		&task.WorkspaceID,
+		&task.DeletedAt,
+		&task.Version,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	return task, err
+}
+
+// getTask fetches a single task of the workspace that is not in the trash
+func getTask(q querier, workspaceID int, taskID any) (models.Task, error) {
+	row := q.QueryRow("SELECT "+taskColumns+" FROM tasks WHERE workspace_id = ? AND id = ? AND deleted_at IS NULL", workspaceID, taskID)
+	return scanTask(row)
+}
+
+// deleteTasks deletes the tasks selected by idQuery along with their labels,
+// dependencies and shares. Subtasks that are not deleted move up to their
+// closest remaining ancestor.
+func deleteTasks(q querier, idQuery string, args ...any) (int64, error) {
+	// Each statement repeats the ID query once per IN clause
+	twice := append(append([]any{}, args...), args...)
+
+	if err := moveSubtasksUp(q, idQuery, args...); err != nil {
+		return 0, err
+	}
+
+	statements := []struct {
+		query string
+		args  []any
+	}{
+		{"DELETE FROM task_labels WHERE task_id IN (" + idQuery + ")", args},
+		{"DELETE FROM task_dependencies WHERE task_id IN (" + idQuery + ") OR blocked_by_id IN (" + idQuery + ")", twice},
+		{"DELETE FROM task_shares WHERE task_id IN (" + idQuery + ")", args},
+		{"DELETE FROM task_comments WHERE task_id IN (" + idQuery + ")", args},
+		{"DELETE FROM task_attachments WHERE task_id IN (" + idQuery + ")", args},
+	}
+	for _, statement := range statements {
+		if _, err := q.Exec(statement.query, statement.args...); err != nil {
+			return 0, err
+		}
+	}
+
+	result, err := q.Exec("DELETE FROM tasks WHERE id IN ("+idQuery+")", args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// moveSubtasksUp moves the subtasks of the tasks selected by idQuery that are
+// not selected themselves up to their closest ancestor outside the selection
+func moveSubtasksUp(q querier, idQuery string, args ...any) error {
+	twice := append(append([]any{}, args...), args...)
+
+	// Move one level at a time until no parent is selected
+	for {
+		result, err := q.Exec(
+			"UPDATE tasks SET parent_id = (SELECT p.parent_id FROM tasks p WHERE p.id = tasks.parent_id), "+touchTaskSQL+" WHERE parent_id IN ("+idQuery+") AND id NOT IN ("+idQuery+")",
+			twice...,
+		)
+		if err != nil {
+			return err
+		}
+		if affected, err := result.RowsAffected(); err != nil {
+			return err
+		} else if affected == 0 {
+			return nil
+		}
+	}
+}
+
+// newTaskResponse converts a task to its API representation
+func newTaskResponse(task models.Task) models.TaskResponse {
+	return models.TaskResponse{
+		ID:          task.ID,
+		WorkspaceID: task.WorkspaceID,
+		Title:       task.Title,
+		Description: task.Description,
+		CreatedAt:   task.CreatedAt,
+		UpdatedAt:   task.UpdatedAt,
+		Version:     task.Version,
+		Completed:   task.Completed,
+		DueAt:       task.DueAt,
+		Priority:    task.Priority,
+		CompletedAt: task.CompletedAt,
+		ProjectID:   task.ProjectID,
+		ParentID:    task.ParentID,
+		Recurrence:  task.Recurrence,
+		DeletedAt:   task.DeletedAt,
+	}
+}
+
+// taskResponseWithDetails converts a task to its API representation including
+// its labels and subtask progress
+func taskResponseWithDetails(q querier, task models.Task) (models.TaskResponse, error) {
+	response := newTaskResponse(task)
+	err := loadTaskDetails(q, []*models.TaskResponse{&response})
+	return response, err
+}
+
+// loadTaskDetails fills in the labels and subtask progress of every task
+func loadTaskDetails(q querier, tasks []*models.TaskResponse) error {
+	if err := loadTaskLabels(q, tasks); err != nil {
+		return err
+	}
+	return loadTaskProgress(q, tasks)
+}
+
+// taskResponsePointers returns pointers to the elements of tasks so they can be filled in place
+func taskResponsePointers(tasks []models.TaskResponse) []*models.TaskResponse {
+	pointers := make([]*models.TaskResponse, len(tasks))
+	for i := range tasks {
+		pointers[i] = &tasks[i]
+	}
+	return pointers
+}
+
+// taskPriority returns the priority to store, defaulting to medium
+func taskPriority(priority string) string {
+	if priority == "" {
+		return models.PriorityMedium
+	}
+	return priority
+}
+
+// sqlTime formats a timestamp the way SQLite's CURRENT_TIMESTAMP stores it so
+// stored values compare correctly as text. A nil time becomes NULL.
+func sqlTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(sqlTimeFormat)
+}
+
+// userLocation loads the timezone configured for the user
+func userLocation(q querier, userID int) (*time.Location, error) {
+	var timezone string
+	if err := q.QueryRow("SELECT timezone FROM users WHERE id = ?", userID).Scan(&timezone); err != nil {
+		return nil, err
+	}
+	return time.LoadLocation(timezone)
+}
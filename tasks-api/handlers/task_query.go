@@ -0,0 +1,365 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/models"
+)
+
+const (
+	defaultTaskListLimit = 50
+	maxTaskListLimit     = 100
+	defaultTaskSort      = "-created_at"
+
+	// sqlTimeFormat matches the format SQLite's CURRENT_TIMESTAMP stores
+	sqlTimeFormat = "2006-01-02 15:04:05"
+)
+
+// taskSortColumns maps the sort keys accepted by GET /api/v1/tasks to columns
+var taskSortColumns = map[string]sortField{
+	"title":      {column: "title", collation: "NOCASE"},
+	"created_at": {column: "created_at"},
+	"updated_at": {column: "updated_at"},
+	// Tasks without a due date sort after every dated task
+	"due_at": {column: "COALESCE(due_at, '9999-12-31 23:59:59')"},
+	// Ranked as text so the raw value in a cursor compares correctly
+	"priority": {column: "CASE priority WHEN 'low' THEN '0' WHEN 'medium' THEN '1' WHEN 'high' THEN '2' ELSE '3' END"},
+}
+
+// sortField is a single column or expression of the ORDER BY clause
+type sortField struct {
+	column    string
+	collation string
+	desc      bool
+}
+
+// expr returns the column with its collation, as used for ordering and comparing
+func (f sortField) expr() string {
+	if f.collation == "" {
+		return f.column
+	}
+	return f.column + " COLLATE " + f.collation
+}
+
+// taskCursor is the decoded form of the opaque next_cursor value.
+// Keys holds the raw values of the sort columns of the last returned task.
+type taskCursor struct {
+	Sort string   `json:"s"`
+	Keys []string `json:"k"`
+	ID   int      `json:"id"`
+}
+
+// taskListQuery holds the validated query parameters of a task listing
+type taskListQuery struct {
+	limit  int
+	sort   string
+	fields []sortField
+	cursor *taskCursor
+	where  []string
+	args   []any
+}
+
+// parseTaskListQuery validates the pagination, filter and sort parameters of a
+// task listing. Validation problems are returned as a field to message map.
+// The due views are computed in loc, which is only needed when ?due= is set.
+func parseTaskListQuery(values url.Values, now time.Time, loc *time.Location) (*taskListQuery, map[string]string) {
+	q := &taskListQuery{limit: defaultTaskListLimit, sort: defaultTaskSort}
+	errs := map[string]string{}
+
+	// Page size
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxTaskListLimit {
+			errs["limit"] = fmt.Sprintf("limit must be a number between 1 and %d", maxTaskListLimit)
+		} else {
+			q.limit = limit
+		}
+	}
+
+	// Sort order
+	if raw := values.Get("sort"); raw != "" {
+		q.sort = raw
+	}
+	fields, err := parseTaskSort(q.sort)
+	if err != nil {
+		errs["sort"] = err.Error()
+	}
+	q.fields = fields
+
+	// Filters
+	if raw := values.Get("completed"); raw != "" {
+		completed, err := strconv.ParseBool(raw)
+		if err != nil {
+			errs["completed"] = "completed must be true or false"
+		} else {
+			q.where = append(q.where, "completed = ?")
+			q.args = append(q.args, completed)
+		}
+	}
+
+	if raw := values.Get("priority"); raw != "" {
+		switch raw {
+		case models.PriorityLow, models.PriorityMedium, models.PriorityHigh, models.PriorityUrgent:
+			q.where = append(q.where, "priority = ?")
+			q.args = append(q.args, raw)
+		default:
+			errs["priority"] = "priority must be one of low, medium, high or urgent"
+		}
+	}
+
+	if raw := values.Get("due"); raw != "" {
+		cond, args, err := dueViewCondition(raw, now, loc)
+		if err != nil {
+			errs["due"] = err.Error()
+		} else {
+			q.where = append(q.where, cond)
+			q.args = append(q.args, args...)
+		}
+	}
+
+	// Project scope; tasks of archived projects are hidden unless a project is
+	// requested explicitly or include_archived is set
+	includeArchived := false
+	if raw := values.Get("include_archived"); raw != "" {
+		var err error
+		if includeArchived, err = strconv.ParseBool(raw); err != nil {
+			errs["include_archived"] = "include_archived must be true or false"
+		}
+	}
+
+	if raw := values.Get("project_id"); raw == "inbox" {
+		q.where = append(q.where, "project_id IS NULL")
+	} else if raw != "" {
+		projectID, err := strconv.Atoi(raw)
+		if err != nil {
+			errs["project_id"] = "project_id must be a project ID or inbox"
+		} else {
+			q.where = append(q.where, "project_id = ?")
+			q.args = append(q.args, projectID)
+		}
+	} else if !includeArchived {
+		q.where = append(q.where, "(project_id IS NULL OR project_id NOT IN (SELECT id FROM projects WHERE archived = TRUE))")
+	}
+
+	matchAll := false
+	switch values.Get("label_match") {
+	case "", "any":
+	case "all":
+		matchAll = true
+	default:
+		errs["label_match"] = "label_match must be any or all"
+	}
+
+	if raw := values.Get("label"); raw != "" {
+		cond, args, err := labelFilterCondition(raw, matchAll)
+		if err != nil {
+			errs["label"] = err.Error()
+		} else {
+			q.where = append(q.where, cond)
+			q.args = append(q.args, args...)
+		}
+	}
+
+	timeFilters := []struct {
+		param string
+		cond  string
+	}{
+		{"created_before", "created_at < ?"},
+		{"created_after", "created_at > ?"},
+		{"updated_since", "updated_at >= ?"},
+		{"due_before", "due_at < ?"},
+		{"due_after", "due_at > ?"},
+	}
+	for _, filter := range timeFilters {
+		raw := values.Get(filter.param)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			errs[filter.param] = fmt.Sprintf("%s must be an RFC 3339 timestamp", filter.param)
+			continue
+		}
+		q.where = append(q.where, filter.cond)
+		q.args = append(q.args, t.UTC().Format(sqlTimeFormat))
+	}
+
+	// Cursor from a previous page
+	if raw := values.Get("cursor"); raw != "" {
+		cursor, err := decodeTaskCursor(raw)
+		if err != nil || cursor.Sort != q.sort || len(cursor.Keys) != len(q.fields) {
+			errs["cursor"] = "cursor is invalid or does not match the requested sort"
+		} else {
+			q.cursor = cursor
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return q, nil
+}
+
+// parseTaskSort parses a sort specification such as "title,-updated_at"
+func parseTaskSort(spec string) ([]sortField, error) {
+	seen := map[string]bool{}
+	fields := []sortField{}
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		desc := strings.HasPrefix(part, "-")
+		name := strings.TrimPrefix(part, "-")
+
+		field, ok := taskSortColumns[name]
+		if !ok {
+			return nil, fmt.Errorf("sort must be a comma separated list of title, created_at, updated_at, due_at and priority, optionally prefixed with -")
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("sort must not repeat %s", name)
+		}
+		seen[name] = true
+		field.desc = desc
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// labelFilterCondition returns the condition for ?label=a,b. Label names match
+// case-insensitively; with matchAll a task needs every label, otherwise one of
+// them is enough.
+func labelFilterCondition(raw string, matchAll bool) (string, []any, error) {
+	names := []string{}
+	args := []any{}
+	seen := map[string]bool{}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		names = append(names, "?")
+		args = append(args, name)
+	}
+	if len(names) == 0 {
+		return "", nil, fmt.Errorf("label must be a comma separated list of label names")
+	}
+
+	labelled := "FROM task_labels tl JOIN labels l ON l.id = tl.label_id" +
+		" WHERE tl.task_id = tasks.id AND l.name COLLATE NOCASE IN (" + strings.Join(names, ", ") + ")"
+
+	if matchAll {
+		return "(SELECT COUNT(DISTINCT l.id) " + labelled + ") = ?", append(args, len(names)), nil
+	}
+	return "EXISTS (SELECT 1 " + labelled + ")", args, nil
+}
+
+// dueViewCondition returns the condition for the overdue, today and week views.
+// Day and week boundaries are computed in the user's timezone; weeks start on Monday.
+func dueViewCondition(view string, now time.Time, loc *time.Location) (string, []any, error) {
+	if view == "overdue" {
+		return "due_at < ? AND completed = FALSE", []any{now.UTC().Format(sqlTimeFormat)}, nil
+	}
+
+	local := now.In(loc)
+	startOfDay := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+
+	var start, end time.Time
+	switch view {
+	case "today":
+		start, end = startOfDay, startOfDay.AddDate(0, 0, 1)
+	case "week":
+		daysSinceMonday := (int(local.Weekday()) + 6) % 7
+		start = startOfDay.AddDate(0, 0, -daysSinceMonday)
+		end = start.AddDate(0, 0, 7)
+	default:
+		return "", nil, fmt.Errorf("due must be one of overdue, today or week")
+	}
+
+	return "due_at >= ? AND due_at < ?", []any{sqlTime(&start), sqlTime(&end)}, nil
+}
+
+// orderBy returns the ORDER BY clause, using the task ID as the final tie breaker
+func (q *taskListQuery) orderBy() string {
+	parts := []string{}
+	for _, f := range q.fields {
+		parts = append(parts, f.expr()+direction(f.desc))
+	}
+	parts = append(parts, "id"+direction(q.tieBreakerDesc()))
+	return strings.Join(parts, ", ")
+}
+
+// keysetCondition returns the condition selecting the rows after the cursor
+func (q *taskListQuery) keysetCondition() (string, []any) {
+	fields := append([]sortField{}, q.fields...)
+	fields = append(fields, sortField{column: "id", desc: q.tieBreakerDesc()})
+
+	values := []any{}
+	for _, key := range q.cursor.Keys {
+		values = append(values, key)
+	}
+	values = append(values, q.cursor.ID)
+
+	ors := []string{}
+	args := []any{}
+	for i, f := range fields {
+		ands := []string{}
+		for j := 0; j < i; j++ {
+			ands = append(ands, fields[j].expr()+" = ?")
+			args = append(args, values[j])
+		}
+		op := " > ?"
+		if f.desc {
+			op = " < ?"
+		}
+		ands = append(ands, f.expr()+op)
+		args = append(args, values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+// sortKeyColumns returns the raw text of every sort column so it can be put in a cursor
+func (q *taskListQuery) sortKeyColumns() string {
+	parts := []string{}
+	for _, f := range q.fields {
+		parts = append(parts, "CAST("+f.column+" AS TEXT)")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (q *taskListQuery) tieBreakerDesc() bool {
+	return q.fields[len(q.fields)-1].desc
+}
+
+func direction(desc bool) string {
+	if desc {
+		return " DESC"
+	}
+	return " ASC"
+}
+
+func encodeTaskCursor(cursor taskCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeTaskCursor(raw string) (*taskCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor taskCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
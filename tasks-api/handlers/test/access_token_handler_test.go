@@ -0,0 +1,262 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// createTestAccessToken creates a personal access token with a session token
+func createTestAccessToken(t *testing.T, sessionToken, body string) models.CreatedAccessTokenResponse {
+	t.Helper()
+
+	rec := authRequest(http.MethodPost, "/api/v1/tokens", sessionToken, strings.NewReader(body), handlers.CreateAccessToken)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Creating token failed with status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Data models.CreatedAccessTokenResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode token: %v", err)
+	}
+	return resp.Data
+}
+
+// tokenTestMux routes requests like the server does, so AuthMiddleware sees
+// the route pattern the scopes of personal access tokens are checked against
+func tokenTestMux() *http.ServeMux {
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/tasks", middleware.AuthMiddleware(ok))
+	mux.HandleFunc("/api/v1/tasks/{id}/comments", middleware.AuthMiddleware(ok))
+	mux.HandleFunc("/api/v1/projects", middleware.AuthMiddleware(ok))
+	mux.HandleFunc("/api/v1/me", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetMe(w, r)
+		} else {
+			ok(w, r)
+		}
+	}))
+	mux.HandleFunc("/api/v1/tokens", middleware.AuthMiddleware(handlers.GetAccessTokens))
+	mux.HandleFunc("/api/v1/admin/stats", middleware.AuthMiddleware(middleware.RequireRole(models.UserRoleAdmin, ok)))
+	return mux
+}
+
+// tokenTestRequest sends a request with the given token through mux
+func tokenTestRequest(mux *http.ServeMux, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCreateAccessToken(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "pat@example.com", "password123")
+	sessionToken := loginTestUser(t, "pat@example.com", "password123")
+
+	created := createTestAccessToken(t, sessionToken, `{"name": " CI ", "scopes": ["tasks:write", "tasks:read", "tasks:write"]}`)
+	assert.True(t, strings.HasPrefix(created.Token, models.AccessTokenPrefix))
+	assert.Equal(t, "CI", created.Name)
+	assert.Equal(t, []string{"tasks:read", "tasks:write"}, created.Scopes)
+	assert.Nil(t, created.ExpiresAt)
+
+	// Only the hash is stored
+	var stored string
+	assert.NoError(t, config.DB.QueryRow("SELECT token_hash FROM personal_access_tokens WHERE id = ?", created.ID).Scan(&stored))
+	assert.NotEqual(t, created.Token, stored)
+	assert.NotContains(t, stored, created.Token[len(models.AccessTokenPrefix):])
+
+	// The token is never shown again
+	rec := authRequest(http.MethodGet, "/api/v1/tokens", sessionToken, nil, handlers.GetAccessTokens)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"name":"CI"`)
+	assert.NotContains(t, rec.Body.String(), created.Token)
+	assert.NotContains(t, rec.Body.String(), `"token"`)
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	expiring := createTestAccessToken(t, sessionToken, `{"name": "Expiring", "scopes": ["profile:read"], "expires_at": "`+expiresAt+`"}`)
+	assert.NotNil(t, expiring.ExpiresAt)
+
+	// Invalid requests
+	for _, body := range []string{
+		`{"name": "", "scopes": ["tasks:read"]}`,
+		`{"name": "No scopes", "scopes": []}`,
+		`{"name": "Unknown scope", "scopes": ["admin"]}`,
+		`{"name": "Expired", "scopes": ["tasks:read"], "expires_at": "2020-01-01T00:00:00Z"}`,
+	} {
+		rec = authRequest(http.MethodPost, "/api/v1/tokens", sessionToken, strings.NewReader(body), handlers.CreateAccessToken)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, body)
+	}
+
+	// Tokens go with the account
+	assert.Equal(t, 2, countTestRows(t, "personal_access_tokens", "user_id", userID))
+	rec = authRequest(http.MethodDelete, "/api/v1/me", sessionToken, strings.NewReader(`{"password": "password123"}`), handlers.DeleteMe)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 0, countTestRows(t, "personal_access_tokens", "user_id", userID))
+}
+
+func TestAccessTokenScopes(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	createTestAdmin(t, "scopes@example.com", "password123")
+	sessionToken := loginTestUser(t, "scopes@example.com", "password123")
+	readOnly := createTestAccessToken(t, sessionToken, `{"name": "Read", "scopes": ["tasks:read", "profile:read"]}`).Token
+	readWrite := createTestAccessToken(t, sessionToken, `{"name": "Write", "scopes": ["tasks:write"]}`).Token
+	mux := tokenTestMux()
+
+	// Read scopes only allow safe methods
+	assert.Equal(t, http.StatusOK, tokenTestRequest(mux, http.MethodGet, "/api/v1/tasks", readOnly).Code)
+	assert.Equal(t, http.StatusOK, tokenTestRequest(mux, http.MethodGet, "/api/v1/tasks/1/comments", readOnly).Code)
+	rec := tokenTestRequest(mux, http.MethodPost, "/api/v1/tasks", readOnly)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "tasks:write")
+
+	// Write scopes include the read scope of the same resource only
+	assert.Equal(t, http.StatusOK, tokenTestRequest(mux, http.MethodPost, "/api/v1/tasks", readWrite).Code)
+	assert.Equal(t, http.StatusOK, tokenTestRequest(mux, http.MethodGet, "/api/v1/tasks", readWrite).Code)
+	assert.Equal(t, http.StatusForbidden, tokenTestRequest(mux, http.MethodGet, "/api/v1/projects", readWrite).Code)
+
+	// The profile can be read but not changed
+	rec = tokenTestRequest(mux, http.MethodGet, "/api/v1/me", readOnly)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "scopes@example.com", decodeTestProfile(t, rec).Email)
+	assert.Equal(t, http.StatusForbidden, tokenTestRequest(mux, http.MethodGet, "/api/v1/me", readWrite).Code)
+	assert.Equal(t, http.StatusForbidden, tokenTestRequest(mux, http.MethodPatch, "/api/v1/me", readOnly).Code)
+
+	// Tokens cannot manage tokens or use the admin API, even for admins
+	rec = tokenTestRequest(mux, http.MethodGet, "/api/v1/tokens", readWrite)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "cannot be used for this route")
+	assert.Equal(t, http.StatusForbidden, tokenTestRequest(mux, http.MethodGet, "/api/v1/admin/stats", readWrite).Code)
+	assert.Equal(t, http.StatusOK, tokenTestRequest(mux, http.MethodGet, "/api/v1/admin/stats", sessionToken).Code)
+
+	// Use is recorded
+	rec = authRequest(http.MethodGet, "/api/v1/tokens", sessionToken, nil, handlers.GetAccessTokens)
+	var list struct {
+		Data []models.AccessTokenResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	if assert.Len(t, list.Data, 2) {
+		assert.NotNil(t, list.Data[0].LastUsedAt)
+	}
+}
+
+func TestAccessTokenRevocationAndExpiry(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "revoke@example.com", "password123")
+	createTestUser(t, "other@example.com", "password123")
+	sessionToken := loginTestUser(t, "revoke@example.com", "password123")
+	otherToken := loginTestUser(t, "other@example.com", "password123")
+	created := createTestAccessToken(t, sessionToken, `{"name": "Revoked", "scopes": ["tasks:read"]}`)
+	expiring := createTestAccessToken(t, sessionToken, `{"name": "Expiring", "scopes": ["tasks:read"], "expires_at": "`+time.Now().Add(time.Hour).UTC().Format(time.RFC3339)+`"}`)
+	mux := tokenTestMux()
+	deletePath := "/api/v1/tokens/" + strconv.Itoa(created.ID)
+
+	// Other users cannot see or revoke the token
+	rec := authRequest(http.MethodDelete, deletePath, otherToken, nil, handlers.DeleteAccessToken)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	assert.Equal(t, http.StatusOK, tokenTestRequest(mux, http.MethodGet, "/api/v1/tasks", created.Token).Code)
+	rec = authRequest(http.MethodDelete, deletePath, sessionToken, nil, handlers.DeleteAccessToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, http.StatusUnauthorized, tokenTestRequest(mux, http.MethodGet, "/api/v1/tasks", created.Token).Code)
+
+	// Disabled accounts cannot use their tokens
+	_, err := config.DB.Exec("UPDATE users SET disabled_at = CURRENT_TIMESTAMP WHERE id = ?", userID)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, tokenTestRequest(mux, http.MethodGet, "/api/v1/tasks", expiring.Token).Code)
+	_, err = config.DB.Exec("UPDATE users SET disabled_at = NULL WHERE id = ?", userID)
+	assert.NoError(t, err)
+
+	// Expired tokens are refused and purged
+	assert.Equal(t, http.StatusOK, tokenTestRequest(mux, http.MethodGet, "/api/v1/tasks", expiring.Token).Code)
+	_, err = config.DB.Exec("UPDATE personal_access_tokens SET expires_at = datetime('now', '-1 second') WHERE id = ?", expiring.ID)
+	assert.NoError(t, err)
+	rec = tokenTestRequest(mux, http.MethodGet, "/api/v1/tasks", expiring.Token)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Token has expired")
+
+	removed, err := handlers.PurgeExpiredAccessTokens()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+	assert.Equal(t, 0, countTestRows(t, "personal_access_tokens", "user_id", userID))
+}
+
+func TestAccessTokensRevokedWithSessions(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	mailPath := useTestMailer(t)
+	createTestAdmin(t, "pat-admin@example.com", "password123")
+	adminToken := loginTestUser(t, "pat-admin@example.com", "password123")
+	userID := createTestUser(t, "pat-revoked@example.com", "password123")
+	mux := tokenTestMux()
+	adminPath := "/api/v1/admin/users/" + strconv.Itoa(userID)
+
+	// newToken logs in with password and creates a token that works
+	newToken := func(password string) string {
+		t.Helper()
+		created := createTestAccessToken(t, loginTestUser(t, "pat-revoked@example.com", password), `{"name": "Kept", "scopes": ["tasks:read"]}`)
+		assert.Equal(t, http.StatusOK, tokenTestRequest(mux, http.MethodGet, "/api/v1/tasks", created.Token).Code)
+		return created.Token
+	}
+
+	// A password reset revokes tokens made with the old password
+	token := newToken("password123")
+	assert.Equal(t, http.StatusOK, publicRequest(handlers.ForgotPassword, `{"email": "pat-revoked@example.com"}`).Code)
+	resets := sentTokens(t, mailPath, "reset-password")
+	if assert.Len(t, resets, 1) {
+		rec := publicRequest(handlers.ResetPassword, `{"token": "`+resets[0]+`", "password": "reset-password"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	assert.Equal(t, http.StatusUnauthorized, tokenTestRequest(mux, http.MethodGet, "/api/v1/tasks", token).Code)
+
+	// So does a password change
+	token = newToken("reset-password")
+	sessionToken := loginTestUser(t, "pat-revoked@example.com", "reset-password")
+	rec := authRequest(http.MethodPost, "/api/v1/me/password", sessionToken, strings.NewReader(`{"current_password": "reset-password", "new_password": "changed-password"}`), handlers.ChangePassword)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, http.StatusUnauthorized, tokenTestRequest(mux, http.MethodGet, "/api/v1/tasks", token).Code)
+
+	// And an admin logging the user out
+	token = newToken("changed-password")
+	rec = adminRequest(http.MethodPost, adminPath+"/logout", adminToken, nil, handlers.LogoutUser)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"revoked_access_tokens":1`)
+	assert.Equal(t, http.StatusUnauthorized, tokenTestRequest(mux, http.MethodGet, "/api/v1/tasks", token).Code)
+
+	// Or disabling the account, so enabling it again does not bring them back
+	token = newToken("changed-password")
+	rec = adminRequest(http.MethodPost, adminPath+"/disable", adminToken, nil, handlers.DisableUser)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = adminRequest(http.MethodPost, adminPath+"/enable", adminToken, nil, handlers.EnableUser)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, http.StatusUnauthorized, tokenTestRequest(mux, http.MethodGet, "/api/v1/tasks", token).Code)
+	assert.Equal(t, 0, countTestRows(t, "personal_access_tokens", "user_id", userID))
+}
@@ -0,0 +1,271 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type adminUserListResponse struct {
+	Data []models.AdminUserResponse `json:"data"`
+	Meta *models.Meta               `json:"meta"`
+}
+
+// createTestAdmin creates a verified user and gives it the admin role
+func createTestAdmin(t *testing.T, email, password string) int {
+	t.Helper()
+
+	userID := createTestUser(t, email, password)
+	if _, err := handlers.PromoteAdmins([]string{email}); err != nil {
+		t.Fatalf("Failed to promote admin: %v", err)
+	}
+	return userID
+}
+
+// adminRequest sends a request through AuthMiddleware and RequireRole for admins
+func adminRequest(method, path, token string, body io.Reader, next http.HandlerFunc) *httptest.ResponseRecorder {
+	return authRequest(method, path, token, body, middleware.RequireRole(models.UserRoleAdmin, next))
+}
+
+// listTestAdminUsers lists the users with the given query string
+func listTestAdminUsers(t *testing.T, token, query string) adminUserListResponse {
+	t.Helper()
+
+	rec := adminRequest(http.MethodGet, "/api/v1/admin/users?"+query, token, nil, handlers.GetAdminUsers)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Listing users failed with status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp adminUserListResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode users: %v", err)
+	}
+	return resp
+}
+
+func TestRequireRole(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	createTestUser(t, "plain@example.com", "password123")
+	createTestAdmin(t, "admin@example.com", "password123")
+	userToken := loginTestUser(t, "plain@example.com", "password123")
+	adminToken := loginTestUser(t, "admin@example.com", "password123")
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	// Users are refused, admins let through
+	rec := adminRequest(http.MethodGet, "/api/v1/admin/stats", userToken, nil, ok)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, http.StatusOK, adminRequest(http.MethodGet, "/api/v1/admin/stats", adminToken, nil, ok).Code)
+
+	// Admins have every permission of users
+	rec = authRequest(http.MethodGet, "/api/v1/me", adminToken, nil, middleware.RequireRole(models.UserRoleUser, ok))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Without AuthMiddleware there is no user to check
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/stats", nil)
+	rec = httptest.NewRecorder()
+	middleware.RequireRole(models.UserRoleAdmin, ok)(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// The role is part of the profile
+	rec = authRequest(http.MethodGet, "/api/v1/me", adminToken, nil, handlers.GetMe)
+	assert.Equal(t, models.UserRoleAdmin, decodeTestProfile(t, rec).Role)
+}
+
+func TestAdminUsers(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	adminID := createTestAdmin(t, "admin@example.com", "password123")
+	aliceID := createTestUser(t, "alice@example.com", "password123")
+	createTestUser(t, "bob@example.com", "password123")
+	_, err := config.DB.Exec("UPDATE users SET email_verified_at = NULL WHERE id = ?", aliceID)
+	assert.NoError(t, err)
+	token := loginTestUser(t, "admin@example.com", "password123")
+
+	// Newest first, one page at a time
+	page := listTestAdminUsers(t, token, "limit=2")
+	assert.Len(t, page.Data, 2)
+	assert.Equal(t, "bob@example.com", page.Data[0].Email)
+	if !assert.NotNil(t, page.Meta.NextCursor) {
+		return
+	}
+	page = listTestAdminUsers(t, token, "limit=2&cursor="+*page.Meta.NextCursor)
+	assert.Len(t, page.Data, 1)
+	assert.Equal(t, adminID, page.Data[0].ID)
+	assert.Equal(t, models.UserRoleAdmin, page.Data[0].Role)
+	assert.Equal(t, 1, page.Data[0].ActiveSessions)
+	assert.NotNil(t, page.Data[0].LastActiveAt)
+	assert.Nil(t, page.Meta.NextCursor)
+
+	// Filters
+	page = listTestAdminUsers(t, token, "q=ALICE")
+	assert.Len(t, page.Data, 1)
+	assert.Equal(t, aliceID, page.Data[0].ID)
+	assert.False(t, page.Data[0].EmailVerified)
+	assert.Empty(t, listTestAdminUsers(t, token, "q=%25").Data, "% is not a wildcard")
+	assert.Empty(t, listTestAdminUsers(t, token, "q=b_b").Data, "_ is not a wildcard")
+	assert.Len(t, listTestAdminUsers(t, token, "status=unverified").Data, 1)
+	assert.Len(t, listTestAdminUsers(t, token, "role=admin").Data, 1)
+	assert.Len(t, listTestAdminUsers(t, token, "role=user&status=active").Data, 2)
+
+	rec := adminRequest(http.MethodGet, "/api/v1/admin/users?status=gone&limit=0", token, nil, handlers.GetAdminUsers)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "status")
+	assert.Contains(t, rec.Body.String(), "limit")
+
+	// A single user
+	rec = adminRequest(http.MethodGet, "/api/v1/admin/users/"+strconv.Itoa(aliceID), token, nil, handlers.GetAdminUser)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "alice@example.com")
+	assert.NotContains(t, rec.Body.String(), "password")
+
+	rec = adminRequest(http.MethodGet, "/api/v1/admin/users/9999", token, nil, handlers.GetAdminUser)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestDisableUser(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	adminID := createTestAdmin(t, "admin@example.com", "password123")
+	userID := createTestUser(t, "user@example.com", "password123")
+	adminToken := loginTestUser(t, "admin@example.com", "password123")
+	userToken := loginTestUser(t, "user@example.com", "password123")
+	disablePath := "/api/v1/admin/users/" + strconv.Itoa(userID) + "/disable"
+
+	// Admins cannot lock themselves out
+	rec := adminRequest(http.MethodPost, "/api/v1/admin/users/"+strconv.Itoa(adminID)+"/disable", adminToken, nil, handlers.DisableUser)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = adminRequest(http.MethodPost, disablePath, adminToken, strings.NewReader(`{"reason": "spam"}`), handlers.DisableUser)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"disabled_at":"`)
+	assert.Equal(t, 0, countTestRows(t, "sessions", "user_id", userID))
+
+	// Disabling twice is fine
+	rec = adminRequest(http.MethodPost, disablePath, adminToken, nil, handlers.DisableUser)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Disabled users cannot log in, even with the right password
+	rec = loginTestAttempt("user@example.com", "password123")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Account is disabled")
+	assert.Equal(t, http.StatusUnauthorized, authRequest(http.MethodGet, "/api/v1/me", userToken, nil, handlers.GetMe).Code)
+
+	assert.Len(t, listTestAdminUsers(t, adminToken, "status=disabled").Data, 1)
+
+	// Enabling lets the user log in again
+	rec = adminRequest(http.MethodPost, "/api/v1/admin/users/"+strconv.Itoa(userID)+"/enable", adminToken, nil, handlers.EnableUser)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"disabled_at":null`)
+	userToken = loginTestUser(t, "user@example.com", "password123")
+
+	// Tokens stop working as soon as the account is disabled
+	_, err := config.DB.Exec("UPDATE users SET disabled_at = CURRENT_TIMESTAMP WHERE id = ?", userID)
+	assert.NoError(t, err)
+	rec = authRequest(http.MethodGet, "/api/v1/me", userToken, nil, handlers.GetMe)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Account is disabled")
+}
+
+func TestLogoutUser(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	createTestAdmin(t, "admin@example.com", "password123")
+	userID := createTestUser(t, "user@example.com", "password123")
+	adminToken := loginTestUser(t, "admin@example.com", "password123")
+	userTokens := loginTestUserTokens(t, "user@example.com", "password123")
+	loginTestUser(t, "user@example.com", "password123")
+
+	rec := adminRequest(http.MethodPost, "/api/v1/admin/users/"+strconv.Itoa(userID)+"/logout", adminToken, nil, handlers.LogoutUser)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"revoked":2`)
+
+	// Neither the access nor the refresh token works any more
+	assert.Equal(t, http.StatusUnauthorized, authRequest(http.MethodGet, "/api/v1/me", userTokens.Token, nil, handlers.GetMe).Code)
+	assert.Equal(t, http.StatusUnauthorized, refreshTestToken(userTokens.RefreshToken).Code)
+
+	// The user can still log in
+	loginTestUser(t, "user@example.com", "password123")
+}
+
+func TestAdminStatsAndAudit(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	adminID := createTestAdmin(t, "admin@example.com", "password123")
+	userID := createTestUser(t, "user@example.com", "password123")
+	adminToken := loginTestUser(t, "admin@example.com", "password123")
+	userToken := loginTestUser(t, "user@example.com", "password123")
+	insertTestTask(t, userID, "Open", "2025-01-01 10:00:00", false)
+	insertTestTask(t, userID, "Done", "2025-01-01 11:00:00", true)
+
+	rec := adminRequest(http.MethodGet, "/api/v1/admin/stats", adminToken, nil, handlers.GetAdminStats)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var stats struct {
+		Data models.AdminStatsResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	assert.Equal(t, 2, stats.Data.Users.Total)
+	assert.Equal(t, 1, stats.Data.Users.Admins)
+	assert.Equal(t, 2, stats.Data.ActiveSessions)
+	assert.Equal(t, 2, stats.Data.Tasks.Total)
+	assert.Equal(t, 1, stats.Data.Tasks.Completed)
+
+	// Users cannot see the audit log
+	rec = adminRequest(http.MethodGet, "/api/v1/admin/audit", userToken, nil, handlers.GetAdminAudit)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = adminRequest(http.MethodPost, "/api/v1/admin/users/"+strconv.Itoa(userID)+"/disable", adminToken, strings.NewReader(`{"reason": "spam"}`), handlers.DisableUser)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Every admin action is recorded, newest first
+	rec = adminRequest(http.MethodGet, "/api/v1/admin/audit", adminToken, nil, handlers.GetAdminAudit)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var audit struct {
+		Data []models.AdminAuditEntry `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &audit))
+	if assert.Len(t, audit.Data, 2) {
+		disable := audit.Data[0]
+		assert.Equal(t, models.AdminActionDisableUser, disable.Action)
+		assert.Equal(t, adminID, disable.AdminID)
+		assert.Equal(t, "admin@example.com", disable.AdminEmail)
+		assert.Equal(t, userID, *disable.TargetUserID)
+		assert.Equal(t, "spam", disable.Details["reason"])
+		assert.Equal(t, float64(1), disable.Details["revoked_sessions"])
+		assert.NotEmpty(t, disable.IPAddress)
+
+		assert.Equal(t, models.AdminActionViewStats, audit.Data[1].Action)
+		assert.Nil(t, audit.Data[1].TargetUserID)
+	}
+
+	// Reading the audit log is recorded too, and can be filtered by user
+	rec = adminRequest(http.MethodGet, "/api/v1/admin/audit?user_id="+strconv.Itoa(userID), adminToken, nil, handlers.GetAdminAudit)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &audit))
+	assert.Len(t, audit.Data, 1)
+	var count int
+	assert.NoError(t, config.DB.QueryRow("SELECT COUNT(*) FROM admin_audit_log WHERE action = ?", models.AdminActionViewAudit).Scan(&count))
+	assert.Equal(t, 2, count)
+}
@@ -0,0 +1,173 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// pngHeader is enough of a PNG file for its type to be detected
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// uploadTestAttachment uploads content as a file attachment of a task
+func uploadTestAttachment(userID, taskID int, filename string, content []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("note", "ignored")
+	part, _ := writer.CreateFormFile("file", filename)
+	part.Write(content)
+	writer.Close()
+
+	req := userRequest(userID, http.MethodPost, "/api/v1/tasks/"+strconv.Itoa(taskID)+"/attachments", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+	handlers.UploadTaskAttachment(rec, req)
+	return rec
+}
+
+// decodeTestAttachment decodes the attachment in an upload response
+func decodeTestAttachment(t *testing.T, rec *httptest.ResponseRecorder) models.AttachmentResponse {
+	t.Helper()
+
+	var resp struct {
+		Data models.AttachmentResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.Data
+}
+
+// countFiles counts the stored files below dir
+func countFiles(t *testing.T, dir string) int {
+	t.Helper()
+
+	count := 0
+	filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			count++
+		}
+		return nil
+	})
+	return count
+}
+
+func TestTaskAttachments(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	// Store files in a temporary directory with a small size limit
+	dir, maxSize := config.AttachmentsDir, config.MaxAttachmentSize
+	config.AttachmentsDir, config.MaxAttachmentSize = t.TempDir(), 1024
+	defer func() { config.AttachmentsDir, config.MaxAttachmentSize = dir, maxSize }()
+
+	ownerID := createTestUser(t, "owner@example.com", "password123")
+	viewerID := createTestUser(t, "viewer@example.com", "password123")
+	strangerID := createTestUser(t, "stranger@example.com", "password123")
+
+	taskID := insertTestTask(t, ownerID, "with files", "2025-01-01 10:00:00", false)
+	otherTaskID := insertTestTask(t, ownerID, "other", "2025-01-02 10:00:00", false)
+	assert.Equal(t, http.StatusCreated, shareTestTask(ownerID, taskID, "viewer@example.com", models.RoleViewer).Code)
+
+	// The type is detected from the content, not the name or header
+	rec := uploadTestAttachment(ownerID, taskID, "../../notes.txt", []byte("hello attachments"))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	notes := decodeTestAttachment(t, rec)
+	assert.Equal(t, "notes.txt", notes.Filename)
+	assert.Equal(t, "text/plain; charset=utf-8", notes.ContentType)
+	assert.Equal(t, int64(17), notes.Size)
+
+	rec = uploadTestAttachment(ownerID, taskID, "image.txt", pngHeader)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "image/png", decodeTestAttachment(t, rec).ContentType)
+
+	// Types outside the allow-list and files over the limit are rejected
+	assert.Equal(t, http.StatusUnsupportedMediaType, uploadTestAttachment(ownerID, taskID, "page.txt", []byte("<html><body>hi</body></html>")).Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, uploadTestAttachment(ownerID, taskID, "big.txt", bytes.Repeat([]byte("a"), 1025)).Code)
+
+	// Viewers cannot upload, users without access cannot see the task
+	assert.Equal(t, http.StatusForbidden, uploadTestAttachment(viewerID, taskID, "x.txt", []byte("x")).Code)
+	assert.Equal(t, http.StatusNotFound, uploadTestAttachment(strangerID, taskID, "x.txt", []byte("x")).Code)
+
+	req := userRequest(ownerID, http.MethodPost, "/api/v1/tasks/"+strconv.Itoa(taskID)+"/attachments", strings.NewReader(`{}`))
+	rec = httptest.NewRecorder()
+	handlers.UploadTaskAttachment(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Identical content is stored once
+	rec = uploadTestAttachment(ownerID, otherTaskID, "copy.txt", []byte("hello attachments"))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, notes.SHA256, decodeTestAttachment(t, rec).SHA256)
+	assert.Equal(t, 2, countFiles(t, config.AttachmentsDir))
+
+	rec = httptest.NewRecorder()
+	handlers.GetTaskAttachments(rec, userRequest(viewerID, http.MethodGet, "/api/v1/tasks/"+strconv.Itoa(taskID)+"/attachments", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var list struct {
+		Data []models.AttachmentResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	assert.Len(t, list.Data, 2)
+
+	// Downloads support ranges and are never rendered inline
+	path := "/api/v1/tasks/" + strconv.Itoa(taskID) + "/attachments/" + strconv.Itoa(notes.ID)
+	rec = httptest.NewRecorder()
+	handlers.DownloadTaskAttachment(rec, userRequest(viewerID, http.MethodGet, path, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "hello attachments", rec.Body.String())
+	assert.Equal(t, `attachment; filename=notes.txt`, rec.Header().Get("Content-Disposition"))
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+
+	req = userRequest(viewerID, http.MethodGet, path, nil)
+	req.Header.Set("Range", "bytes=6-16")
+	rec = httptest.NewRecorder()
+	handlers.DownloadTaskAttachment(rec, req)
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, "attachments", rec.Body.String())
+	assert.Equal(t, "bytes 6-16/17", rec.Header().Get("Content-Range"))
+
+	// Attachments cannot be reached through another task
+	rec = httptest.NewRecorder()
+	handlers.DownloadTaskAttachment(rec, userRequest(ownerID, http.MethodGet, "/api/v1/tasks/"+strconv.Itoa(otherTaskID)+"/attachments/"+strconv.Itoa(notes.ID), nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Viewers cannot delete; deleting keeps files other attachments still use
+	rec = httptest.NewRecorder()
+	handlers.DeleteTaskAttachment(rec, userRequest(viewerID, http.MethodDelete, path, nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handlers.DeleteTaskAttachment(rec, userRequest(ownerID, http.MethodDelete, path, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 2, countFiles(t, config.AttachmentsDir))
+
+	// Tasks in the trash keep their files until they are purged
+	rec = httptest.NewRecorder()
+	handlers.DeleteTask(rec, userRequest(ownerID, http.MethodDelete, "/api/v1/tasks/"+strconv.Itoa(taskID), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 2, countFiles(t, config.AttachmentsDir))
+
+	// Purging tasks removes the files they owned
+	assert.Equal(t, http.StatusOK, purgeTestTask(ownerID, taskID).Code)
+	assert.Equal(t, 1, countFiles(t, config.AttachmentsDir))
+
+	rec = httptest.NewRecorder()
+	handlers.DeleteTask(rec, userRequest(ownerID, http.MethodDelete, "/api/v1/tasks/"+strconv.Itoa(otherTaskID), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, http.StatusOK, purgeTestTask(ownerID, otherTaskID).Code)
+	assert.Equal(t, 0, countFiles(t, config.AttachmentsDir))
+}
@@ -0,0 +1,142 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// runTestBatch runs a batch as userID and decodes its results
+func runTestBatch(t *testing.T, userID int, body string) (int, models.BatchResponse) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	handlers.BatchTasks(rec, userRequest(userID, http.MethodPost, "/api/v1/tasks/batch", strings.NewReader(body)))
+
+	var resp struct {
+		Data models.BatchResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return rec.Code, resp.Data
+}
+
+func TestBatchTasks(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "batch@example.com", "password123")
+	otherID := createTestUser(t, "other-batch@example.com", "password123")
+	updateID := insertTestTask(t, userID, "update me", "2025-01-01 10:00:00", false)
+	completeID := insertTestTask(t, userID, "complete me", "2025-01-02 10:00:00", false)
+	deleteID := insertTestTask(t, userID, "delete me", "2025-01-03 10:00:00", false)
+	foreignID := insertTestTask(t, otherID, "not mine", "2025-01-04 10:00:00", false)
+
+	body := `{"operations": [
+		{"op": "create", "task": {"title": "created", "priority": "high"}},
+		{"op": "update", "id": ` + strconv.Itoa(updateID) + `, "task": {"title": "updated"}},
+		{"op": "complete", "id": ` + strconv.Itoa(completeID) + `},
+		{"op": "delete", "id": ` + strconv.Itoa(deleteID) + `}
+	]}`
+	code, batch := runTestBatch(t, userID, body)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, batch.Committed)
+	assert.Equal(t, models.BatchModeAtomic, batch.Mode)
+	assert.Equal(t, 4, batch.Succeeded)
+	if assert.Len(t, batch.Results, 4) {
+		assert.Equal(t, http.StatusCreated, batch.Results[0].Status)
+		assert.Equal(t, "created", batch.Results[0].Task.Title)
+		assert.Equal(t, "updated", batch.Results[1].Task.Title)
+		assert.True(t, batch.Results[2].Task.Completed)
+		assert.Nil(t, batch.Results[3].Task)
+	}
+
+	_, list := listTestTasks(t, userID, nil)
+	assert.ElementsMatch(t, []string{"created", "updated", "complete me"}, titles(list.Data))
+
+	// One failing operation rolls an atomic batch back
+	body = `{"operations": [
+		{"op": "create", "task": {"title": "rolled back"}},
+		{"op": "update", "id": ` + strconv.Itoa(updateID) + `, "task": {"title": ""}},
+		{"op": "reopen", "id": ` + strconv.Itoa(completeID) + `}
+	]}`
+	code, batch = runTestBatch(t, userID, body)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.False(t, batch.Committed)
+	if assert.Len(t, batch.Results, 2) {
+		assert.Equal(t, http.StatusUnprocessableEntity, batch.Results[1].Status)
+		assert.Equal(t, "title is required", batch.Results[1].Errors["title"])
+	}
+
+	_, list = listTestTasks(t, userID, nil)
+	assert.NotContains(t, titles(list.Data), "rolled back")
+
+	// A continue batch keeps the operations that succeeded
+	body = `{"mode": "continue", "operations": [
+		{"op": "create", "task": {"title": "kept"}},
+		{"op": "update", "id": ` + strconv.Itoa(foreignID) + `, "task": {"title": "stolen"}},
+		{"op": "archive", "id": ` + strconv.Itoa(updateID) + `},
+		{"op": "create", "task": {"title": "bad label", "label_ids": [999]}},
+		{"op": "reopen", "id": ` + strconv.Itoa(completeID) + `}
+	]}`
+	code, batch = runTestBatch(t, userID, body)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, batch.Committed)
+	assert.Equal(t, 2, batch.Succeeded)
+	assert.Equal(t, 3, batch.Failed)
+	if assert.Len(t, batch.Results, 5) {
+		assert.Equal(t, http.StatusNotFound, batch.Results[1].Status)
+		assert.Equal(t, http.StatusUnprocessableEntity, batch.Results[2].Status)
+		assert.Contains(t, batch.Results[2].Errors, "op")
+		assert.Equal(t, http.StatusUnprocessableEntity, batch.Results[3].Status)
+		assert.False(t, batch.Results[4].Task.Completed)
+	}
+
+	_, list = listTestTasks(t, userID, nil)
+	assert.Contains(t, titles(list.Data), "kept")
+	assert.NotContains(t, titles(list.Data), "bad label")
+}
+
+func TestBatchTasksLimit(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	limit := config.MaxBatchSize
+	config.MaxBatchSize = 2
+	defer func() { config.MaxBatchSize = limit }()
+
+	userID := createTestUser(t, "batch-limit@example.com", "password123")
+
+	op := `{"op": "create", "task": {"title": "task"}}`
+	rec := httptest.NewRecorder()
+	body := `{"operations": [` + op + `,` + op + `,` + op + `]}`
+	handlers.BatchTasks(rec, userRequest(userID, http.MethodPost, "/api/v1/tasks/batch", strings.NewReader(body)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "at most 2 operations")
+
+	rec = httptest.NewRecorder()
+	handlers.BatchTasks(rec, userRequest(userID, http.MethodPost, "/api/v1/tasks/batch", strings.NewReader(`{"operations": []}`)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	// Bodies larger than the limit allows are refused without reading them
+	large := `{"op": "create", "task": {"title": "task", "description": "` + strings.Repeat("x", int(config.MaxBatchOperationSize)) + `"}}`
+	rec = httptest.NewRecorder()
+	handlers.BatchTasks(rec, userRequest(userID, http.MethodPost, "/api/v1/tasks/batch", strings.NewReader(`{"operations": [`+large+`,`+large+`]}`)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Equal(t, 0, countTestTasks(t, "task"))
+
+	rec = httptest.NewRecorder()
+	handlers.BatchTasks(rec, userRequest(userID, http.MethodPost, "/api/v1/tasks/batch", strings.NewReader(`{"mode": "sometimes", "operations": [`+op+`]}`)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
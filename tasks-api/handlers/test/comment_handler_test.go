@@ -0,0 +1,125 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// commentTestTask comments on a task as userID
+func commentTestTask(userID, taskID int, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	payload, _ := json.Marshal(models.CommentRequest{Body: body})
+	handlers.CreateTaskComment(rec, userRequest(userID, http.MethodPost, "/api/v1/tasks/"+strconv.Itoa(taskID)+"/comments", strings.NewReader(string(payload))))
+	return rec
+}
+
+// listTestComments lists the comments of a task as userID
+func listTestComments(t *testing.T, userID, taskID int) []models.CommentResponse {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	handlers.GetTaskComments(rec, userRequest(userID, http.MethodGet, "/api/v1/tasks/"+strconv.Itoa(taskID)+"/comments", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Data []models.CommentResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.Data
+}
+
+func TestTaskComments(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	ownerID := createTestUser(t, "owner@example.com", "password123")
+	viewerID := createTestUser(t, "viewer@example.com", "password123")
+	strangerID := createTestUser(t, "stranger@example.com", "password123")
+
+	taskID := insertTestTask(t, ownerID, "discuss", "2025-01-01 10:00:00", false)
+	assert.Equal(t, http.StatusCreated, shareTestTask(ownerID, taskID, "viewer@example.com", models.RoleViewer).Code)
+
+	// Markdown bodies are stored as written, with the author included
+	rec := commentTestTask(ownerID, taskID, "**Bold** plan:\n\n- step one\n- step two")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	var created struct {
+		Data models.CommentResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.Equal(t, "**Bold** plan:\n\n- step one\n- step two", created.Data.Body)
+	assert.Equal(t, "owner@example.com", created.Data.Author.Email)
+	assert.Equal(t, "Test User", created.Data.Author.Name)
+	assert.Nil(t, created.Data.EditedAt)
+	commentID := created.Data.ID
+
+	// Viewers can comment, users who cannot see the task cannot
+	assert.Equal(t, http.StatusCreated, commentTestTask(viewerID, taskID, "Looks good").Code)
+	assert.Equal(t, http.StatusNotFound, commentTestTask(strangerID, taskID, "hello").Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, commentTestTask(ownerID, taskID, "   ").Code)
+
+	rec = httptest.NewRecorder()
+	handlers.GetTaskComments(rec, userRequest(strangerID, http.MethodGet, "/api/v1/tasks/"+strconv.Itoa(taskID)+"/comments", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	comments := listTestComments(t, viewerID, taskID)
+	if assert.Len(t, comments, 2) {
+		assert.Equal(t, commentID, comments[0].ID)
+		assert.Equal(t, "viewer@example.com", comments[1].Author.Email)
+	}
+
+	// Only the author can edit or delete a comment
+	path := "/api/v1/tasks/" + strconv.Itoa(taskID) + "/comments/" + strconv.Itoa(commentID)
+
+	rec = httptest.NewRecorder()
+	handlers.UpdateTaskComment(rec, userRequest(viewerID, http.MethodPut, path, strings.NewReader(`{"body": "hijacked"}`)))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handlers.DeleteTaskComment(rec, userRequest(viewerID, http.MethodDelete, path, nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handlers.UpdateTaskComment(rec, userRequest(ownerID, http.MethodPut, path, strings.NewReader(`{"body": "Updated plan"}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var updated struct {
+		Data models.CommentResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &updated))
+	assert.Equal(t, "Updated plan", updated.Data.Body)
+	assert.NotNil(t, updated.Data.EditedAt)
+
+	// Comments of other tasks cannot be reached through this task
+	otherTaskID := insertTestTask(t, ownerID, "other", "2025-01-02 10:00:00", false)
+	rec = httptest.NewRecorder()
+	handlers.DeleteTaskComment(rec, userRequest(ownerID, http.MethodDelete, "/api/v1/tasks/"+strconv.Itoa(otherTaskID)+"/comments/"+strconv.Itoa(commentID), nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handlers.DeleteTaskComment(rec, userRequest(ownerID, http.MethodDelete, path, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, listTestComments(t, ownerID, taskID), 1)
+
+	// Purging the task deletes its comments
+	rec = httptest.NewRecorder()
+	handlers.DeleteTask(rec, userRequest(ownerID, http.MethodDelete, "/api/v1/tasks/"+strconv.Itoa(taskID), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, http.StatusOK, purgeTestTask(ownerID, taskID).Code)
+
+	var count int
+	assert.NoError(t, config.DB.QueryRow("SELECT COUNT(*) FROM task_comments WHERE task_id = ?", taskID).Scan(&count))
+	assert.Equal(t, 0, count)
+}
@@ -0,0 +1,90 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// addTestDependency makes taskID blocked by blockerID and returns the status code
+func addTestDependency(t *testing.T, userID, taskID, blockerID int) int {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	body := `{"blocked_by_id": ` + strconv.Itoa(blockerID) + `}`
+	target := "/api/v1/tasks/" + strconv.Itoa(taskID) + "/dependencies"
+	handlers.AddTaskDependency(rec, userRequest(userID, http.MethodPost, target, strings.NewReader(body)))
+	return rec.Code
+}
+
+func completeTestTask(userID, taskID int) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handlers.CompleteTask(rec, userRequest(userID, http.MethodPatch, "/api/v1/tasks/"+strconv.Itoa(taskID), nil))
+	return rec
+}
+
+func TestTaskDependencies(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "dependencies@example.com", "password123")
+	otherID := createTestUser(t, "other-dependencies@example.com", "password123")
+
+	design := insertTestTask(t, userID, "design", "2025-01-01 10:00:00", false)
+	build := insertTestTask(t, userID, "build", "2025-01-02 10:00:00", false)
+	ship := insertTestTask(t, userID, "ship", "2025-01-03 10:00:00", false)
+	foreign := insertTestTask(t, otherID, "foreign", "2025-01-04 10:00:00", false)
+
+	// ship <- build <- design
+	assert.Equal(t, http.StatusCreated, addTestDependency(t, userID, build, design))
+	assert.Equal(t, http.StatusCreated, addTestDependency(t, userID, ship, build))
+
+	// Direct, transitive and self cycles are rejected
+	assert.Equal(t, http.StatusConflict, addTestDependency(t, userID, design, build))
+	assert.Equal(t, http.StatusConflict, addTestDependency(t, userID, design, ship))
+	assert.Equal(t, http.StatusConflict, addTestDependency(t, userID, design, design))
+
+	// Other users' tasks cannot be used on either side
+	assert.Equal(t, http.StatusUnprocessableEntity, addTestDependency(t, userID, design, foreign))
+	assert.Equal(t, http.StatusNotFound, addTestDependency(t, otherID, design, foreign))
+
+	rec := httptest.NewRecorder()
+	handlers.GetTaskDependencies(rec, userRequest(userID, http.MethodGet, "/api/v1/tasks/"+strconv.Itoa(build)+"/dependencies", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Data models.TaskDependenciesResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	if assert.Len(t, resp.Data.BlockedBy, 1) && assert.Len(t, resp.Data.Blocking, 1) {
+		assert.Equal(t, "design", resp.Data.BlockedBy[0].Title)
+		assert.Equal(t, "ship", resp.Data.Blocking[0].Title)
+	}
+
+	// Blocked tasks cannot be completed until their blockers are
+	rec = completeTestTask(userID, build)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), strconv.Itoa(design))
+
+	assert.Equal(t, http.StatusOK, completeTestTask(userID, design).Code)
+	assert.Equal(t, http.StatusOK, completeTestTask(userID, build).Code)
+
+	// Removing an edge unblocks the task
+	rec = httptest.NewRecorder()
+	target := "/api/v1/tasks/" + strconv.Itoa(ship) + "/dependencies/" + strconv.Itoa(build)
+	handlers.RemoveTaskDependency(rec, userRequest(userID, http.MethodDelete, target, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handlers.RemoveTaskDependency(rec, userRequest(userID, http.MethodDelete, target, nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
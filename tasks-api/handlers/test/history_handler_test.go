@@ -0,0 +1,163 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type activityResponse struct {
+	Data []models.TaskEventResponse `json:"data"`
+	Meta *models.Meta               `json:"meta"`
+}
+
+// getTestHistory fetches the history of a task as userID
+func getTestHistory(t *testing.T, userID, taskID int) (int, []models.TaskEventResponse) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	handlers.GetTaskHistory(rec, userRequest(userID, http.MethodGet, "/api/v1/tasks/"+strconv.Itoa(taskID)+"/history", nil))
+
+	var resp struct {
+		Data []models.TaskEventResponse `json:"data"`
+	}
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+	return rec.Code, resp.Data
+}
+
+// getTestActivity fetches a page of the activity feed of userID
+func getTestActivity(t *testing.T, userID int, params url.Values) (int, activityResponse) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	handlers.GetActivity(rec, userRequest(userID, http.MethodGet, "/api/v1/activity?"+params.Encode(), nil))
+
+	var resp activityResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return rec.Code, resp
+}
+
+func TestTaskHistory(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	ownerID := createTestUser(t, "owner@example.com", "password123")
+	editorID := createTestUser(t, "editor@example.com", "password123")
+	strangerID := createTestUser(t, "stranger@example.com", "password123")
+
+	rec := httptest.NewRecorder()
+	handlers.CreateTask(rec, userRequest(ownerID, http.MethodPost, "/api/v1/tasks", strings.NewReader(`{"title": "Draft", "priority": "low"}`)))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	var created struct {
+		Data models.TaskResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	taskID := created.Data.ID
+
+	assert.Equal(t, http.StatusCreated, shareTestTask(ownerID, taskID, "editor@example.com", models.RoleEditor).Code)
+
+	// Updates record only the fields that changed, with the editor as the actor
+	rec = httptest.NewRecorder()
+	handlers.UpdateTask(rec, userRequest(editorID, http.MethodPut, "/api/v1/tasks/"+strconv.Itoa(taskID), strings.NewReader(`{"title": "Final", "priority": "low"}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Saving the same values again is not an event
+	assert.Equal(t, http.StatusOK, updateTestTask(editorID, taskID, "Final").Code)
+
+	assert.Equal(t, http.StatusOK, completeTestTask(ownerID, taskID).Code)
+	assert.Equal(t, http.StatusOK, completeTestTask(ownerID, taskID).Code)
+
+	code, events := getTestHistory(t, editorID, taskID)
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, events, 4) {
+		assert.Equal(t, models.TaskEventCreated, events[0].Action)
+		assert.Equal(t, "owner@example.com", events[0].Actor.Email)
+		assert.Equal(t, models.FieldChange{Old: nil, New: "Draft"}, events[0].Changes["title"])
+
+		assert.Equal(t, models.TaskEventUpdated, events[1].Action)
+		assert.Equal(t, editorID, events[1].Actor.ID)
+		assert.Equal(t, map[string]models.FieldChange{"title": {Old: "Draft", New: "Final"}}, events[1].Changes)
+
+		// updateTestTask leaves out the priority, which resets it to medium
+		assert.Equal(t, map[string]models.FieldChange{"priority": {Old: "low", New: "medium"}}, events[2].Changes)
+
+		assert.Equal(t, models.TaskEventCompleted, events[3].Action)
+		assert.Equal(t, models.FieldChange{Old: false, New: true}, events[3].Changes["completed"])
+		assert.Nil(t, events[3].Changes["completed_at"].Old)
+		assert.NotNil(t, events[3].Changes["completed_at"].New)
+	}
+
+	code, _ = getTestHistory(t, strangerID, taskID)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// Deleting keeps the history in the activity feed
+	rec = httptest.NewRecorder()
+	handlers.DeleteTask(rec, userRequest(ownerID, http.MethodDelete, "/api/v1/tasks/"+strconv.Itoa(taskID), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	code, feed := getTestActivity(t, ownerID, nil)
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, feed.Data, 3) {
+		assert.Equal(t, models.TaskEventDeleted, feed.Data[0].Action)
+		assert.Equal(t, "Final", feed.Data[0].TaskTitle)
+		assert.Equal(t, models.FieldChange{Old: "Final", New: nil}, feed.Data[0].Changes["title"])
+		assert.Equal(t, models.TaskEventCompleted, feed.Data[1].Action)
+		assert.Equal(t, models.TaskEventCreated, feed.Data[2].Action)
+	}
+
+	// The feed only has the user's own changes
+	_, feed = getTestActivity(t, editorID, nil)
+	assert.Len(t, feed.Data, 2)
+}
+
+func TestActivityPagination(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "feed@example.com", "password123")
+
+	for i := 1; i <= 5; i++ {
+		rec := httptest.NewRecorder()
+		handlers.CreateTask(rec, userRequest(userID, http.MethodPost, "/api/v1/tasks", strings.NewReader(`{"title": "task `+strconv.Itoa(i)+`"}`)))
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	}
+
+	titles := []string{}
+	params := url.Values{"limit": {"2"}}
+	for page := 0; page < 5; page++ {
+		code, resp := getTestActivity(t, userID, params)
+		assert.Equal(t, http.StatusOK, code)
+		for _, event := range resp.Data {
+			titles = append(titles, event.TaskTitle)
+		}
+		if resp.Meta.NextCursor == nil {
+			break
+		}
+		params.Set("cursor", *resp.Meta.NextCursor)
+	}
+	assert.Equal(t, []string{"task 5", "task 4", "task 3", "task 2", "task 1"}, titles)
+
+	code, _ := getTestActivity(t, userID, url.Values{"limit": {"0"}})
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+
+	code, _ = getTestActivity(t, userID, url.Values{"cursor": {"%%%"}})
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+}
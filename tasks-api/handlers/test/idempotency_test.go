@@ -0,0 +1,240 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+// idempotentRequest sends a request with an Idempotency-Key through the
+// Idempotency middleware as userID
+func idempotentRequest(userID int, method, target, body, key string, next http.HandlerFunc) *httptest.ResponseRecorder {
+	req := userRequest(userID, method, target, strings.NewReader(body))
+	req.Header.Set(middleware.IdempotencyKeyHeader, key)
+
+	rec := httptest.NewRecorder()
+	middleware.Idempotency(next)(rec, req)
+	return rec
+}
+
+// countTestTasks counts the tasks with the given title
+func countTestTasks(t *testing.T, title string) int {
+	t.Helper()
+
+	var count int
+	if err := config.DB.QueryRow("SELECT COUNT(*) FROM tasks WHERE title = ?", title).Scan(&count); err != nil {
+		t.Fatalf("Failed to count tasks: %v", err)
+	}
+	return count
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "idempotent@example.com", "password123")
+	otherID := createTestUser(t, "other-idempotent@example.com", "password123")
+
+	// Retrying a request replays the first response without creating a duplicate
+	first := idempotentRequest(userID, http.MethodPost, "/api/v1/tasks", `{"title": "once"}`, "key-1", handlers.CreateTask)
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	retry := idempotentRequest(userID, http.MethodPost, "/api/v1/tasks", `{"title": "once"}`, "key-1", handlers.CreateTask)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, first.Header().Get("ETag"), retry.Header().Get("ETag"))
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, 1, countTestTasks(t, "once"))
+
+	// Reusing the key for a different request is an error
+	rec := idempotentRequest(userID, http.MethodPost, "/api/v1/tasks", `{"title": "twice"}`, "key-1", handlers.CreateTask)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, 0, countTestTasks(t, "twice"))
+
+	// Keys belong to a single user
+	rec = idempotentRequest(otherID, http.MethodPost, "/api/v1/tasks", `{"title": "once"}`, "key-1", handlers.CreateTask)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Empty(t, rec.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, 2, countTestTasks(t, "once"))
+
+	// Requests without a key and safe requests are not recorded
+	rec = idempotentRequest(userID, http.MethodPost, "/api/v1/tasks", `{"title": "no key"}`, "", handlers.CreateTask)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	var stored int
+	assert.NoError(t, config.DB.QueryRow("SELECT COUNT(*) FROM idempotency_keys").Scan(&stored))
+	assert.Equal(t, 2, stored)
+
+	// Client errors are replayed too
+	rec = idempotentRequest(userID, http.MethodPost, "/api/v1/tasks", `{"title": ""}`, "key-2", handlers.CreateTask)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	rec = idempotentRequest(userID, http.MethodPost, "/api/v1/tasks", `{"title": ""}`, "key-2", handlers.CreateTask)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get("Idempotent-Replayed"))
+
+	// Server errors release the key so the request can be retried
+	failing := func(w http.ResponseWriter, r *http.Request) {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed", nil)
+	}
+	assert.Equal(t, http.StatusInternalServerError, idempotentRequest(userID, http.MethodPost, "/api/v1/tasks", `{"title": "retried"}`, "key-3", failing).Code)
+	assert.Equal(t, http.StatusCreated, idempotentRequest(userID, http.MethodPost, "/api/v1/tasks", `{"title": "retried"}`, "key-3", handlers.CreateTask).Code)
+}
+
+func TestIdempotencyKeyFailures(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "failures@example.com", "password123")
+
+	// Bodies are only read into memory up to a limit
+	maxSize := config.MaxIdempotentRequestSize
+	config.MaxIdempotentRequestSize = 32
+	rec := idempotentRequest(userID, http.MethodPost, "/api/v1/tasks", `{"title": "`+strings.Repeat("x", 32)+`"}`, "large", handlers.CreateTask)
+	config.MaxIdempotentRequestSize = maxSize
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Equal(t, 0, countTestTasks(t, strings.Repeat("x", 32)))
+
+	// A response that cannot be stored releases the key instead of leaving
+	// it in progress
+	_, err := config.DB.Exec("CREATE TRIGGER fail_idempotency_store BEFORE UPDATE ON idempotency_keys BEGIN SELECT RAISE(ABORT, 'store failed'); END")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, idempotentRequest(userID, http.MethodPost, "/api/v1/tasks", `{"title": "unstored"}`, "key", handlers.CreateTask).Code)
+
+	var stored int
+	assert.NoError(t, config.DB.QueryRow("SELECT COUNT(*) FROM idempotency_keys").Scan(&stored))
+	assert.Equal(t, 0, stored)
+
+	_, err = config.DB.Exec("DROP TRIGGER fail_idempotency_store")
+	assert.NoError(t, err)
+	rec = idempotentRequest(userID, http.MethodPost, "/api/v1/tasks", `{"title": "unstored"}`, "key", handlers.CreateTask)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Empty(t, rec.Header().Get("Idempotent-Replayed"))
+}
+
+func TestIdempotencyKeyInFlight(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "in-flight@example.com", "password123")
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		config.WriteCreatedResponse(w, "Created", nil)
+	}
+
+	var wg sync.WaitGroup
+	var first *httptest.ResponseRecorder
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		first = idempotentRequest(userID, http.MethodPost, "/api/v1/tasks", `{}`, "key", slow)
+	}()
+
+	// A second request while the first one runs is refused
+	<-started
+	rec := idempotentRequest(userID, http.MethodPost, "/api/v1/tasks", `{}`, "key", slow)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	// Once it has finished its response is replayed
+	rec = idempotentRequest(userID, http.MethodPost, "/api/v1/tasks", `{}`, "key", slow)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get("Idempotent-Replayed"))
+}
+
+func TestIdempotencyKeyExpiry(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "expiry@example.com", "password123")
+
+	assert.Equal(t, http.StatusCreated, idempotentRequest(userID, http.MethodPost, "/api/v1/tasks", `{"title": "expiring"}`, "key", handlers.CreateTask).Code)
+
+	// Expired keys can be used again and are purged
+	_, err := config.DB.Exec("UPDATE idempotency_keys SET expires_at = datetime('now', '-1 hour')")
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusCreated, idempotentRequest(userID, http.MethodPost, "/api/v1/tasks", `{"title": "expiring"}`, "key", handlers.CreateTask).Code)
+	assert.Equal(t, 2, countTestTasks(t, "expiring"))
+
+	_, err = config.DB.Exec("UPDATE idempotency_keys SET expires_at = datetime('now', '-1 hour')")
+	assert.NoError(t, err)
+
+	removed, err := middleware.PurgeExpiredIdempotencyKeys()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+}
+
+func TestRegisterIdempotencyKey(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	body := `{"name": "Retry User", "email": "retry@example.com", "password": "password123"}`
+	register := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/register", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.IdempotencyKeyHeader, "register-key")
+		rec := httptest.NewRecorder()
+		middleware.Idempotency(handlers.Register)(rec, req)
+		return rec
+	}
+
+	// Without authentication keys are scoped to the client address
+	first := register()
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	retry := register()
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+}
+
+func TestIdempotencyKeySecretResponses(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	createTestUser(t, "secret@example.com", "password123")
+	sessionToken := loginTestUser(t, "secret@example.com", "password123")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/tokens", middleware.AuthMiddleware(handlers.CreateAccessToken))
+	createToken := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tokens", strings.NewReader(`{"name": "CI", "scopes": ["tasks:read"]}`))
+		req.Header.Set("Authorization", "Bearer "+sessionToken)
+		req.Header.Set(middleware.IdempotencyKeyHeader, "token-key")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Responses with a new token are not stored, so the key is not honoured
+	first := createToken()
+	assert.Equal(t, http.StatusCreated, first.Code)
+	retry := createToken()
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Empty(t, retry.Header().Get("Idempotent-Replayed"))
+	assert.NotEqual(t, first.Body.String(), retry.Body.String())
+
+	var stored int
+	assert.NoError(t, config.DB.QueryRow("SELECT COUNT(*) FROM idempotency_keys").Scan(&stored))
+	assert.Equal(t, 0, stored)
+}
@@ -0,0 +1,128 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// inviteTestUser invites email to a workspace as userID
+func inviteTestUser(userID, workspaceID int, email, role string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	body := `{"email": "` + email + `", "role": "` + role + `"}`
+	handlers.CreateInvitation(rec, userRequest(userID, http.MethodPost, "/api/v1/workspaces/"+strconv.Itoa(workspaceID)+"/invitations", strings.NewReader(body)))
+	return rec
+}
+
+// acceptTestInvitation accepts an invitation token as userID
+func acceptTestInvitation(userID int, token string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handlers.AcceptInvitation(rec, userRequest(userID, http.MethodPost, "/api/v1/invitations/accept", strings.NewReader(`{"token": "`+token+`"}`)))
+	return rec
+}
+
+// invitationToken returns the token of a created invitation
+func invitationToken(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	var resp struct {
+		Data models.InvitationResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.Data.Token
+}
+
+func TestInvitations(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	ownerID := createTestUser(t, "owner@example.com", "password123")
+	memberID := createTestUser(t, "member@example.com", "password123")
+	inviteeID := createTestUser(t, "invitee@example.com", "password123")
+	otherID := createTestUser(t, "other@example.com", "password123")
+
+	workspaceID := createTestWorkspace(t, ownerID, "Team")
+	addTestMember(t, workspaceID, memberID, models.WorkspaceRoleMember)
+	path := "/api/v1/workspaces/" + strconv.Itoa(workspaceID) + "/invitations"
+
+	// Members cannot invite, existing members and personal workspaces are rejected
+	assert.Equal(t, http.StatusForbidden, inviteTestUser(memberID, workspaceID, "invitee@example.com", models.WorkspaceRoleMember).Code)
+	assert.Equal(t, http.StatusConflict, inviteTestUser(ownerID, workspaceID, "Member@Example.com", models.WorkspaceRoleMember).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, inviteTestUser(ownerID, workspaceID, "invitee@example.com", models.WorkspaceRoleOwner).Code)
+
+	personal, err := middleware.LookupWorkspace(ownerID, "")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, inviteTestUser(ownerID, personal.WorkspaceID, "invitee@example.com", models.WorkspaceRoleMember).Code)
+
+	// Inviting again replaces the pending invitation
+	rec := inviteTestUser(ownerID, workspaceID, "invitee@example.com", models.WorkspaceRoleMember)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	staleToken := invitationToken(t, rec)
+
+	rec = inviteTestUser(ownerID, workspaceID, "Invitee@Example.com", models.WorkspaceRoleAdmin)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	token := invitationToken(t, rec)
+	assert.NotEmpty(t, token)
+
+	rec = httptest.NewRecorder()
+	handlers.GetInvitations(rec, userRequest(ownerID, http.MethodGet, path, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var list struct {
+		Data []models.InvitationResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	if assert.Len(t, list.Data, 1) {
+		assert.Equal(t, "invitee@example.com", list.Data[0].Email)
+		assert.Equal(t, models.WorkspaceRoleAdmin, list.Data[0].Role)
+		assert.Empty(t, list.Data[0].Token)
+	}
+
+	// Only the invited email can accept, and a token works once
+	assert.Equal(t, http.StatusNotFound, acceptTestInvitation(inviteeID, staleToken).Code)
+	assert.Equal(t, http.StatusForbidden, acceptTestInvitation(otherID, token).Code)
+	assert.Equal(t, http.StatusOK, acceptTestInvitation(inviteeID, token).Code)
+	assert.Equal(t, http.StatusNotFound, acceptTestInvitation(inviteeID, token).Code)
+
+	membership, err := middleware.LookupWorkspace(inviteeID, strconv.Itoa(workspaceID))
+	assert.NoError(t, err)
+	assert.Equal(t, models.WorkspaceRoleAdmin, membership.Role)
+
+	// Expired invitations cannot be accepted
+	rec = inviteTestUser(ownerID, workspaceID, "other@example.com", models.WorkspaceRoleMember)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	expiredToken := invitationToken(t, rec)
+
+	_, err = config.DB.Exec("UPDATE workspace_invitations SET expires_at = '2000-01-01 00:00:00' WHERE email = ?", "other@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusGone, acceptTestInvitation(otherID, expiredToken).Code)
+
+	// Revoked invitations cannot be accepted
+	rec = inviteTestUser(inviteeID, workspaceID, "other@example.com", models.WorkspaceRoleMember)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	revokedToken := invitationToken(t, rec)
+
+	var invitationID int
+	assert.NoError(t, config.DB.QueryRow("SELECT id FROM workspace_invitations WHERE email = ? AND accepted_at IS NULL", "other@example.com").Scan(&invitationID))
+
+	rec = httptest.NewRecorder()
+	handlers.RevokeInvitation(rec, userRequest(memberID, http.MethodDelete, path+"/"+strconv.Itoa(invitationID), nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handlers.RevokeInvitation(rec, userRequest(ownerID, http.MethodDelete, path+"/"+strconv.Itoa(invitationID), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, http.StatusNotFound, acceptTestInvitation(otherID, revokedToken).Code)
+}
@@ -0,0 +1,246 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// createTestLabel creates a label through the handler and returns its ID
+func createTestLabel(t *testing.T, userID int, name string) int {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	body := `{"name": "` + name + `"}`
+	handlers.CreateLabel(rec, userRequest(userID, http.MethodPost, "/api/v1/labels", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Failed to create label: %s", rec.Body.String())
+	}
+
+	var resp struct {
+		Data models.LabelResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.Data.ID
+}
+
+func labelNames(labels []models.LabelResponse) []string {
+	names := []string{}
+	for _, label := range labels {
+		names = append(names, label.Name)
+	}
+	return names
+}
+
+func TestLabelCRUD(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "labels@example.com", "password123")
+	otherID := createTestUser(t, "other-labels@example.com", "password123")
+
+	workID := createTestLabel(t, userID, "work")
+	createTestLabel(t, otherID, "work")
+
+	// Names are unique per user, ignoring case
+	rec := httptest.NewRecorder()
+	handlers.CreateLabel(rec, userRequest(userID, http.MethodPost, "/api/v1/labels", strings.NewReader(`{"name": "Work"}`)))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	// Colours must be hex colours
+	rec = httptest.NewRecorder()
+	handlers.CreateLabel(rec, userRequest(userID, http.MethodPost, "/api/v1/labels", strings.NewReader(`{"name": "home", "color": "red"}`)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	// Update
+	rec = httptest.NewRecorder()
+	body := `{"name": "Office", "color": "#FF0000"}`
+	handlers.UpdateLabel(rec, userRequest(userID, http.MethodPut, "/api/v1/labels/"+strconv.Itoa(workID), strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Data models.LabelResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "Office", resp.Data.Name)
+	assert.Equal(t, "#ff0000", resp.Data.Color)
+
+	// Other users cannot see the label
+	rec = httptest.NewRecorder()
+	handlers.GetOneLabel(rec, userRequest(otherID, http.MethodGet, "/api/v1/labels/"+strconv.Itoa(workID), nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// List
+	rec = httptest.NewRecorder()
+	handlers.GetLabels(rec, userRequest(userID, http.MethodGet, "/api/v1/labels", nil))
+	var list struct {
+		Data []models.LabelResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	assert.Equal(t, []string{"Office"}, labelNames(list.Data))
+}
+
+func TestTaskLabels(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "task-labels@example.com", "password123")
+	otherID := createTestUser(t, "other-task-labels@example.com", "password123")
+
+	workID := createTestLabel(t, userID, "work")
+	urgentID := createTestLabel(t, userID, "urgent")
+	foreignID := createTestLabel(t, otherID, "foreign")
+
+	// Labels are attached on create and returned with the task
+	rec := httptest.NewRecorder()
+	body := `{"title": "Report", "label_ids": [` + strconv.Itoa(workID) + `, ` + strconv.Itoa(urgentID) + `]}`
+	handlers.CreateTask(rec, userRequest(userID, http.MethodPost, "/api/v1/tasks", strings.NewReader(body)))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	var resp struct {
+		Data models.TaskResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, []string{"urgent", "work"}, labelNames(resp.Data.Labels))
+	taskID := strconv.Itoa(resp.Data.ID)
+
+	// Another user's labels are rejected and nothing is created
+	rec = httptest.NewRecorder()
+	body = `{"title": "Sneaky", "label_ids": [` + strconv.Itoa(foreignID) + `]}`
+	handlers.CreateTask(rec, userRequest(userID, http.MethodPost, "/api/v1/tasks", strings.NewReader(body)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	var count int
+	assert.NoError(t, config.DB.QueryRow("SELECT COUNT(*) FROM tasks WHERE title = 'Sneaky'").Scan(&count))
+	assert.Equal(t, 0, count)
+
+	// Updating without label_ids keeps the labels
+	rec = httptest.NewRecorder()
+	handlers.UpdateTask(rec, userRequest(userID, http.MethodPut, "/api/v1/tasks/"+taskID, strings.NewReader(`{"title": "Report v2"}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, []string{"urgent", "work"}, labelNames(resp.Data.Labels))
+
+	// Detach and attach single labels
+	rec = httptest.NewRecorder()
+	handlers.DetachTaskLabel(rec, userRequest(userID, http.MethodDelete, "/api/v1/tasks/"+taskID+"/labels/"+strconv.Itoa(urgentID), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, []string{"work"}, labelNames(resp.Data.Labels))
+
+	rec = httptest.NewRecorder()
+	handlers.AttachTaskLabel(rec, userRequest(userID, http.MethodPost, "/api/v1/tasks/"+taskID+"/labels/"+strconv.Itoa(foreignID), nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Deleting a label detaches it from its tasks
+	rec = httptest.NewRecorder()
+	handlers.DeleteLabel(rec, userRequest(userID, http.MethodDelete, "/api/v1/labels/"+strconv.Itoa(workID), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handlers.GetOneTask(rec, userRequest(userID, http.MethodGet, "/api/v1/tasks/"+taskID, nil))
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, []string{}, labelNames(resp.Data.Labels))
+
+	assert.NoError(t, config.DB.QueryRow("SELECT COUNT(*) FROM task_labels").Scan(&count))
+	assert.Equal(t, 0, count)
+}
+
+func TestTaskLabelsByEditor(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	ownerID := createTestUser(t, "label-owner@example.com", "password123")
+	editorID := createTestUser(t, "label-editor@example.com", "password123")
+	viewerID := createTestUser(t, "label-viewer@example.com", "password123")
+	workID := createTestLabel(t, ownerID, "work")
+	editorLabelID := createTestLabel(t, editorID, "mine")
+	taskID := insertTestTask(t, ownerID, "Shared", "2025-01-01 10:00:00", false)
+	assert.Equal(t, http.StatusCreated, shareTestTask(ownerID, taskID, "label-editor@example.com", models.RoleEditor).Code)
+	assert.Equal(t, http.StatusCreated, shareTestTask(ownerID, taskID, "label-viewer@example.com", models.RoleViewer).Code)
+
+	labelPath := func(labelID int) string {
+		return "/api/v1/tasks/" + strconv.Itoa(taskID) + "/labels/" + strconv.Itoa(labelID)
+	}
+
+	// Editors attach and detach the owner's labels, as with label_ids on updates
+	rec := httptest.NewRecorder()
+	handlers.AttachTaskLabel(rec, userRequest(editorID, http.MethodPost, labelPath(workID), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"name":"work"`)
+
+	rec = httptest.NewRecorder()
+	handlers.AttachTaskLabel(rec, userRequest(editorID, http.MethodPost, labelPath(editorLabelID), nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	body := `{"title": "Shared", "label_ids": [` + strconv.Itoa(editorLabelID) + `]}`
+	handlers.UpdateTask(rec, userRequest(editorID, http.MethodPut, "/api/v1/tasks/"+strconv.Itoa(taskID), strings.NewReader(body)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	// Viewers cannot change labels either way
+	rec = httptest.NewRecorder()
+	handlers.DetachTaskLabel(rec, userRequest(viewerID, http.MethodDelete, labelPath(workID), nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handlers.DetachTaskLabel(rec, userRequest(editorID, http.MethodDelete, labelPath(workID), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"labels":[]`)
+
+	// Both changes are in the task history, with the editor as the actor
+	code, events := getTestHistory(t, ownerID, taskID)
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, models.TaskEventUpdated, events[0].Action)
+		assert.Equal(t, editorID, events[0].Actor.ID)
+		assert.Equal(t, map[string]models.FieldChange{"label_ids": {Old: []any{}, New: []any{float64(workID)}}}, events[0].Changes)
+		assert.Equal(t, map[string]models.FieldChange{"label_ids": {Old: []any{float64(workID)}, New: []any{}}}, events[1].Changes)
+	}
+}
+
+func TestGetTasksLabelFilter(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "label-filter@example.com", "password123")
+	workID := createTestLabel(t, userID, "work")
+	urgentID := createTestLabel(t, userID, "urgent")
+
+	both := insertTestTask(t, userID, "both", "2025-01-01 10:00:00", false)
+	workOnly := insertTestTask(t, userID, "work only", "2025-01-02 10:00:00", false)
+	insertTestTask(t, userID, "none", "2025-01-03 10:00:00", false)
+
+	for _, link := range [][2]int{{both, workID}, {both, urgentID}, {workOnly, workID}} {
+		if _, err := config.DB.Exec("INSERT INTO task_labels (task_id, label_id) VALUES (?, ?)", link[0], link[1]); err != nil {
+			t.Fatalf("Failed to attach label: %v", err)
+		}
+	}
+
+	code, resp := listTestTasks(t, userID, url.Values{"label": {"WORK,urgent"}, "sort": {"title"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"both", "work only"}, titles(resp.Data))
+
+	code, resp = listTestTasks(t, userID, url.Values{"label": {"work,urgent"}, "label_match": {"all"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"both"}, titles(resp.Data))
+
+	code, resp = listTestTasks(t, userID, url.Values{"label": {"work"}, "label_match": {"some"}})
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Contains(t, resp.Errors, "label_match")
+}
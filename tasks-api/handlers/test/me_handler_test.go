@@ -0,0 +1,213 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// decodeTestProfile decodes the profile in a response
+func decodeTestProfile(t *testing.T, rec *httptest.ResponseRecorder) models.ProfileResponse {
+	t.Helper()
+
+	var resp struct {
+		Data models.ProfileResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode profile: %v", err)
+	}
+	return resp.Data
+}
+
+// countTestRows counts the rows of a table that belong to a user
+func countTestRows(t *testing.T, table, column string, userID int) int {
+	t.Helper()
+
+	var count int
+	if err := config.DB.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE "+column+" = ?", userID).Scan(&count); err != nil {
+		t.Fatalf("Failed to count %s: %v", table, err)
+	}
+	return count
+}
+
+func TestGetUserFromContext(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "context@example.com", "password123")
+	token := loginTestUser(t, "context@example.com", "password123")
+
+	rec := authRequest(http.MethodGet, "/api/v1/me", token, nil, func(w http.ResponseWriter, r *http.Request) {
+		user, ok := middleware.GetUserFromContext(r)
+		assert.True(t, ok)
+		assert.Equal(t, userID, user.ID)
+		assert.Equal(t, "Test User", user.Name)
+		assert.Equal(t, "context@example.com", user.Email)
+		assert.Equal(t, "UTC", user.Timezone)
+		assert.NotNil(t, user.EmailVerifiedAt)
+		w.WriteHeader(http.StatusOK)
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestUpdateMe(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	mailPath := useTestMailer(t)
+	createTestUser(t, "me@example.com", "password123")
+	createTestUser(t, "taken@example.com", "password123")
+	token := loginTestUser(t, "me@example.com", "password123")
+
+	rec := authRequest(http.MethodGet, "/api/v1/me", token, nil, handlers.GetMe)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	profile := decodeTestProfile(t, rec)
+	assert.Equal(t, "me@example.com", profile.Email)
+	assert.True(t, profile.EmailVerified)
+	assert.NotContains(t, rec.Body.String(), "password")
+
+	// Fields that are left out keep their value
+	rec = authRequest(http.MethodPatch, "/api/v1/me", token, strings.NewReader(`{"name": "New Name", "timezone": "Europe/Berlin"}`), handlers.UpdateMe)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	profile = decodeTestProfile(t, rec)
+	assert.Equal(t, "New Name", profile.Name)
+	assert.Equal(t, "Europe/Berlin", profile.Timezone)
+	assert.Equal(t, "me@example.com", profile.Email)
+
+	rec = authRequest(http.MethodPatch, "/api/v1/me", token, strings.NewReader(`{"timezone": "Mars/Olympus"}`), handlers.UpdateMe)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	rec = authRequest(http.MethodPatch, "/api/v1/me", token, strings.NewReader(`{"email": "taken@example.com"}`), handlers.UpdateMe)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	// A new email has to be verified again
+	rec = authRequest(http.MethodPatch, "/api/v1/me", token, strings.NewReader(`{"email": "new-me@example.com"}`), handlers.UpdateMe)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	profile = decodeTestProfile(t, rec)
+	assert.Equal(t, "new-me@example.com", profile.Email)
+	assert.False(t, profile.EmailVerified)
+
+	tokens := sentTokens(t, mailPath, "verify-email")
+	assert.Len(t, tokens, 1)
+	assert.Equal(t, http.StatusOK, publicRequest(handlers.VerifyEmail, `{"token": "`+tokens[0]+`"}`).Code)
+
+	rec = authRequest(http.MethodGet, "/api/v1/me", token, nil, handlers.GetMe)
+	assert.True(t, decodeTestProfile(t, rec).EmailVerified)
+	assert.Equal(t, http.StatusOK, loginTestAttempt("new-me@example.com", "password123").Code)
+}
+
+func TestChangePassword(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	createTestUser(t, "change@example.com", "password123")
+	token := loginTestUser(t, "change@example.com", "password123")
+	other := loginTestUser(t, "change@example.com", "password123")
+
+	rec := authRequest(http.MethodPost, "/api/v1/me/password", token, strings.NewReader(`{"current_password": "wrong-password", "new_password": "new-password"}`), handlers.ChangePassword)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = authRequest(http.MethodPost, "/api/v1/me/password", token, strings.NewReader(`{"current_password": "password123", "new_password": "short"}`), handlers.ChangePassword)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	rec = authRequest(http.MethodPost, "/api/v1/me/password", token, strings.NewReader(`{"current_password": "password123", "new_password": "new-password"}`), handlers.ChangePassword)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Data models.TokenResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp.Data.RefreshToken)
+
+	// Every old session is revoked and the new tokens work
+	assert.Equal(t, http.StatusUnauthorized, authRequest(http.MethodGet, "/api/v1/me", token, nil, handlers.GetMe).Code)
+	assert.Equal(t, http.StatusUnauthorized, authRequest(http.MethodGet, "/api/v1/me", other, nil, handlers.GetMe).Code)
+	assert.Equal(t, http.StatusOK, authRequest(http.MethodGet, "/api/v1/me", resp.Data.Token, nil, handlers.GetMe).Code)
+
+	assert.Equal(t, http.StatusUnauthorized, loginTestAttempt("change@example.com", "password123").Code)
+	assert.Equal(t, http.StatusOK, loginTestAttempt("change@example.com", "new-password").Code)
+}
+
+func TestDeleteMe(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "delete-me@example.com", "password123")
+	otherID := createTestUser(t, "stays@example.com", "password123")
+	token := loginTestUser(t, "delete-me@example.com", "password123")
+
+	taskID := insertTestTask(t, userID, "My task", "2025-01-01 10:00:00", false)
+	createTestLabel(t, userID, "mine")
+	assert.Equal(t, http.StatusCreated, commentTestTask(userID, taskID, "My comment").Code)
+	otherTaskID := insertTestTask(t, otherID, "Other task", "2025-01-01 10:00:00", false)
+
+	// Changes the user makes to their own task and to a task shared with them
+	assert.Equal(t, http.StatusOK, updateTestTask(userID, taskID, "My renamed task").Code)
+	assert.Equal(t, http.StatusCreated, shareTestTask(otherID, otherTaskID, "delete-me@example.com", models.RoleEditor).Code)
+	assert.Equal(t, http.StatusOK, updateTestTask(userID, otherTaskID, "Renamed by the deleted user").Code)
+
+	// A workspace shared with others keeps the account from being deleted
+	workspaceID := createTestWorkspace(t, userID, "Team")
+	addTestMember(t, workspaceID, otherID, models.WorkspaceRoleMember)
+
+	rec := authRequest(http.MethodDelete, "/api/v1/me", token, strings.NewReader(`{"password": "wrong-password"}`), handlers.DeleteMe)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = authRequest(http.MethodDelete, "/api/v1/me", token, strings.NewReader(`{"password": "password123"}`), handlers.DeleteMe)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Team")
+
+	_, err := config.DB.Exec("DELETE FROM workspace_members WHERE workspace_id = ? AND user_id = ?", workspaceID, otherID)
+	assert.NoError(t, err)
+
+	rec = authRequest(http.MethodDelete, "/api/v1/me", token, strings.NewReader(`{"password": "password123"}`), handlers.DeleteMe)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// The response exports the deleted data
+	var resp struct {
+		Data models.AccountExport `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "delete-me@example.com", resp.Data.User.Email)
+	assert.Len(t, resp.Data.Workspaces, 2)
+	assert.Len(t, resp.Data.Tasks, 1)
+	assert.Equal(t, "My renamed task", resp.Data.Tasks[0].Title)
+	assert.Len(t, resp.Data.Labels, 1)
+	assert.Len(t, resp.Data.Comments, 1)
+
+	// Everything of the account is gone
+	assert.Equal(t, 0, countTestRows(t, "users", "id", userID))
+	assert.Equal(t, 0, countTestRows(t, "tasks", "user_id", userID))
+	assert.Equal(t, 0, countTestRows(t, "labels", "user_id", userID))
+	assert.Equal(t, 0, countTestRows(t, "task_comments", "user_id", userID))
+	assert.Equal(t, 0, countTestRows(t, "workspace_members", "user_id", userID))
+	assert.Equal(t, 0, countTestRows(t, "sessions", "user_id", userID))
+	assert.Equal(t, 0, countTestRows(t, "workspaces", "id", workspaceID))
+	assert.Equal(t, http.StatusUnauthorized, authRequest(http.MethodGet, "/api/v1/me", token, nil, handlers.GetMe).Code)
+
+	// Other users keep their data
+	assert.Equal(t, http.StatusOK, getTestTask(otherID, otherTaskID).Code)
+
+	// The history of the deleted task goes, while the change to the other
+	// user's task stays without saying who made it
+	assert.Equal(t, 0, countTestRows(t, "task_events", "task_id", taskID))
+	assert.Equal(t, 0, countTestRows(t, "task_events", "user_id", userID))
+	code, events := getTestHistory(t, otherID, otherTaskID)
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, models.UserSummary{ID: 0, Name: "Deleted user"}, events[0].Actor)
+		assert.Equal(t, models.FieldChange{Old: "Other task", New: "Renamed by the deleted user"}, events[0].Changes["title"])
+	}
+}
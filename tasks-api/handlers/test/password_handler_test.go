@@ -0,0 +1,221 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/mail"
+	"github.com/stretchr/testify/assert"
+)
+
+// useTestMailer writes the emails sent during the test to a file and returns
+// its path. Emails are sent before the request returns, so the test can read
+// them right away.
+func useTestMailer(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "mail.log")
+	useTestEmails(t, &mail.LogMailer{Path: path, From: "no-reply@example.com"}, func(send func()) { send() })
+	return path
+}
+
+// useTestEmails sends the emails of the rest of the test with mailer, through
+// sendInBackground
+func useTestEmails(t *testing.T, mailer mail.Mailer, sendInBackground func(send func())) {
+	previousMailer, previousSend := config.Mailer, config.SendInBackground
+	config.Mailer, config.SendInBackground = mailer, sendInBackground
+	t.Cleanup(func() {
+		config.Mailer, config.SendInBackground = previousMailer, previousSend
+	})
+}
+
+// blockingMailer holds every email until release is closed
+type blockingMailer struct {
+	release chan struct{}
+}
+
+func (m *blockingMailer) Send(msg mail.Message) error {
+	<-m.release
+	return nil
+}
+
+// blockTestEmails holds the emails of the rest of the test until the
+// returned function is called, sending them in the background like the
+// server does. The returned function waits until they have been sent.
+func blockTestEmails(t *testing.T) func() {
+	mailer := &blockingMailer{release: make(chan struct{})}
+	var sending sync.WaitGroup
+	useTestEmails(t, mailer, func(send func()) {
+		sending.Add(1)
+		go func() {
+			defer sending.Done()
+			send()
+		}()
+	})
+
+	return func() {
+		close(mailer.release)
+		sending.Wait()
+	}
+}
+
+// answeredWithoutEmail runs request and fails the test if it waits for an
+// email to be sent
+func answeredWithoutEmail(t *testing.T, request func() *httptest.ResponseRecorder) *httptest.ResponseRecorder {
+	t.Helper()
+
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		done <- request()
+	}()
+
+	select {
+	case rec := <-done:
+		return rec
+	case <-time.After(5 * time.Second):
+		t.Fatal("The request waited for an email to be sent")
+		return nil
+	}
+}
+
+// publicRequest sends a JSON body to a handler that needs no login
+func publicRequest(next http.HandlerFunc, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/password", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	next(rec, req)
+	return rec
+}
+
+// sentTokens returns the tokens in the links to page in the emails sent so far
+func sentTokens(t *testing.T, path, page string) []string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("Failed to read sent emails: %v", err)
+	}
+
+	var tokens []string
+	pattern := regexp.MustCompile(regexp.QuoteMeta(page) + `\?token=(\S+)`)
+	for _, match := range pattern.FindAllStringSubmatch(string(data), -1) {
+		token, err := url.QueryUnescape(match[1])
+		if err != nil {
+			t.Fatalf("Failed to decode reset token: %v", err)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+func TestPasswordReset(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	mailPath := useTestMailer(t)
+	createTestUser(t, "forgot@example.com", "password123")
+	token := loginTestUser(t, "forgot@example.com", "password123")
+
+	// Unknown emails get the same response without an email
+	unknown := publicRequest(handlers.ForgotPassword, `{"email": "nobody@example.com"}`)
+	assert.Equal(t, http.StatusOK, unknown.Code)
+	assert.Empty(t, sentTokens(t, mailPath, "reset-password"))
+
+	rec := publicRequest(handlers.ForgotPassword, `{"email": "forgot@example.com"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, unknown.Body.String(), rec.Body.String())
+
+	data, err := os.ReadFile(mailPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "To: forgot@example.com")
+	assert.Contains(t, string(data), "Subject: Reset your password")
+
+	// Only the hash of the token is stored
+	tokens := sentTokens(t, mailPath, "reset-password")
+	assert.Len(t, tokens, 1)
+	var stored int
+	assert.NoError(t, config.DB.QueryRow("SELECT COUNT(*) FROM password_resets WHERE token_hash = ?", tokens[0]).Scan(&stored))
+	assert.Equal(t, 0, stored)
+
+	// Asking again replaces the first token
+	assert.Equal(t, http.StatusOK, publicRequest(handlers.ForgotPassword, `{"email": "forgot@example.com"}`).Code)
+	tokens = sentTokens(t, mailPath, "reset-password")
+	assert.Len(t, tokens, 2)
+
+	rec = publicRequest(handlers.ResetPassword, `{"token": "`+tokens[0]+`", "password": "new-password"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Invalid or expired reset token")
+
+	// The new password is validated
+	rec = publicRequest(handlers.ResetPassword, `{"token": "`+tokens[1]+`", "password": "short"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	rec = publicRequest(handlers.ResetPassword, `{"token": "`+tokens[1]+`", "password": "new-password"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Existing sessions are revoked and only the new password works
+	rec = authRequest(http.MethodGet, "/api/v1/sessions", token, nil, handlers.GetSessions)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, http.StatusUnauthorized, loginTestAttempt("forgot@example.com", "password123").Code)
+	assert.Equal(t, http.StatusOK, loginTestAttempt("forgot@example.com", "new-password").Code)
+
+	// The token works only once
+	rec = publicRequest(handlers.ResetPassword, `{"token": "`+tokens[1]+`", "password": "other-password"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestForgotPasswordDoesNotWaitForEmail(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "slow-mail@example.com", "password123")
+	release := blockTestEmails(t)
+
+	// Existing accounts are answered before the email is sent, like unknown
+	// emails, so the response time does not tell them apart
+	rec := answeredWithoutEmail(t, func() *httptest.ResponseRecorder {
+		return publicRequest(handlers.ForgotPassword, `{"email": "slow-mail@example.com"}`)
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	release()
+	assert.Equal(t, 1, countTestRows(t, "password_resets", "user_id", userID))
+}
+
+func TestPasswordResetExpiry(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	mailPath := useTestMailer(t)
+	createTestUser(t, "expired-reset@example.com", "password123")
+
+	assert.Equal(t, http.StatusOK, publicRequest(handlers.ForgotPassword, `{"email": "expired-reset@example.com"}`).Code)
+	tokens := sentTokens(t, mailPath, "reset-password")
+	assert.Len(t, tokens, 1)
+
+	_, err := config.DB.Exec("UPDATE password_resets SET expires_at = datetime('now', '-1 minute')")
+	assert.NoError(t, err)
+
+	rec := publicRequest(handlers.ResetPassword, `{"token": "`+tokens[0]+`", "password": "new-password"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, http.StatusOK, loginTestAttempt("expired-reset@example.com", "password123").Code)
+
+	// Expired tokens are purged
+	removed, err := handlers.PurgeExpiredPasswordResets()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+}
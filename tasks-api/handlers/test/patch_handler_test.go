@@ -0,0 +1,216 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// patchTestTask patches a task as userID with the given Content-Type
+func patchTestTask(userID, taskID int, contentType, body string) *httptest.ResponseRecorder {
+	req := userRequest(userID, http.MethodPatch, "/api/v1/tasks/"+strconv.Itoa(taskID), strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+
+	rec := httptest.NewRecorder()
+	handlers.PatchTask(rec, req)
+	return rec
+}
+
+// decodeTestTask decodes the task in a single task response
+func decodeTestTask(t *testing.T, rec *httptest.ResponseRecorder) models.TaskResponse {
+	t.Helper()
+
+	var resp struct {
+		Data models.TaskResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.Data
+}
+
+func TestMergePatchTask(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "merge@example.com", "password123")
+	labelID := createTestLabel(t, userID, "work")
+
+	rec := httptest.NewRecorder()
+	body := `{"title": "draft", "description": "keep me", "priority": "high", "label_ids": [` + strconv.Itoa(labelID) + `]}`
+	handlers.CreateTask(rec, userRequest(userID, http.MethodPost, "/api/v1/tasks", strings.NewReader(body)))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	taskID := decodeTestTask(t, rec).ID
+
+	// Fields missing from the patch keep their values
+	rec = patchTestTask(userID, taskID, "application/merge-patch+json", `{"title": "final"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	task := decodeTestTask(t, rec)
+	assert.Equal(t, "final", task.Title)
+	assert.Equal(t, "keep me", task.Description)
+	assert.Equal(t, models.PriorityHigh, task.Priority)
+	assert.Len(t, task.Labels, 1)
+	assert.Equal(t, 2, task.Version)
+
+	// Only the fields present are validated
+	rec = patchTestTask(userID, taskID, "application/merge-patch+json", `{"priority": "someday"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"priority"`)
+	assert.NotContains(t, rec.Body.String(), `"title"`)
+
+	rec = patchTestTask(userID, taskID, "application/merge-patch+json", `{"title": null}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "title is required")
+
+	rec = patchTestTask(userID, taskID, "application/merge-patch+json", `{"title": 5}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	rec = patchTestTask(userID, taskID, "application/merge-patch+json", `{"owner": 2}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "unknown field")
+
+	// null removes a value; plain application/json is treated as a merge patch
+	rec = patchTestTask(userID, taskID, "application/json", `{"description": null, "label_ids": null}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	task = decodeTestTask(t, rec)
+	assert.Empty(t, task.Description)
+	assert.Empty(t, task.Labels)
+
+	// Completing and un-completing work through PATCH too
+	rec = patchTestTask(userID, taskID, "application/merge-patch+json", `{"completed": true, "priority": "low"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	task = decodeTestTask(t, rec)
+	assert.True(t, task.Completed)
+	assert.NotNil(t, task.CompletedAt)
+	assert.Equal(t, models.PriorityLow, task.Priority)
+
+	rec = patchTestTask(userID, taskID, "application/merge-patch+json", `{"completed": false}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	task = decodeTestTask(t, rec)
+	assert.False(t, task.Completed)
+	assert.Nil(t, task.CompletedAt)
+
+	_, events := getTestHistory(t, userID, taskID)
+	if assert.NotEmpty(t, events) {
+		assert.Equal(t, models.TaskEventUpdated, events[len(events)-1].Action)
+		assert.Equal(t, models.TaskEventCompleted, events[len(events)-2].Action)
+	}
+
+	// Other formats and empty patches are refused
+	assert.Equal(t, http.StatusUnsupportedMediaType, patchTestTask(userID, taskID, "text/plain", `title=x`).Code)
+	assert.Equal(t, http.StatusBadRequest, patchTestTask(userID, taskID, "application/merge-patch+json", `[]`).Code)
+	assert.Equal(t, http.StatusBadRequest, patchTestTask(userID, taskID, "application/merge-patch+json", `{`).Code)
+}
+
+func TestJSONPatchTask(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "jsonpatch@example.com", "password123")
+	workID := createTestLabel(t, userID, "work")
+	homeID := createTestLabel(t, userID, "home")
+	taskID := insertTestTask(t, userID, "draft", "2025-01-01 10:00:00", false)
+
+	body := `[
+		{"op": "test", "path": "/title", "value": "draft"},
+		{"op": "replace", "path": "/title", "value": "final"},
+		{"op": "add", "path": "/label_ids/-", "value": ` + strconv.Itoa(workID) + `},
+		{"op": "add", "path": "/label_ids/0", "value": ` + strconv.Itoa(homeID) + `},
+		{"op": "copy", "from": "/title", "path": "/description"}
+	]`
+	rec := patchTestTask(userID, taskID, "application/json-patch+json", body)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	task := decodeTestTask(t, rec)
+	assert.Equal(t, "final", task.Title)
+	assert.Equal(t, "final", task.Description)
+	assert.Len(t, task.Labels, 2)
+
+	// A failed test applies none of the operations
+	body = `[{"op": "replace", "path": "/title", "value": "other"}, {"op": "test", "path": "/title", "value": "draft"}]`
+	assert.Equal(t, http.StatusConflict, patchTestTask(userID, taskID, "application/json-patch+json", body).Code)
+	assert.Contains(t, getTestTask(userID, taskID).Body.String(), `"title":"final"`)
+
+	// Operations on missing values and invalid values are refused
+	body = `[{"op": "remove", "path": "/label_ids/5"}]`
+	assert.Equal(t, http.StatusUnprocessableEntity, patchTestTask(userID, taskID, "application/json-patch+json", body).Code)
+
+	body = `[{"op": "replace", "path": "/title", "value": ""}]`
+	assert.Equal(t, http.StatusUnprocessableEntity, patchTestTask(userID, taskID, "application/json-patch+json", body).Code)
+
+	body = `[{"op": "replace", "path": "/recurrence", "value": "FREQ=DAILY"}]`
+	rec = patchTestTask(userID, taskID, "application/json-patch+json", body)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "recurring tasks require due_at")
+
+	// Removing a member and un-completing
+	assert.Equal(t, http.StatusOK, completeTestTask(userID, taskID).Code)
+	body = `[{"op": "remove", "path": "/label_ids/1"}, {"op": "replace", "path": "/completed", "value": false}]`
+	rec = patchTestTask(userID, taskID, "application/json-patch+json", body)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	task = decodeTestTask(t, rec)
+	assert.False(t, task.Completed)
+	if assert.Len(t, task.Labels, 1) {
+		assert.Equal(t, homeID, task.Labels[0].ID)
+	}
+}
+
+func TestPatchTaskIfMatch(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "patch-etag@example.com", "password123")
+	taskID := insertTestTask(t, userID, "draft", "2025-01-01 10:00:00", false)
+	etag := getTestTask(userID, taskID).Header().Get("ETag")
+
+	req := userRequest(userID, http.MethodPatch, "/api/v1/tasks/"+strconv.Itoa(taskID), strings.NewReader(`{"completed": true, "title": "done"}`))
+	req.Header.Set("If-Match", etag)
+	rec := httptest.NewRecorder()
+	handlers.PatchTask(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+
+	req = userRequest(userID, http.MethodPatch, "/api/v1/tasks/"+strconv.Itoa(taskID), strings.NewReader(`{"title": "again"}`))
+	req.Header.Set("If-Match", etag)
+	rec = httptest.NewRecorder()
+	handlers.PatchTask(rec, req)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+}
+
+func TestCompleteAndReopenTask(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "reopen@example.com", "password123")
+	taskID := insertTestTask(t, userID, "draft", "2025-01-01 10:00:00", false)
+	target := "/api/v1/tasks/" + strconv.Itoa(taskID)
+
+	rec := httptest.NewRecorder()
+	handlers.CompleteTask(rec, userRequest(userID, http.MethodPost, target+"/complete", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, getTestTask(userID, taskID).Body.String(), `"completed":true`)
+
+	rec = httptest.NewRecorder()
+	handlers.ReopenTask(rec, userRequest(userID, http.MethodPost, target+"/reopen", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	task := decodeTestTask(t, getTestTask(userID, taskID))
+	assert.False(t, task.Completed)
+	assert.Nil(t, task.CompletedAt)
+
+	// Reopening an open task changes nothing
+	rec = httptest.NewRecorder()
+	handlers.ReopenTask(rec, userRequest(userID, http.MethodPost, target+"/reopen", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, task.Version, decodeTestTask(t, getTestTask(userID, taskID)).Version)
+}
@@ -0,0 +1,207 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// createTestProject creates a project through the handler and returns its ID
+func createTestProject(t *testing.T, userID int, body string) int {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	handlers.CreateProject(rec, userRequest(userID, http.MethodPost, "/api/v1/projects", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Failed to create project: %s", rec.Body.String())
+	}
+
+	var resp struct {
+		Data models.ProjectResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.Data.ID
+}
+
+// setTestTaskProject moves a task into a project
+func setTestTaskProject(t *testing.T, taskID, projectID int) {
+	t.Helper()
+
+	if _, err := config.DB.Exec("UPDATE tasks SET project_id = ? WHERE id = ?", projectID, taskID); err != nil {
+		t.Fatalf("Failed to set task project: %v", err)
+	}
+}
+
+func listTestProjects(t *testing.T, userID int, query string) []models.ProjectResponse {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	handlers.GetProjects(rec, userRequest(userID, http.MethodGet, "/api/v1/projects"+query, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Data []models.ProjectResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.Data
+}
+
+func TestProjectCRUD(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "projects@example.com", "password123")
+	otherID := createTestUser(t, "other-projects@example.com", "password123")
+
+	homeID := createTestProject(t, userID, `{"name": "Home"}`)
+	workID := createTestProject(t, userID, `{"name": "Work", "color": "#00FF00"}`)
+	createTestProject(t, otherID, `{"name": "Other"}`)
+
+	// New projects go to the end of the list
+	projects := listTestProjects(t, userID, "")
+	if assert.Len(t, projects, 2) {
+		assert.Equal(t, "Home", projects[0].Name)
+		assert.Equal(t, models.DefaultProjectColor, projects[0].Color)
+		assert.Equal(t, 0, projects[0].Position)
+		assert.Equal(t, "#00ff00", projects[1].Color)
+		assert.Equal(t, 1, projects[1].Position)
+	}
+
+	// Reorder and archive
+	rec := httptest.NewRecorder()
+	body := `{"name": "Home", "position": 5, "archived": true}`
+	handlers.UpdateProject(rec, userRequest(userID, http.MethodPut, "/api/v1/projects/"+strconv.Itoa(homeID), strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	projects = listTestProjects(t, userID, "")
+	if assert.Len(t, projects, 1) {
+		assert.Equal(t, workID, projects[0].ID)
+	}
+
+	projects = listTestProjects(t, userID, "?include_archived=true")
+	if assert.Len(t, projects, 2) {
+		assert.Equal(t, "Home", projects[1].Name)
+		assert.True(t, projects[1].Archived)
+	}
+
+	// Other users cannot see the project
+	rec = httptest.NewRecorder()
+	handlers.GetOneProject(rec, userRequest(otherID, http.MethodGet, "/api/v1/projects/"+strconv.Itoa(workID), nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestProjectTasks(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "project-tasks@example.com", "password123")
+	otherID := createTestUser(t, "other-project-tasks@example.com", "password123")
+
+	workID := createTestProject(t, userID, `{"name": "Work"}`)
+	oldID := createTestProject(t, userID, `{"name": "Old", "archived": true}`)
+	foreignID := createTestProject(t, otherID, `{"name": "Foreign"}`)
+
+	// Tasks can be created in a project
+	rec := httptest.NewRecorder()
+	body := `{"title": "report", "project_id": ` + strconv.Itoa(workID) + `}`
+	handlers.CreateTask(rec, userRequest(userID, http.MethodPost, "/api/v1/tasks", strings.NewReader(body)))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	var resp struct {
+		Data models.TaskResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	if assert.NotNil(t, resp.Data.ProjectID) {
+		assert.Equal(t, workID, *resp.Data.ProjectID)
+	}
+
+	// But not in another user's project
+	rec = httptest.NewRecorder()
+	body = `{"title": "sneaky", "project_id": ` + strconv.Itoa(foreignID) + `}`
+	handlers.CreateTask(rec, userRequest(userID, http.MethodPost, "/api/v1/tasks", strings.NewReader(body)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	insertTestTask(t, userID, "inbox", "2025-01-01 10:00:00", false)
+	archived := insertTestTask(t, userID, "archived", "2025-01-02 10:00:00", false)
+	setTestTaskProject(t, archived, oldID)
+
+	// Tasks of archived projects are hidden from the default listing
+	_, list := listTestTasks(t, userID, url.Values{"sort": {"title"}})
+	assert.Equal(t, []string{"inbox", "report"}, titles(list.Data))
+
+	_, list = listTestTasks(t, userID, url.Values{"sort": {"title"}, "include_archived": {"true"}})
+	assert.Equal(t, []string{"archived", "inbox", "report"}, titles(list.Data))
+
+	_, list = listTestTasks(t, userID, url.Values{"project_id": {"inbox"}})
+	assert.Equal(t, []string{"inbox"}, titles(list.Data))
+
+	// The project task listing shows the tasks of archived projects too
+	rec = httptest.NewRecorder()
+	handlers.GetProjectTasks(rec, userRequest(userID, http.MethodGet, "/api/v1/projects/"+strconv.Itoa(oldID)+"/tasks", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var projectList struct {
+		Data []models.Task `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &projectList))
+	assert.Equal(t, []string{"archived"}, titles(projectList.Data))
+
+	rec = httptest.NewRecorder()
+	handlers.GetProjectTasks(rec, userRequest(otherID, http.MethodGet, "/api/v1/projects/"+strconv.Itoa(workID)+"/tasks", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestDeleteProject(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "delete-projects@example.com", "password123")
+
+	moveID := createTestProject(t, userID, `{"name": "Move"}`)
+	cascadeID := createTestProject(t, userID, `{"name": "Cascade"}`)
+
+	moved := insertTestTask(t, userID, "moved", "2025-01-01 10:00:00", false)
+	setTestTaskProject(t, moved, moveID)
+	deleted := insertTestTask(t, userID, "deleted", "2025-01-02 10:00:00", false)
+	setTestTaskProject(t, deleted, cascadeID)
+
+	// By default tasks move to the inbox
+	rec := httptest.NewRecorder()
+	handlers.DeleteProject(rec, userRequest(userID, http.MethodDelete, "/api/v1/projects/"+strconv.Itoa(moveID), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"tasks_moved":1`)
+
+	var projectID *int
+	assert.NoError(t, config.DB.QueryRow("SELECT project_id FROM tasks WHERE id = ?", moved).Scan(&projectID))
+	assert.Nil(t, projectID)
+
+	// Unknown modes are rejected
+	rec = httptest.NewRecorder()
+	handlers.DeleteProject(rec, userRequest(userID, http.MethodDelete, "/api/v1/projects/"+strconv.Itoa(cascadeID)+"?tasks=archive", nil))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	// Cascading deletes the tasks too
+	rec = httptest.NewRecorder()
+	handlers.DeleteProject(rec, userRequest(userID, http.MethodDelete, "/api/v1/projects/"+strconv.Itoa(cascadeID)+"?tasks=delete", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"tasks_deleted":1`)
+
+	_, list := listTestTasks(t, userID, url.Values{})
+	assert.Equal(t, []string{"moved"}, titles(list.Data))
+}
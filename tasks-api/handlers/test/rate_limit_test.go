@@ -0,0 +1,222 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// loginTestAttempt tries to log in with the given credentials
+func loginTestAttempt(email, password string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(models.LoginRequest{Email: email, Password: password})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/login", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+	handlers.Login(rec, req)
+	return rec
+}
+
+// setTestRateLimit sets the rate limit of a route for the rest of the test
+func setTestRateLimit(t *testing.T, pattern string, limit config.RateLimit) {
+	previous, ok := config.RouteRateLimits[pattern]
+	config.RouteRateLimits[pattern] = limit
+	t.Cleanup(func() {
+		if ok {
+			config.RouteRateLimits[pattern] = previous
+		} else {
+			delete(config.RouteRateLimits, pattern)
+		}
+	})
+}
+
+func TestRateLimitByIP(t *testing.T) {
+	setTestRateLimit(t, "/limited/ip", config.RateLimit{Requests: 2, Window: time.Minute})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/limited/ip", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := middleware.RateLimit(mux)
+
+	request := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/limited/ip", nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request("10.0.0.1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2;w=60", rec.Header().Get("RateLimit-Policy"))
+
+	rec = request("10.0.0.1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+
+	// The bucket is empty until a request has been refilled
+	rec = request("10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	assert.NoError(t, err)
+	assert.True(t, retryAfter > 0 && retryAfter <= 30)
+	assert.Contains(t, rec.Body.String(), "Too many requests")
+
+	// Other clients have buckets of their own
+	assert.Equal(t, http.StatusOK, request("10.0.0.2").Code)
+}
+
+func TestRateLimitInWorkspace(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	setTestRateLimit(t, "/api/v1/login", config.RateLimit{Requests: 2, Window: time.Minute})
+	createTestUser(t, "prefixed@example.com", "password123")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/login", handlers.Login)
+	mux.HandleFunc("/api/v1/workspaces/{workspace_id}/", middleware.WorkspacePrefix(mux))
+	handler := middleware.RateLimit(mux)
+
+	login := func(path string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.LoginRequest{Email: "prefixed@example.com", Password: "wrong"})
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "10.0.2.1:1234"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Logins through a workspace prefix share the limit of /api/v1/login
+	assert.Equal(t, http.StatusUnauthorized, login("/api/v1/workspaces/1/login").Code)
+	assert.Equal(t, http.StatusUnauthorized, login("/api/v1/login").Code)
+	assert.Equal(t, http.StatusTooManyRequests, login("/api/v1/workspaces/1/login").Code)
+	assert.Equal(t, http.StatusTooManyRequests, login("/api/v1/workspaces/2/login").Code)
+	assert.Equal(t, http.StatusTooManyRequests, login("/api/v1/login").Code)
+}
+
+func TestRateLimitByUser(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	setTestRateLimit(t, "/limited/user", config.RateLimit{Requests: 1, Window: time.Hour})
+
+	createTestUser(t, "limited@example.com", "password123")
+	token := loginTestUser(t, "limited@example.com", "password123")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/limited/user", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/limited/user", nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The user's bucket is shared between addresses
+	assert.Equal(t, http.StatusOK, request("10.0.1.1").Code)
+	rec := request("10.0.1.2")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+}
+
+func TestLoginLockout(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	threshold := config.LoginLockoutThreshold
+	config.LoginLockoutThreshold = 3
+	defer func() { config.LoginLockoutThreshold = threshold }()
+
+	createTestUser(t, "locked@example.com", "password123")
+
+	// Existing and unknown accounts get the same responses
+	for _, email := range []string{"locked@example.com", "nobody@example.com"} {
+		for i := 0; i < 3; i++ {
+			rec := loginTestAttempt(email, "wrong-password")
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			assert.Contains(t, rec.Body.String(), "Invalid credentials")
+		}
+
+		rec := loginTestAttempt(email, "wrong-password")
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+	}
+
+	// The right password does not help while the account is locked
+	assert.Equal(t, http.StatusTooManyRequests, loginTestAttempt("LOCKED@example.com", "password123").Code)
+
+	// Every failure after the lockout doubles it
+	_, err := config.DB.Exec("UPDATE login_attempts SET locked_until = datetime('now', '-1 second')")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, loginTestAttempt("locked@example.com", "wrong-password").Code)
+	assert.Equal(t, "120", loginTestAttempt("locked@example.com", "wrong-password").Header().Get("Retry-After"))
+
+	// A successful login clears the failures
+	_, err = config.DB.Exec("UPDATE login_attempts SET locked_until = datetime('now', '-1 second')")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, loginTestAttempt("locked@example.com", "password123").Code)
+	assert.Equal(t, http.StatusUnauthorized, loginTestAttempt("locked@example.com", "wrong-password").Code)
+	assert.Equal(t, http.StatusUnauthorized, loginTestAttempt("locked@example.com", "wrong-password").Code)
+
+	// Failures that no longer count are purged
+	_, err = config.DB.Exec("UPDATE login_attempts SET last_failed_at = datetime('now', '-2 days'), locked_until = NULL")
+	assert.NoError(t, err)
+	removed, err := handlers.PurgeStaleLoginAttempts()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), removed)
+}
+
+func TestLoginLockoutParallel(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	threshold := config.LoginLockoutThreshold
+	config.LoginLockoutThreshold = 21
+	defer func() { config.LoginLockoutThreshold = threshold }()
+
+	createTestUser(t, "parallel@example.com", "password123")
+
+	// Failures that arrive together are all counted
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			loginTestAttempt("parallel@example.com", "wrong-password")
+		}()
+	}
+	wg.Wait()
+
+	var failures int
+	assert.NoError(t, config.DB.QueryRow("SELECT failures FROM login_attempts WHERE email = ?", "parallel@example.com").Scan(&failures))
+	assert.Equal(t, 20, failures)
+
+	// So the next one reaches the threshold and locks the account
+	assert.Equal(t, http.StatusUnauthorized, loginTestAttempt("parallel@example.com", "wrong-password").Code)
+	assert.Equal(t, http.StatusTooManyRequests, loginTestAttempt("parallel@example.com", "password123").Code)
+}
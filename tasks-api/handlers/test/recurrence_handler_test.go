@@ -0,0 +1,153 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// createRecurringTask creates a task with a due date and recurrence rule
+func createRecurringTask(t *testing.T, userID int, dueAt, rule string) models.TaskResponse {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	body := `{"title": "chore", "due_at": "` + dueAt + `", "recurrence": "` + rule + `"}`
+	handlers.CreateTask(rec, userRequest(userID, http.MethodPost, "/api/v1/tasks", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Failed to create recurring task: %s", rec.Body.String())
+	}
+
+	var resp struct {
+		Data models.TaskResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.Data
+}
+
+// previewOccurrences returns the next count occurrences of a task in UTC
+func previewOccurrences(t *testing.T, userID, taskID, count int) []string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	target := "/api/v1/tasks/" + strconv.Itoa(taskID) + "/occurrences?count=" + strconv.Itoa(count)
+	handlers.GetTaskOccurrences(rec, userRequest(userID, http.MethodGet, target, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Failed to preview occurrences: %s", rec.Body.String())
+	}
+
+	var resp struct {
+		Data models.TaskOccurrencesResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	occurrences := []string{}
+	for _, occurrence := range resp.Data.Occurrences {
+		occurrences = append(occurrences, occurrence.UTC().Format(time.RFC3339))
+	}
+	return occurrences
+}
+
+func TestRecurringTaskAcrossDST(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "recurring@example.com", "password123")
+	_, err := config.DB.Exec("UPDATE users SET timezone = 'America/New_York' WHERE id = ?", userID)
+	assert.NoError(t, err)
+
+	labelID := createTestLabel(t, userID, "chores")
+
+	// Thursday 9:00 EST; clocks move forward on Sunday 9 March 2025
+	task := createRecurringTask(t, userID, "2025-03-06T09:00:00-05:00", "rrule:freq=weekly;byday=th,tu;count=3")
+	if assert.NotNil(t, task.Recurrence) {
+		assert.Equal(t, "FREQ=WEEKLY;BYDAY=TU,TH;COUNT=3", *task.Recurrence)
+	}
+	_, err = config.DB.Exec("INSERT INTO task_labels (task_id, label_id) VALUES (?, ?)", task.ID, labelID)
+	assert.NoError(t, err)
+
+	// Occurrences stay at 9:00 local time, 13:00 UTC after the change, and stop after COUNT
+	assert.Equal(t, []string{"2025-03-11T13:00:00Z", "2025-03-13T13:00:00Z"}, previewOccurrences(t, userID, task.ID, 5))
+
+	// Completing the task creates the next occurrence with the same labels
+	rec := completeTestTask(userID, task.ID)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Data struct {
+			NextTask models.TaskResponse `json:"next_task"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	next := resp.Data.NextTask
+	if assert.NotNil(t, next.DueAt) {
+		assert.Equal(t, "2025-03-11T13:00:00Z", next.DueAt.UTC().Format(time.RFC3339))
+	}
+	assert.Equal(t, []string{"chores"}, labelNames(next.Labels))
+	assert.False(t, next.Completed)
+
+	// Completing it again does not create another occurrence
+	assert.Equal(t, http.StatusOK, completeTestTask(userID, task.ID).Code)
+
+	// The last occurrence of the series does not create a new one
+	rec = completeTestTask(userID, next.ID)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	last := resp.Data.NextTask
+
+	rec = completeTestTask(userID, last.ID)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "next_task")
+
+	var count int
+	assert.NoError(t, config.DB.QueryRow("SELECT COUNT(*) FROM tasks WHERE user_id = ?", userID).Scan(&count))
+	assert.Equal(t, 3, count)
+}
+
+func TestRecurringTaskRules(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "rules@example.com", "password123")
+
+	// Months without the day are skipped
+	task := createRecurringTask(t, userID, "2025-01-31T08:00:00Z", "FREQ=MONTHLY;BYMONTHDAY=31")
+	assert.Equal(t, []string{"2025-03-31T08:00:00Z", "2025-05-31T08:00:00Z"}, previewOccurrences(t, userID, task.ID, 2))
+
+	// Negative days count from the end of the month
+	task = createRecurringTask(t, userID, "2025-01-31T08:00:00Z", "FREQ=MONTHLY;BYMONTHDAY=-1")
+	assert.Equal(t, []string{"2025-02-28T08:00:00Z", "2025-03-31T08:00:00Z"}, previewOccurrences(t, userID, task.ID, 2))
+
+	// Intervals and end dates
+	task = createRecurringTask(t, userID, "2025-06-01T08:00:00Z", "FREQ=DAILY;INTERVAL=3;UNTIL=20250607")
+	assert.Equal(t, []string{"2025-06-04T08:00:00Z", "2025-06-07T08:00:00Z"}, previewOccurrences(t, userID, task.ID, 5))
+
+	task = createRecurringTask(t, userID, "2025-06-04T08:00:00Z", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE")
+	assert.Equal(t, []string{"2025-06-16T08:00:00Z", "2025-06-18T08:00:00Z", "2025-06-30T08:00:00Z"}, previewOccurrences(t, userID, task.ID, 3))
+
+	// Invalid rules are rejected
+	for _, body := range []string{
+		`{"title": "x", "recurrence": "FREQ=DAILY"}`,
+		`{"title": "x", "due_at": "2025-06-01T08:00:00Z", "recurrence": "FREQ=YEARLY"}`,
+		`{"title": "x", "due_at": "2025-06-01T08:00:00Z", "recurrence": "FREQ=DAILY;BYDAY=MO"}`,
+		`{"title": "x", "due_at": "2025-06-01T08:00:00Z", "recurrence": "FREQ=DAILY;COUNT=2;UNTIL=20250701"}`,
+	} {
+		rec := httptest.NewRecorder()
+		handlers.CreateTask(rec, userRequest(userID, http.MethodPost, "/api/v1/tasks", strings.NewReader(body)))
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, body)
+		assert.Contains(t, rec.Body.String(), "recurrence", body)
+	}
+}
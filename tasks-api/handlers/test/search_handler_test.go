@@ -0,0 +1,97 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type searchResponse struct {
+	Message string                    `json:"message"`
+	Data    []models.TaskSearchResult `json:"data"`
+	Errors  map[string]string         `json:"errors"`
+}
+
+// searchTestTasks calls SearchTasks with the given query
+func searchTestTasks(t *testing.T, userID int, q string) (int, searchResponse) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	target := "/api/v1/tasks/search?" + url.Values{"q": {q}}.Encode()
+	handlers.SearchTasks(rec, userRequest(userID, http.MethodGet, target, nil))
+
+	var resp searchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return rec.Code, resp
+}
+
+func TestSearchTasks(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "search@example.com", "password123")
+	otherID := createTestUser(t, "other-search@example.com", "password123")
+
+	_, err := config.DB.Exec(
+		"INSERT INTO tasks (user_id, workspace_id, title, description) SELECT personal_user_id, id, ?, ? FROM workspaces WHERE personal_user_id = ?"+
+			" UNION ALL SELECT personal_user_id, id, ?, ? FROM workspaces WHERE personal_user_id = ?"+
+			" UNION ALL SELECT personal_user_id, id, ?, ? FROM workspaces WHERE personal_user_id = ?"+
+			" UNION ALL SELECT personal_user_id, id, ?, ? FROM workspaces WHERE personal_user_id = ?",
+		"Write invoice", "Send the <monthly> invoice to the client", userID,
+		"Groceries", "Buy milk and remember the invoice folder", userID,
+		"Walk the dog", "Around the park", userID,
+		"Invoice for someone else", "Should never be returned", otherID,
+	)
+	assert.NoError(t, err)
+
+	// Prefix matching, ranking title matches first and scoping to the user
+	code, resp := searchTestTasks(t, userID, "invo")
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, resp.Data, 2) {
+		assert.Equal(t, "Write invoice", resp.Data[0].Title)
+		assert.Equal(t, "Groceries", resp.Data[1].Title)
+		assert.Greater(t, resp.Data[0].Rank, resp.Data[1].Rank)
+		assert.Equal(t, "Write <mark>invoice</mark>", resp.Data[0].TitleHighlight)
+		assert.Contains(t, resp.Data[0].DescriptionSnippet, "&lt;monthly&gt; <mark>invoice</mark>")
+	}
+
+	// Every term must match
+	code, resp = searchTestTasks(t, userID, "invoice milk")
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, resp.Data, 1) {
+		assert.Equal(t, "Groceries", resp.Data[0].Title)
+	}
+
+	// Updates and deletes keep the index in sync
+	_, err = config.DB.Exec("UPDATE tasks SET title = 'Walk the cat' WHERE title = 'Walk the dog'")
+	assert.NoError(t, err)
+	_, resp = searchTestTasks(t, userID, "dog")
+	assert.Empty(t, resp.Data)
+	_, resp = searchTestTasks(t, userID, "cat")
+	assert.Len(t, resp.Data, 1)
+
+	_, err = config.DB.Exec("DELETE FROM tasks WHERE title = 'Groceries'")
+	assert.NoError(t, err)
+	_, resp = searchTestTasks(t, userID, "milk")
+	assert.Empty(t, resp.Data)
+
+	// FTS syntax in the input is ignored
+	code, resp = searchTestTasks(t, userID, `"invoice" -( ^*`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, resp.Data, 1)
+
+	// A query without terms is rejected
+	code, resp = searchTestTasks(t, userID, "  *** ")
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Contains(t, resp.Errors, "q")
+}
@@ -0,0 +1,203 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// createTestUser inserts a verified user with the given credentials and returns its ID
+func createTestUser(t *testing.T, email, password string) int {
+	t.Helper()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+
+	result, err := config.DB.Exec("INSERT INTO users (email, name, password, email_verified_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)", email, "Test User", hashedPassword)
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		t.Fatalf("Failed to get test user ID: %v", err)
+	}
+	return int(id)
+}
+
+// loginTestUser logs in through the Login handler and returns the access token
+func loginTestUser(t *testing.T, email, password string) string {
+	t.Helper()
+	return loginTestUserTokens(t, email, password).Token
+}
+
+// loginTestUserTokens logs in through the Login handler and returns the login response
+func loginTestUserTokens(t *testing.T, email, password string) models.LoginResponse {
+	t.Helper()
+
+	body, _ := json.Marshal(models.LoginRequest{Email: email, Password: password})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/login", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "test-agent")
+	rec := httptest.NewRecorder()
+	handlers.Login(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Login failed with status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Data models.LoginResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode login response: %v", err)
+	}
+	return resp.Data
+}
+
+// authRequest sends a request with the given token through AuthMiddleware
+func authRequest(method, path, token string, body io.Reader, next http.HandlerFunc) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	middleware.AuthMiddleware(next)(rec, req)
+	return rec
+}
+
+func TestLogout(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	createTestUser(t, "logout@example.com", "password123")
+	token := loginTestUser(t, "logout@example.com", "password123")
+
+	rec := authRequest(http.MethodPost, "/api/v1/logout", token, nil, handlers.Logout)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// The token must no longer authenticate
+	rec = authRequest(http.MethodGet, "/api/v1/sessions", token, nil, handlers.GetSessions)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestGetSessions(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	createTestUser(t, "sessions@example.com", "password123")
+	first := loginTestUser(t, "sessions@example.com", "password123")
+	loginTestUser(t, "sessions@example.com", "password123")
+
+	rec := authRequest(http.MethodGet, "/api/v1/sessions", first, nil, handlers.GetSessions)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Data []models.SessionResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Len(t, resp.Data, 2)
+
+	current := 0
+	for _, session := range resp.Data {
+		assert.Equal(t, "test-agent", session.UserAgent)
+		assert.NotEmpty(t, session.IPAddress)
+		if session.Current {
+			current++
+			assert.NotNil(t, session.LastUsedAt)
+		}
+	}
+	assert.Equal(t, 1, current)
+}
+
+func TestDeleteSession(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	createTestUser(t, "revoke@example.com", "password123")
+	first := loginTestUser(t, "revoke@example.com", "password123")
+	second := loginTestUser(t, "revoke@example.com", "password123")
+
+	// Revoke the second session from the first one
+	path := "/api/v1/sessions/" + strconv.Itoa(sessionIDForToken(t, second))
+	rec := authRequest(http.MethodDelete, path, first, nil, handlers.DeleteSession)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = authRequest(http.MethodGet, "/api/v1/sessions", second, nil, handlers.GetSessions)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Sessions of other users cannot be revoked
+	createTestUser(t, "other@example.com", "password123")
+	other := loginTestUser(t, "other@example.com", "password123")
+	rec = authRequest(http.MethodDelete, "/api/v1/sessions/"+strconv.Itoa(sessionIDForToken(t, first)), other, nil, handlers.DeleteSession)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestDeleteAllSessions(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	createTestUser(t, "everywhere@example.com", "password123")
+	first := loginTestUser(t, "everywhere@example.com", "password123")
+	second := loginTestUser(t, "everywhere@example.com", "password123")
+
+	rec := authRequest(http.MethodDelete, "/api/v1/sessions", first, nil, handlers.DeleteAllSessions)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	for _, token := range []string{first, second} {
+		rec = authRequest(http.MethodGet, "/api/v1/sessions", token, nil, handlers.GetSessions)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+}
+
+func TestCleanupExpiredSessions(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "cleanup@example.com", "password123")
+	loginTestUser(t, "cleanup@example.com", "password123")
+
+	past := time.Now().UTC().Add(-time.Hour)
+	_, err := config.DB.Exec(
+		"INSERT INTO sessions (user_id, token, created_at, expires_at) VALUES (?, ?, ?, ?)",
+		userID, "expired-token", past.Add(-time.Hour), past,
+	)
+	assert.NoError(t, err)
+
+	removed, err := handlers.CleanupExpiredSessions()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	var count int
+	assert.NoError(t, config.DB.QueryRow("SELECT COUNT(*) FROM sessions WHERE user_id = ?", userID).Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+// sessionIDForToken looks up the ID of the session that owns token
+func sessionIDForToken(t *testing.T, token string) int {
+	t.Helper()
+
+	var id int
+	if err := config.DB.QueryRow("SELECT id FROM sessions WHERE token = ?", token).Scan(&id); err != nil {
+		t.Fatalf("Failed to find session: %v", err)
+	}
+	return id
+}
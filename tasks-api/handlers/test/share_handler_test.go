@@ -0,0 +1,192 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// shareTestTask shares a task with the user registered under email
+func shareTestTask(userID, taskID int, email, role string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	body := `{"email": "` + email + `", "role": "` + role + `"}`
+	handlers.ShareTask(rec, userRequest(userID, http.MethodPost, "/api/v1/tasks/"+strconv.Itoa(taskID)+"/shares", strings.NewReader(body)))
+	return rec
+}
+
+// getTestTask fetches a single task as userID
+func getTestTask(userID, taskID int) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handlers.GetOneTask(rec, userRequest(userID, http.MethodGet, "/api/v1/tasks/"+strconv.Itoa(taskID), nil))
+	return rec
+}
+
+// updateTestTask updates a task's title as userID
+func updateTestTask(userID, taskID int, title string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	body := `{"title": "` + title + `"}`
+	handlers.UpdateTask(rec, userRequest(userID, http.MethodPut, "/api/v1/tasks/"+strconv.Itoa(taskID), strings.NewReader(body)))
+	return rec
+}
+
+func TestShareTask(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	ownerID := createTestUser(t, "owner@example.com", "password123")
+	viewerID := createTestUser(t, "viewer@example.com", "password123")
+	editorID := createTestUser(t, "editor@example.com", "password123")
+	strangerID := createTestUser(t, "stranger@example.com", "password123")
+
+	taskID := insertTestTask(t, ownerID, "shared", "2025-01-01 10:00:00", false)
+	insertTestTask(t, ownerID, "private", "2025-01-02 10:00:00", false)
+
+	assert.Equal(t, http.StatusCreated, shareTestTask(ownerID, taskID, "Viewer@Example.com", models.RoleViewer).Code)
+	assert.Equal(t, http.StatusCreated, shareTestTask(ownerID, taskID, "editor@example.com", models.RoleEditor).Code)
+
+	// Unknown users, the owner and invalid roles are rejected
+	unknown := shareTestTask(ownerID, taskID, "nobody@example.com", models.RoleViewer)
+	self := shareTestTask(ownerID, taskID, "owner@example.com", models.RoleViewer)
+	assert.Equal(t, http.StatusUnprocessableEntity, unknown.Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, self.Code)
+
+	// Both look the same, so sharing does not tell whether an email has an account
+	assert.Equal(t, unknown.Body.String(), self.Body.String())
+	assert.NotContains(t, unknown.Body.String(), "not found")
+	assert.Equal(t, http.StatusUnprocessableEntity, shareTestTask(ownerID, taskID, "stranger@example.com", models.RoleOwner).Code)
+
+	// Only the owner can share
+	assert.Equal(t, http.StatusForbidden, shareTestTask(editorID, taskID, "stranger@example.com", models.RoleViewer).Code)
+	assert.Equal(t, http.StatusNotFound, shareTestTask(strangerID, taskID, "stranger@example.com", models.RoleViewer).Code)
+
+	// Viewers can read but not change the task
+	assert.Equal(t, http.StatusOK, getTestTask(viewerID, taskID).Code)
+	assert.Equal(t, http.StatusForbidden, updateTestTask(viewerID, taskID, "renamed").Code)
+	assert.Equal(t, http.StatusForbidden, completeTestTask(viewerID, taskID).Code)
+
+	// Editors can change the task but not delete it
+	assert.Equal(t, http.StatusOK, updateTestTask(editorID, taskID, "renamed").Code)
+	assert.Equal(t, http.StatusOK, completeTestTask(editorID, taskID).Code)
+
+	rec := httptest.NewRecorder()
+	handlers.DeleteTask(rec, userRequest(editorID, http.MethodDelete, "/api/v1/tasks/"+strconv.Itoa(taskID), nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Users without a share cannot see the task at all
+	assert.Equal(t, http.StatusNotFound, getTestTask(strangerID, taskID).Code)
+	assert.Equal(t, http.StatusNotFound, updateTestTask(strangerID, taskID, "hijacked").Code)
+
+	// Shared tasks are listed separately from the user's own tasks
+	rec = httptest.NewRecorder()
+	handlers.GetSharedTasks(rec, userRequest(viewerID, http.MethodGet, "/api/v1/shared/tasks", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var list taskListResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	assert.Equal(t, []string{"renamed"}, titles(list.Data))
+
+	_, own := listTestTasks(t, viewerID, nil)
+	assert.Empty(t, own.Data)
+
+	// The owner sees who has access
+	rec = httptest.NewRecorder()
+	handlers.GetTaskShares(rec, userRequest(ownerID, http.MethodGet, "/api/v1/tasks/"+strconv.Itoa(taskID)+"/shares", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var shares struct {
+		Data []models.ShareResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &shares))
+	if assert.Len(t, shares.Data, 2) {
+		assert.Equal(t, "viewer@example.com", shares.Data[0].Email)
+		assert.Equal(t, models.RoleViewer, shares.Data[0].Role)
+	}
+
+	// Sharing again changes the role
+	assert.Equal(t, http.StatusCreated, shareTestTask(ownerID, taskID, "viewer@example.com", models.RoleEditor).Code)
+	assert.Equal(t, http.StatusOK, updateTestTask(viewerID, taskID, "again").Code)
+
+	// Revoking removes access, and shared users can remove themselves
+	rec = httptest.NewRecorder()
+	handlers.RevokeTaskShare(rec, userRequest(editorID, http.MethodDelete, "/api/v1/tasks/"+strconv.Itoa(taskID)+"/shares/"+strconv.Itoa(viewerID), nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handlers.RevokeTaskShare(rec, userRequest(ownerID, http.MethodDelete, "/api/v1/tasks/"+strconv.Itoa(taskID)+"/shares/"+strconv.Itoa(viewerID), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, http.StatusNotFound, getTestTask(viewerID, taskID).Code)
+
+	rec = httptest.NewRecorder()
+	handlers.RevokeTaskShare(rec, userRequest(editorID, http.MethodDelete, "/api/v1/tasks/"+strconv.Itoa(taskID)+"/shares/"+strconv.Itoa(editorID), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, http.StatusNotFound, getTestTask(editorID, taskID).Code)
+}
+
+func TestShareProject(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	ownerID := createTestUser(t, "project-owner@example.com", "password123")
+	memberID := createTestUser(t, "member@example.com", "password123")
+
+	projectID := createTestProject(t, ownerID, `{"name": "Team"}`)
+	taskID := insertTestTask(t, ownerID, "team task", "2025-01-01 10:00:00", false)
+	setTestTaskProject(t, taskID, projectID)
+	insertTestTask(t, ownerID, "private", "2025-01-02 10:00:00", false)
+
+	rec := httptest.NewRecorder()
+	body := `{"email": "member@example.com", "role": "viewer"}`
+	handlers.ShareProject(rec, userRequest(ownerID, http.MethodPost, "/api/v1/projects/"+strconv.Itoa(projectID)+"/shares", strings.NewReader(body)))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	// Sharing a project shares its tasks with the same role
+	assert.Equal(t, http.StatusOK, getTestTask(memberID, taskID).Code)
+	assert.Equal(t, http.StatusForbidden, updateTestTask(memberID, taskID, "renamed").Code)
+
+	rec = httptest.NewRecorder()
+	handlers.GetProjectTasks(rec, userRequest(memberID, http.MethodGet, "/api/v1/projects/"+strconv.Itoa(projectID)+"/tasks", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "team task")
+	assert.NotContains(t, rec.Body.String(), "private")
+
+	rec = httptest.NewRecorder()
+	handlers.GetSharedProjects(rec, userRequest(memberID, http.MethodGet, "/api/v1/shared/projects", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var projects struct {
+		Data []models.SharedProjectResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &projects))
+	if assert.Len(t, projects.Data, 1) {
+		assert.Equal(t, "Team", projects.Data[0].Name)
+		assert.Equal(t, models.RoleViewer, projects.Data[0].Role)
+		assert.Equal(t, "project-owner@example.com", projects.Data[0].OwnerEmail)
+	}
+
+	// Editors of the project can update it but not delete it
+	rec = httptest.NewRecorder()
+	body = `{"email": "member@example.com", "role": "editor"}`
+	handlers.ShareProject(rec, userRequest(ownerID, http.MethodPost, "/api/v1/projects/"+strconv.Itoa(projectID)+"/shares", strings.NewReader(body)))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, http.StatusOK, updateTestTask(memberID, taskID, "renamed").Code)
+
+	rec = httptest.NewRecorder()
+	handlers.DeleteProject(rec, userRequest(memberID, http.MethodDelete, "/api/v1/projects/"+strconv.Itoa(projectID), nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Revoking the project share removes access to its tasks
+	rec = httptest.NewRecorder()
+	handlers.RevokeProjectShare(rec, userRequest(ownerID, http.MethodDelete, "/api/v1/projects/"+strconv.Itoa(projectID)+"/shares/"+strconv.Itoa(memberID), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, http.StatusNotFound, getTestTask(memberID, taskID).Code)
+}
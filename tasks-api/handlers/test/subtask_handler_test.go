@@ -0,0 +1,99 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// createTestSubtask creates a task under parentID and returns the response
+func createTestSubtask(t *testing.T, userID int, title string, parentID int) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	body := `{"title": "` + title + `", "parent_id": ` + strconv.Itoa(parentID) + `}`
+	handlers.CreateTask(rec, userRequest(userID, http.MethodPost, "/api/v1/tasks", strings.NewReader(body)))
+	return rec
+}
+
+func TestSubtasks(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "subtasks@example.com", "password123")
+	otherID := createTestUser(t, "other-subtasks@example.com", "password123")
+
+	root := insertTestTask(t, userID, "release", "2025-01-01 10:00:00", false)
+	foreign := insertTestTask(t, otherID, "foreign", "2025-01-01 10:00:00", false)
+
+	var resp struct {
+		Data models.TaskResponse `json:"data"`
+	}
+	rec := createTestSubtask(t, userID, "docs", root)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	docs := resp.Data.ID
+
+	rec = createTestSubtask(t, userID, "code", root)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	code := resp.Data.ID
+
+	rec = createTestSubtask(t, userID, "tests", code)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	tests := resp.Data.ID
+
+	// Another user's task cannot be a parent
+	rec = createTestSubtask(t, userID, "sneaky", foreign)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	// Progress counts the completed direct subtasks
+	assert.Equal(t, http.StatusOK, completeTestTask(userID, docs).Code)
+
+	rec = httptest.NewRecorder()
+	handlers.GetOneTask(rec, userRequest(userID, http.MethodGet, "/api/v1/tasks/"+strconv.Itoa(root), nil))
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	if assert.NotNil(t, resp.Data.Progress) {
+		assert.Equal(t, models.TaskProgress{Completed: 1, Total: 2}, *resp.Data.Progress)
+	}
+
+	// The subtree nests every level
+	rec = httptest.NewRecorder()
+	handlers.GetTaskSubtree(rec, userRequest(userID, http.MethodGet, "/api/v1/tasks/"+strconv.Itoa(root)+"/subtree", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var tree struct {
+		Data models.TaskTreeNode `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tree))
+	if assert.Len(t, tree.Data.Subtasks, 2) {
+		assert.Equal(t, "docs", tree.Data.Subtasks[0].Title)
+		assert.Empty(t, tree.Data.Subtasks[0].Subtasks)
+		if assert.Len(t, tree.Data.Subtasks[1].Subtasks, 1) {
+			assert.Equal(t, "tests", tree.Data.Subtasks[1].Subtasks[0].Title)
+		}
+	}
+
+	// A task cannot be moved under one of its own subtasks
+	rec = httptest.NewRecorder()
+	body := `{"title": "release", "parent_id": ` + strconv.Itoa(tests) + `}`
+	handlers.UpdateTask(rec, userRequest(userID, http.MethodPut, "/api/v1/tasks/"+strconv.Itoa(root), strings.NewReader(body)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	// Deleting a task moves its subtasks up a level
+	rec = httptest.NewRecorder()
+	handlers.DeleteTask(rec, userRequest(userID, http.MethodDelete, "/api/v1/tasks/"+strconv.Itoa(code), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var parentID int
+	assert.NoError(t, config.DB.QueryRow("SELECT parent_id FROM tasks WHERE id = ?", tests).Scan(&parentID))
+	assert.Equal(t, root, parentID)
+}
@@ -0,0 +1,97 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// conditionalTestRequest calls handler for a task as userID with a
+// conditional header set
+func conditionalTestRequest(handler http.HandlerFunc, userID int, method string, taskID int, body, header, etag string) *httptest.ResponseRecorder {
+	var req *http.Request
+	if body == "" {
+		req = userRequest(userID, method, "/api/v1/tasks/"+strconv.Itoa(taskID), nil)
+	} else {
+		req = userRequest(userID, method, "/api/v1/tasks/"+strconv.Itoa(taskID), strings.NewReader(body))
+	}
+	req.Header.Set(header, etag)
+
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func TestTaskETags(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "etag@example.com", "password123")
+	taskID := insertTestTask(t, userID, "draft", "2025-01-01 10:00:00", false)
+
+	rec := getTestTask(userID, taskID)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	// A client that has the current version gets a 304 without a body
+	rec = conditionalTestRequest(handlers.GetOneTask, userID, http.MethodGet, taskID, "", "If-None-Match", etag)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	rec = conditionalTestRequest(handlers.GetOneTask, userID, http.MethodGet, taskID, "", "If-None-Match", `"other", W/`+etag)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	// Updating with the current version works, bumps the version and updated_at
+	rec = conditionalTestRequest(handlers.UpdateTask, userID, http.MethodPut, taskID, `{"title": "first"}`, "If-Match", etag)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	newETag := rec.Header().Get("ETag")
+	assert.NotEqual(t, etag, newETag)
+
+	var updated struct {
+		Data models.TaskResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &updated))
+	assert.Equal(t, 2, updated.Data.Version)
+	assert.True(t, updated.Data.UpdatedAt.After(updated.Data.CreatedAt))
+
+	// A second client still holding the old version is refused
+	rec = conditionalTestRequest(handlers.UpdateTask, userID, http.MethodPut, taskID, `{"title": "second"}`, "If-Match", etag)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	assert.Equal(t, newETag, rec.Header().Get("ETag"))
+
+	rec = conditionalTestRequest(handlers.CompleteTask, userID, http.MethodPatch, taskID, "", "If-Match", etag)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	// Weak tags never match If-Match
+	rec = conditionalTestRequest(handlers.UpdateTask, userID, http.MethodPut, taskID, `{"title": "second"}`, "If-Match", "W/"+newETag)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	// The old version is no longer current for If-None-Match either
+	rec = conditionalTestRequest(handlers.GetOneTask, userID, http.MethodGet, taskID, "", "If-None-Match", etag)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = conditionalTestRequest(handlers.CompleteTask, userID, http.MethodPatch, taskID, "", "If-Match", newETag)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, newETag, rec.Header().Get("ETag"))
+
+	// Requests without If-Match keep overwriting
+	assert.Equal(t, http.StatusOK, updateTestTask(userID, taskID, "third").Code)
+
+	// Changing the labels is a new version too
+	labelID := createTestLabel(t, userID, "work")
+	etag = getTestTask(userID, taskID).Header().Get("ETag")
+	rec = httptest.NewRecorder()
+	handlers.AttachTaskLabel(rec, userRequest(userID, http.MethodPost, "/api/v1/tasks/"+strconv.Itoa(taskID)+"/labels/"+strconv.Itoa(labelID), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+}
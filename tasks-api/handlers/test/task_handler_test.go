@@ -19,18 +19,26 @@ func setupTest() (*httptest.ResponseRecorder, *http.Request) {
 	recorder := httptest.NewRecorder()
 	req := httptest.NewRequest("GET", "/api/v1/tasks", nil)
 	req.Header.Set("Authorization", "Bearer test-session-token")
-	req = req.WithContext(context.WithValue(req.Context(), middleware.ContextUserIDKey, 1))
-	return recorder, req
+	return recorder, withTestUser(req)
+}
+
+// withTestUser authenticates req as the test user in their personal workspace
+func withTestUser(req *http.Request) *http.Request {
+	ctx := context.WithValue(req.Context(), middleware.ContextUserIDKey, 1)
+	if membership, err := middleware.LookupWorkspace(1, ""); err == nil {
+		ctx = context.WithValue(ctx, middleware.ContextWorkspaceKey, membership)
+	}
+	return req.WithContext(ctx)
 }
 
 // setupTestData creates test data for the test user
 func setupTestData() {
 	// Create test user
-	config.DB.Exec(`INSERT INTO users (id, email, password_hash) VALUES (?, ?, ?)`, 1, "test@example.com", "hashed-password")
+	config.DB.Exec(`INSERT INTO users (id, email, name, password) VALUES (?, ?, ?, ?)`, 1, "test@example.com", "Test User", "hashed-password")
 	// Create test session
 	config.DB.Exec(`INSERT INTO sessions (user_id, token, created_at, expires_at) VALUES (?, ?, datetime('now'), datetime('now', '+1 day'))`, 1, "test-session-token")
 	// Create test task
-	config.DB.Exec(`INSERT INTO tasks (id, user_id, title, description, created_at, updated_at, completed) VALUES (?, ?, ?, ?, datetime('now'), datetime('now'), ?)`, 1, 1, "Test Task", "Test Description", false)
+	config.DB.Exec(`INSERT INTO tasks (id, user_id, workspace_id, title, description, created_at, updated_at, completed) VALUES (?, ?, (SELECT id FROM workspaces WHERE personal_user_id = ?), ?, ?, datetime('now'), datetime('now'), ?)`, 1, 1, 1, "Test Task", "Test Description", false)
 }
 
 // cleanupTestData removes test data
@@ -46,8 +54,7 @@ func setupTestWithBody(body []byte) (*httptest.ResponseRecorder, *http.Request)
 	req := httptest.NewRequest("POST", "/api/v1/tasks", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer test-session-token")
-	req = req.WithContext(context.WithValue(req.Context(), middleware.ContextUserIDKey, 1))
-	return recorder, req
+	return recorder, withTestUser(req)
 }
 
 func TestGetTasks(t *testing.T) {
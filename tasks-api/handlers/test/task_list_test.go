@@ -0,0 +1,285 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// userRequest creates a request that is already authenticated as userID and
+// acts in their personal workspace
+func userRequest(userID int, method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	req.Header.Set("Content-Type", "application/json")
+
+	ctx := context.WithValue(req.Context(), middleware.ContextUserIDKey, userID)
+	if membership, err := middleware.LookupWorkspace(userID, ""); err == nil {
+		ctx = context.WithValue(ctx, middleware.ContextWorkspaceKey, membership)
+	}
+	return req.WithContext(ctx)
+}
+
+// insertTestTask inserts a task with fixed timestamps and returns its ID
+func insertTestTask(t *testing.T, userID int, title string, createdAt string, completed bool) int {
+	t.Helper()
+
+	result, err := config.DB.Exec(
+		"INSERT INTO tasks (user_id, workspace_id, title, description, created_at, updated_at, completed) VALUES (?, (SELECT id FROM workspaces WHERE personal_user_id = ?), ?, '', ?, ?, ?)",
+		userID, userID, title, createdAt, createdAt, completed,
+	)
+	if err != nil {
+		t.Fatalf("Failed to insert task: %v", err)
+	}
+
+	id, _ := result.LastInsertId()
+	return int(id)
+}
+
+type taskListResponse struct {
+	Status  string            `json:"status"`
+	Message string            `json:"message"`
+	Data    []models.Task     `json:"data"`
+	Meta    *models.Meta      `json:"meta"`
+	Errors  map[string]string `json:"errors"`
+}
+
+// listTestTasks calls GetTasks with the given query parameters
+func listTestTasks(t *testing.T, userID int, params url.Values) (int, taskListResponse) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	handlers.GetTasks(rec, userRequest(userID, http.MethodGet, "/api/v1/tasks?"+params.Encode(), nil))
+
+	var resp taskListResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return rec.Code, resp
+}
+
+func titles(tasks []models.Task) []string {
+	result := []string{}
+	for _, task := range tasks {
+		result = append(result, task.Title)
+	}
+	return result
+}
+
+func TestGetTasksPagination(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "pages@example.com", "password123")
+	otherID := createTestUser(t, "other-pages@example.com", "password123")
+
+	// Two tasks share a timestamp to exercise the ID tie breaker
+	insertTestTask(t, userID, "a", "2025-01-01 10:00:00", false)
+	insertTestTask(t, userID, "b", "2025-01-02 10:00:00", false)
+	insertTestTask(t, userID, "c", "2025-01-02 10:00:00", true)
+	insertTestTask(t, userID, "d", "2025-01-03 10:00:00", false)
+	insertTestTask(t, userID, "e", "2025-01-04 10:00:00", true)
+	insertTestTask(t, otherID, "other", "2025-01-05 10:00:00", false)
+
+	seen := []string{}
+	params := url.Values{"limit": {"2"}}
+	for page := 0; page < 5; page++ {
+		code, resp := listTestTasks(t, userID, params)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, 2, resp.Meta.Limit)
+		seen = append(seen, titles(resp.Data)...)
+
+		if resp.Meta.NextCursor == nil {
+			break
+		}
+		params.Set("cursor", *resp.Meta.NextCursor)
+	}
+
+	assert.Equal(t, []string{"e", "d", "c", "b", "a"}, seen)
+}
+
+func TestGetTasksFiltersAndSort(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "filters@example.com", "password123")
+	insertTestTask(t, userID, "Banana", "2025-01-01 10:00:00", false)
+	insertTestTask(t, userID, "apple", "2025-01-02 10:00:00", true)
+	insertTestTask(t, userID, "Cherry", "2025-01-03 10:00:00", false)
+
+	code, resp := listTestTasks(t, userID, url.Values{"sort": {"title"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"apple", "Banana", "Cherry"}, titles(resp.Data))
+	assert.Nil(t, resp.Meta.NextCursor)
+
+	code, resp = listTestTasks(t, userID, url.Values{"completed": {"false"}, "sort": {"-title"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"Cherry", "Banana"}, titles(resp.Data))
+
+	code, resp = listTestTasks(t, userID, url.Values{
+		"created_after":  {"2025-01-01T12:00:00Z"},
+		"created_before": {"2025-01-03T00:00:00Z"},
+	})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"apple"}, titles(resp.Data))
+
+	code, resp = listTestTasks(t, userID, url.Values{"updated_since": {"2025-01-02T10:00:00Z"}, "sort": {"updated_at"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"apple", "Cherry"}, titles(resp.Data))
+}
+
+func TestGetTasksValidation(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "invalid@example.com", "password123")
+
+	code, resp := listTestTasks(t, userID, url.Values{
+		"limit":         {"1000"},
+		"sort":          {"colour"},
+		"completed":     {"maybe"},
+		"updated_since": {"yesterday"},
+		"cursor":        {"not-a-cursor"},
+		"due":           {"someday"},
+		"priority":      {"critical"},
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, "Validation failed", resp.Message)
+	for _, field := range []string{"limit", "sort", "completed", "updated_since", "cursor", "due", "priority"} {
+		assert.Contains(t, resp.Errors, field)
+	}
+}
+
+// insertDueTask inserts a task due at the given time
+func insertDueTask(t *testing.T, userID int, title string, dueAt time.Time, completed bool) {
+	t.Helper()
+
+	_, err := config.DB.Exec(
+		"INSERT INTO tasks (user_id, workspace_id, title, description, due_at, completed) VALUES (?, (SELECT id FROM workspaces WHERE personal_user_id = ?), ?, '', ?, ?)",
+		userID, userID, title, dueAt.UTC().Format("2006-01-02 15:04:05"), completed,
+	)
+	if err != nil {
+		t.Fatalf("Failed to insert task: %v", err)
+	}
+}
+
+func TestGetTasksDueViews(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	// A timezone far from UTC so local and UTC days differ
+	const timezone = "Pacific/Kiritimati"
+	loc, err := time.LoadLocation(timezone)
+	assert.NoError(t, err)
+
+	userID := createTestUser(t, "due@example.com", "password123")
+	_, err = config.DB.Exec("UPDATE users SET timezone = ? WHERE id = ?", timezone, userID)
+	assert.NoError(t, err)
+
+	now := time.Now().In(loc)
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	startOfWeek := startOfDay.AddDate(0, 0, -((int(now.Weekday()) + 6) % 7))
+
+	insertDueTask(t, userID, "past", now.Add(-time.Hour), false)
+	insertDueTask(t, userID, "past done", now.Add(-time.Hour), true)
+	insertDueTask(t, userID, "future", now.Add(time.Hour), false)
+	insertDueTask(t, userID, "early today", startOfDay.Add(time.Minute), false)
+	insertDueTask(t, userID, "late today", startOfDay.Add(24*time.Hour-time.Minute), false)
+	insertDueTask(t, userID, "yesterday", startOfDay.Add(-time.Minute), false)
+	insertDueTask(t, userID, "tomorrow", startOfDay.AddDate(0, 0, 1).Add(time.Minute), false)
+	insertDueTask(t, userID, "week start", startOfWeek.Add(time.Minute), false)
+	insertDueTask(t, userID, "last week", startOfWeek.Add(-time.Minute), false)
+	insertDueTask(t, userID, "next week", startOfWeek.AddDate(0, 0, 7).Add(time.Minute), false)
+	insertTestTask(t, userID, "no due date", "2025-01-01 10:00:00", false)
+
+	code, resp := listTestTasks(t, userID, url.Values{"due": {"overdue"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, titles(resp.Data), "past")
+	assert.Contains(t, titles(resp.Data), "last week")
+	assert.NotContains(t, titles(resp.Data), "past done")
+	assert.NotContains(t, titles(resp.Data), "future")
+	assert.NotContains(t, titles(resp.Data), "no due date")
+
+	code, resp = listTestTasks(t, userID, url.Values{"due": {"today"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, titles(resp.Data), "early today")
+	assert.Contains(t, titles(resp.Data), "late today")
+	assert.NotContains(t, titles(resp.Data), "yesterday")
+	assert.NotContains(t, titles(resp.Data), "tomorrow")
+
+	code, resp = listTestTasks(t, userID, url.Values{"due": {"week"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, titles(resp.Data), "week start")
+	assert.Contains(t, titles(resp.Data), "late today")
+	assert.NotContains(t, titles(resp.Data), "last week")
+	assert.NotContains(t, titles(resp.Data), "next week")
+
+	// Sorting by due date puts tasks without one last
+	code, resp = listTestTasks(t, userID, url.Values{"sort": {"due_at"}, "limit": {"100"}})
+	assert.Equal(t, http.StatusOK, code)
+	all := titles(resp.Data)
+	assert.Equal(t, "last week", all[0])
+	assert.Equal(t, "no due date", all[len(all)-1])
+}
+
+func TestCreateTaskSchedulingFields(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "schedule@example.com", "password123")
+
+	// Priority defaults to medium
+	rec := httptest.NewRecorder()
+	handlers.CreateTask(rec, userRequest(userID, http.MethodPost, "/api/v1/tasks", strings.NewReader(`{"title": "Plain"}`)))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	var resp struct {
+		Data models.TaskResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, models.PriorityMedium, resp.Data.Priority)
+	assert.Nil(t, resp.Data.DueAt)
+
+	// Due date and priority are stored
+	rec = httptest.NewRecorder()
+	body := `{"title": "Scheduled", "due_at": "2025-07-01T09:30:00+02:00", "priority": "urgent"}`
+	handlers.CreateTask(rec, userRequest(userID, http.MethodPost, "/api/v1/tasks", strings.NewReader(body)))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, models.PriorityUrgent, resp.Data.Priority)
+	if assert.NotNil(t, resp.Data.DueAt) {
+		assert.True(t, resp.Data.DueAt.Equal(time.Date(2025, 7, 1, 7, 30, 0, 0, time.UTC)))
+	}
+
+	// Completing a task records when it happened
+	rec = httptest.NewRecorder()
+	req := userRequest(userID, http.MethodPatch, "/api/v1/tasks/"+strconv.Itoa(resp.Data.ID), nil)
+	handlers.CompleteTask(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var completedAt *time.Time
+	assert.NoError(t, config.DB.QueryRow("SELECT completed_at FROM tasks WHERE id = ?", resp.Data.ID).Scan(&completedAt))
+	assert.NotNil(t, completedAt)
+
+	// Unknown priorities are rejected
+	rec = httptest.NewRecorder()
+	handlers.CreateTask(rec, userRequest(userID, http.MethodPost, "/api/v1/tasks", strings.NewReader(`{"title": "Bad", "priority": "critical"}`)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
@@ -0,0 +1,131 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// refreshTestToken calls the RefreshToken handler with the given refresh token
+func refreshTestToken(refreshToken string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(models.RefreshTokenRequest{RefreshToken: refreshToken})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/token/refresh", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handlers.RefreshToken(rec, req)
+	return rec
+}
+
+func TestRefreshToken(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	createTestUser(t, "refresh@example.com", "password123")
+	login := loginTestUserTokens(t, "refresh@example.com", "password123")
+	assert.NotEmpty(t, login.RefreshToken)
+	assert.WithinDuration(t, time.Now().Add(config.AccessTokenDuration), login.ExpiresAt, time.Minute)
+
+	rec := refreshTestToken(login.RefreshToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Data models.TokenResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp.Data.Token)
+	assert.NotEqual(t, login.Token, resp.Data.Token)
+	assert.NotEqual(t, login.RefreshToken, resp.Data.RefreshToken)
+
+	// The old access token is replaced by the new one
+	rec = authRequest(http.MethodGet, "/api/v1/sessions", login.Token, nil, handlers.GetSessions)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = authRequest(http.MethodGet, "/api/v1/sessions", resp.Data.Token, nil, handlers.GetSessions)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Unknown refresh tokens are rejected
+	rec = refreshTestToken("unknown-token")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	createTestUser(t, "reuse@example.com", "password123")
+	login := loginTestUserTokens(t, "reuse@example.com", "password123")
+	other := loginTestUserTokens(t, "reuse@example.com", "password123")
+
+	rec := refreshTestToken(login.RefreshToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Data models.TokenResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+
+	// Replaying the rotated refresh token is detected
+	rec = refreshTestToken(login.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	var errResp models.Response
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	assert.Equal(t, "Refresh token reuse detected", errResp.Message)
+
+	// Every token of the family is now revoked
+	rec = refreshTestToken(resp.Data.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = authRequest(http.MethodGet, "/api/v1/sessions", resp.Data.Token, nil, handlers.GetSessions)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Tokens issued by other logins are unaffected
+	rec = refreshTestToken(other.RefreshToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestLogoutRevokesRefreshToken(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	createTestUser(t, "logout-refresh@example.com", "password123")
+	login := loginTestUserTokens(t, "logout-refresh@example.com", "password123")
+
+	rec := authRequest(http.MethodPost, "/api/v1/logout", login.Token, nil, handlers.Logout)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = refreshTestToken(login.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestAuthMiddlewareSlidesExpiry(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	createTestUser(t, "sliding@example.com", "password123")
+	token := loginTestUser(t, "sliding@example.com", "password123")
+
+	// Bring the session close to its expiry
+	soon := time.Now().UTC().Add(time.Minute)
+	_, err := config.DB.Exec("UPDATE sessions SET expires_at = ? WHERE token = ?", soon, token)
+	assert.NoError(t, err)
+
+	rec := authRequest(http.MethodGet, "/api/v1/sessions", token, nil, handlers.GetSessions)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var expiresAt time.Time
+	assert.NoError(t, config.DB.QueryRow("SELECT expires_at FROM sessions WHERE token = ?", token).Scan(&expiresAt))
+	assert.WithinDuration(t, time.Now().Add(config.AccessTokenDuration), expiresAt, time.Minute)
+}
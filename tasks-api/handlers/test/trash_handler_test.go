@@ -0,0 +1,235 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// deleteTestTask moves a task to the trash as userID
+func deleteTestTask(userID, taskID int) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handlers.DeleteTask(rec, userRequest(userID, http.MethodDelete, "/api/v1/tasks/"+strconv.Itoa(taskID), nil))
+	return rec
+}
+
+// restoreTestTask restores a task from the trash as userID
+func restoreTestTask(userID, taskID int) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handlers.RestoreTask(rec, userRequest(userID, http.MethodPost, "/api/v1/tasks/"+strconv.Itoa(taskID)+"/restore", nil))
+	return rec
+}
+
+// purgeTestTask permanently deletes a task in the trash as userID
+func purgeTestTask(userID, taskID int) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handlers.PurgeTask(rec, userRequest(userID, http.MethodDelete, "/api/v1/trash/"+strconv.Itoa(taskID), nil))
+	return rec
+}
+
+// listTestTrash lists the trash of the personal workspace of userID
+func listTestTrash(t *testing.T, userID int) []models.TaskResponse {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	handlers.GetTrash(rec, userRequest(userID, http.MethodGet, "/api/v1/trash", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Data []models.TaskResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.Data
+}
+
+func TestTrash(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	ownerID := createTestUser(t, "owner@example.com", "password123")
+	editorID := createTestUser(t, "editor@example.com", "password123")
+
+	parentID := insertTestTask(t, ownerID, "parent", "2025-01-01 10:00:00", false)
+	blockerID := insertTestTask(t, ownerID, "blocker", "2025-01-02 10:00:00", false)
+	rec := createTestSubtask(t, ownerID, "child", parentID)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var child struct {
+		Data models.TaskResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &child))
+	childID := child.Data.ID
+	assert.Equal(t, http.StatusCreated, addTestDependency(t, ownerID, parentID, blockerID))
+	assert.Equal(t, http.StatusCreated, shareTestTask(ownerID, parentID, "editor@example.com", models.RoleEditor).Code)
+
+	// Deleted tasks disappear from every normal query
+	assert.Equal(t, http.StatusOK, deleteTestTask(ownerID, blockerID).Code)
+	assert.Equal(t, http.StatusNotFound, getTestTask(ownerID, blockerID).Code)
+	assert.Equal(t, http.StatusNotFound, deleteTestTask(ownerID, blockerID).Code)
+
+	_, list := listTestTasks(t, ownerID, url.Values{})
+	assert.ElementsMatch(t, []string{"parent", "child"}, titles(list.Data))
+
+	_, results := searchTestTasks(t, ownerID, "blocker")
+	assert.Empty(t, results.Data)
+
+	// A task in the trash no longer blocks completion
+	assert.Equal(t, http.StatusOK, completeTestTask(ownerID, parentID).Code)
+
+	trash := listTestTrash(t, ownerID)
+	if assert.Len(t, trash, 1) {
+		assert.Equal(t, blockerID, trash[0].ID)
+		assert.NotNil(t, trash[0].DeletedAt)
+	}
+
+	// Deleting a parent moves its subtasks up, as before
+	assert.Equal(t, http.StatusOK, deleteTestTask(ownerID, parentID).Code)
+	var childParent *int
+	assert.NoError(t, config.DB.QueryRow("SELECT parent_id FROM tasks WHERE id = ?", childID).Scan(&childParent))
+	assert.Nil(t, childParent)
+	assert.Len(t, listTestTrash(t, ownerID), 2)
+
+	// Shared users cannot restore or purge, users without access cannot see the task
+	assert.Equal(t, http.StatusForbidden, restoreTestTask(editorID, parentID).Code)
+	assert.Equal(t, http.StatusForbidden, purgeTestTask(editorID, parentID).Code)
+	assert.Equal(t, http.StatusNotFound, restoreTestTask(ownerID, childID).Code)
+
+	// Restoring brings the task back with its shares and history
+	rec = restoreTestTask(ownerID, parentID)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var restored struct {
+		Data models.TaskResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &restored))
+	assert.Equal(t, "parent", restored.Data.Title)
+	assert.Nil(t, restored.Data.DeletedAt)
+	assert.Equal(t, http.StatusOK, getTestTask(editorID, parentID).Code)
+
+	_, events := getTestHistory(t, ownerID, parentID)
+	if assert.NotEmpty(t, events) {
+		assert.Equal(t, models.TaskEventRestored, events[len(events)-1].Action)
+		assert.Equal(t, models.TaskEventDeleted, events[len(events)-2].Action)
+	}
+
+	// Purging deletes the task for good
+	assert.Equal(t, http.StatusOK, purgeTestTask(ownerID, blockerID).Code)
+	assert.Equal(t, http.StatusNotFound, restoreTestTask(ownerID, blockerID).Code)
+	assert.Empty(t, listTestTrash(t, ownerID))
+
+	var count int
+	assert.NoError(t, config.DB.QueryRow("SELECT COUNT(*) FROM task_dependencies WHERE blocked_by_id = ?", blockerID).Scan(&count))
+	assert.Equal(t, 0, count)
+}
+
+func TestRestoreSubtask(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	userID := createTestUser(t, "subtasks@example.com", "password123")
+
+	parentID := insertTestTask(t, userID, "parent", "2025-01-01 10:00:00", false)
+	rec := createTestSubtask(t, userID, "child", parentID)
+	var child struct {
+		Data models.TaskResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &child))
+
+	// A subtask restored while its parent is in the trash moves to the top level
+	assert.Equal(t, http.StatusOK, deleteTestTask(userID, child.Data.ID).Code)
+	assert.Equal(t, http.StatusOK, deleteTestTask(userID, parentID).Code)
+	assert.Equal(t, http.StatusOK, restoreTestTask(userID, child.Data.ID).Code)
+
+	var parent *int
+	assert.NoError(t, config.DB.QueryRow("SELECT parent_id FROM tasks WHERE id = ?", child.Data.ID).Scan(&parent))
+	assert.Nil(t, parent)
+}
+
+func TestEmptyTrash(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	ownerID := createTestUser(t, "owner@example.com", "password123")
+	memberID := createTestUser(t, "member@example.com", "password123")
+	workspaceID := createTestWorkspace(t, ownerID, "Team")
+	addTestMember(t, workspaceID, memberID, models.WorkspaceRoleMember)
+
+	for _, userID := range []int{ownerID, memberID} {
+		rec := httptest.NewRecorder()
+		handlers.CreateTask(rec, workspaceRequest(t, userID, workspaceID, http.MethodPost, "/api/v1/tasks", strings.NewReader(`{"title": "trashed"}`)))
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var created struct {
+			Data models.TaskResponse `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+
+		rec = httptest.NewRecorder()
+		handlers.DeleteTask(rec, workspaceRequest(t, userID, workspaceID, http.MethodDelete, "/api/v1/tasks/"+strconv.Itoa(created.Data.ID), nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	// Members only purge the tasks they created
+	rec := httptest.NewRecorder()
+	handlers.EmptyTrash(rec, workspaceRequest(t, memberID, workspaceID, http.MethodDelete, "/api/v1/trash", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"tasks_deleted":1`)
+
+	rec = httptest.NewRecorder()
+	handlers.EmptyTrash(rec, workspaceRequest(t, ownerID, workspaceID, http.MethodDelete, "/api/v1/trash", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"tasks_deleted":1`)
+}
+
+func TestPurgeExpiredTrash(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	retention := config.TrashRetention
+	config.TrashRetention = 24 * time.Hour
+	defer func() { config.TrashRetention = retention }()
+
+	userID := createTestUser(t, "trash@example.com", "password123")
+	oldID := insertTestTask(t, userID, "old", "2025-01-01 10:00:00", false)
+	recentID := insertTestTask(t, userID, "recent", "2025-01-02 10:00:00", false)
+	insertTestTask(t, userID, "kept", "2025-01-03 10:00:00", false)
+
+	assert.Equal(t, http.StatusOK, deleteTestTask(userID, oldID).Code)
+	assert.Equal(t, http.StatusOK, deleteTestTask(userID, recentID).Code)
+
+	twoDaysAgo := time.Now().Add(-48 * time.Hour).UTC().Format("2006-01-02 15:04:05")
+	_, err := config.DB.Exec("UPDATE tasks SET deleted_at = ? WHERE id = ?", twoDaysAgo, oldID)
+	assert.NoError(t, err)
+
+	removed, err := handlers.PurgeExpiredTrash()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	trash := listTestTrash(t, userID)
+	if assert.Len(t, trash, 1) {
+		assert.Equal(t, "recent", trash[0].Title)
+	}
+
+	// A retention of zero keeps the trash until it is emptied by hand
+	config.TrashRetention = 0
+	_, err = config.DB.Exec("UPDATE tasks SET deleted_at = ? WHERE id = ?", twoDaysAgo, recentID)
+	assert.NoError(t, err)
+
+	removed, err = handlers.PurgeExpiredTrash()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), removed)
+}
@@ -0,0 +1,179 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// registerTestUser signs up through the Register handler
+func registerTestUser(t *testing.T, email, password string) models.RegisterResponse {
+	t.Helper()
+
+	body, _ := json.Marshal(models.RegisterRequest{Name: "New User", Email: email, Password: password})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/register", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handlers.Register(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Register failed with status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Data models.RegisterResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode register response: %v", err)
+	}
+	return resp.Data
+}
+
+// setTestUnverifiedPolicy sets the policy for unverified users for the rest of the test
+func setTestUnverifiedPolicy(t *testing.T, policy config.UnverifiedPolicy) {
+	previous := config.UnverifiedUsers
+	config.UnverifiedUsers = policy
+	t.Cleanup(func() { config.UnverifiedUsers = previous })
+}
+
+func TestEmailVerification(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	mailPath := useTestMailer(t)
+	setTestUnverifiedPolicy(t, config.UnverifiedReadOnly)
+
+	// New accounts start unverified and get a verification link
+	user := registerTestUser(t, "verify@example.com", "password123")
+	assert.False(t, user.EmailVerified)
+	tokens := sentTokens(t, mailPath, "verify-email")
+	assert.Len(t, tokens, 1)
+
+	// Read only users can log in and read, but not change anything
+	login := loginTestUserTokens(t, "verify@example.com", "password123")
+	assert.False(t, login.User.EmailVerified)
+
+	rec := authRequest(http.MethodGet, "/api/v1/tasks", login.Token, nil, handlers.GetTasks)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = authRequest(http.MethodPost, "/api/v1/tasks", login.Token, strings.NewReader(`{"title": "blocked"}`), handlers.CreateTask)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Email address is not verified")
+
+	// Tampered tokens are rejected
+	rec = publicRequest(handlers.VerifyEmail, `{"token": "`+tokens[0]+`x"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = publicRequest(handlers.VerifyEmail, `{"token": "`+tokens[0]+`"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Email verified successfully")
+
+	// Opening the link again does no harm
+	rec = publicRequest(handlers.VerifyEmail, `{"token": "`+tokens[0]+`"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Email already verified")
+
+	rec = authRequest(http.MethodPost, "/api/v1/tasks", login.Token, strings.NewReader(`{"title": "allowed"}`), handlers.CreateTask)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.True(t, loginTestUserTokens(t, "verify@example.com", "password123").User.EmailVerified)
+
+	// Verified accounts get no more emails
+	rec = publicRequest(handlers.ResendVerification, `{"email": "verify@example.com"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, sentTokens(t, mailPath, "verify-email"), 1)
+}
+
+func TestResendVerification(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	mailPath := useTestMailer(t)
+	registerTestUser(t, "resend@example.com", "password123")
+
+	// Another email is only sent once the interval has passed
+	unknown := publicRequest(handlers.ResendVerification, `{"email": "nobody@example.com"}`)
+	assert.Equal(t, http.StatusOK, unknown.Code)
+
+	rec := publicRequest(handlers.ResendVerification, `{"email": "resend@example.com"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, unknown.Body.String(), rec.Body.String())
+	assert.Len(t, sentTokens(t, mailPath, "verify-email"), 1)
+
+	_, err := config.DB.Exec("UPDATE users SET verification_sent_at = datetime('now', '-2 minutes')")
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, publicRequest(handlers.ResendVerification, `{"email": "resend@example.com"}`).Code)
+	tokens := sentTokens(t, mailPath, "verify-email")
+	assert.Len(t, tokens, 2)
+
+	// Links signed with another secret do not work
+	secret := config.EmailVerificationSecret
+	config.EmailVerificationSecret = []byte("another secret")
+	rec = publicRequest(handlers.VerifyEmail, `{"token": "`+tokens[1]+`"}`)
+	config.EmailVerificationSecret = secret
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Every link sent is valid
+	assert.Equal(t, http.StatusOK, publicRequest(handlers.VerifyEmail, `{"token": "`+tokens[0]+`"}`).Code)
+}
+
+func TestResendVerificationDoesNotWaitForEmail(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	useTestMailer(t)
+	registerTestUser(t, "slow-verify@example.com", "password123")
+	_, err := config.DB.Exec("UPDATE users SET verification_sent_at = datetime('now', '-2 minutes')")
+	assert.NoError(t, err)
+	release := blockTestEmails(t)
+
+	// Unverified accounts are answered before the email is sent, like unknown
+	// emails, so the response time does not tell them apart
+	rec := answeredWithoutEmail(t, func() *httptest.ResponseRecorder {
+		return publicRequest(handlers.ResendVerification, `{"email": "slow-verify@example.com"}`)
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	release()
+
+	// The email was still claimed for the account
+	var claimed int
+	assert.NoError(t, config.DB.QueryRow("SELECT COUNT(*) FROM users WHERE email = ? AND verification_sent_at > datetime('now', '-1 minute')", "slow-verify@example.com").Scan(&claimed))
+	assert.Equal(t, 1, claimed)
+}
+
+func TestUnverifiedUserPolicies(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	useTestMailer(t)
+	registerTestUser(t, "policy@example.com", "password123")
+
+	// Without restrictions unverified users can do everything
+	setTestUnverifiedPolicy(t, config.UnverifiedAllow)
+	token := loginTestUser(t, "policy@example.com", "password123")
+	rec := authRequest(http.MethodPost, "/api/v1/tasks", token, strings.NewReader(`{"title": "allowed"}`), handlers.CreateTask)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	// Without a verified email they cannot log in, and their sessions stop working
+	config.UnverifiedUsers = config.UnverifiedNoLogin
+	rec = loginTestAttempt("policy@example.com", "password123")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Email address is not verified")
+
+	rec = authRequest(http.MethodGet, "/api/v1/tasks", token, nil, handlers.GetTasks)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Wrong passwords are still told apart from unverified accounts
+	assert.Equal(t, http.StatusUnauthorized, loginTestAttempt("policy@example.com", "wrong-password").Code)
+}
@@ -0,0 +1,290 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/handlers"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// createTestWorkspace creates a workspace owned by userID and returns its ID
+func createTestWorkspace(t *testing.T, userID int, name string) int {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	handlers.CreateWorkspace(rec, userRequest(userID, http.MethodPost, "/api/v1/workspaces", strings.NewReader(`{"name": "`+name+`"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Failed to create workspace: %s", rec.Body.String())
+	}
+
+	var resp struct {
+		Data models.WorkspaceResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.Data.ID
+}
+
+// addTestMember adds a user to a workspace with the given role
+func addTestMember(t *testing.T, workspaceID, userID int, role string) {
+	t.Helper()
+
+	if _, err := config.DB.Exec("INSERT INTO workspace_members (workspace_id, user_id, role) VALUES (?, ?, ?)", workspaceID, userID, role); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+}
+
+// workspaceRequest creates a request authenticated as userID acting in the
+// given workspace
+func workspaceRequest(t *testing.T, userID, workspaceID int, method, target string, body io.Reader) *http.Request {
+	t.Helper()
+
+	membership, err := middleware.LookupWorkspace(userID, strconv.Itoa(workspaceID))
+	if err != nil {
+		t.Fatalf("Failed to look up workspace: %v", err)
+	}
+
+	req := userRequest(userID, method, target, body)
+	return req.WithContext(context.WithValue(req.Context(), middleware.ContextWorkspaceKey, membership))
+}
+
+func TestWorkspaceCRUD(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	ownerID := createTestUser(t, "owner@example.com", "password123")
+	adminID := createTestUser(t, "admin@example.com", "password123")
+	memberID := createTestUser(t, "member@example.com", "password123")
+	strangerID := createTestUser(t, "stranger@example.com", "password123")
+
+	workspaceID := createTestWorkspace(t, ownerID, "Team")
+	addTestMember(t, workspaceID, adminID, models.WorkspaceRoleAdmin)
+	addTestMember(t, workspaceID, memberID, models.WorkspaceRoleMember)
+	path := "/api/v1/workspaces/" + strconv.Itoa(workspaceID)
+
+	// The personal workspace is listed first
+	rec := httptest.NewRecorder()
+	handlers.GetWorkspaces(rec, userRequest(ownerID, http.MethodGet, "/api/v1/workspaces", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var list struct {
+		Data []models.WorkspaceResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	if assert.Len(t, list.Data, 2) {
+		assert.True(t, list.Data[0].Personal)
+		assert.Equal(t, "Team", list.Data[1].Name)
+		assert.Equal(t, models.WorkspaceRoleOwner, list.Data[1].Role)
+		assert.Equal(t, 3, list.Data[1].MemberCount)
+	}
+
+	// Non-members cannot see the workspace
+	rec = httptest.NewRecorder()
+	handlers.GetOneWorkspace(rec, userRequest(strangerID, http.MethodGet, path, nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Admins can rename the workspace, members cannot
+	rec = httptest.NewRecorder()
+	handlers.UpdateWorkspace(rec, userRequest(memberID, http.MethodPut, path, strings.NewReader(`{"name": "Mine"}`)))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handlers.UpdateWorkspace(rec, userRequest(adminID, http.MethodPut, path, strings.NewReader(`{"name": "Renamed"}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Only the owner can change roles, and not their own
+	rec = httptest.NewRecorder()
+	handlers.UpdateWorkspaceMember(rec, userRequest(adminID, http.MethodPut, path+"/members/"+strconv.Itoa(memberID), strings.NewReader(`{"role": "admin"}`)))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handlers.UpdateWorkspaceMember(rec, userRequest(ownerID, http.MethodPut, path+"/members/"+strconv.Itoa(ownerID), strings.NewReader(`{"role": "member"}`)))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handlers.UpdateWorkspaceMember(rec, userRequest(ownerID, http.MethodPut, path+"/members/"+strconv.Itoa(adminID), strings.NewReader(`{"role": "owner"}`)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	// Members cannot remove admins, admins can remove members
+	rec = httptest.NewRecorder()
+	handlers.RemoveWorkspaceMember(rec, userRequest(memberID, http.MethodDelete, path+"/members/"+strconv.Itoa(adminID), nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handlers.RemoveWorkspaceMember(rec, userRequest(adminID, http.MethodDelete, path+"/members/"+strconv.Itoa(memberID), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handlers.GetOneWorkspace(rec, userRequest(memberID, http.MethodGet, path, nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// The owner cannot leave, and only the owner can delete the workspace
+	rec = httptest.NewRecorder()
+	handlers.RemoveWorkspaceMember(rec, userRequest(ownerID, http.MethodDelete, path+"/members/"+strconv.Itoa(ownerID), nil))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handlers.DeleteWorkspace(rec, userRequest(adminID, http.MethodDelete, path, nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handlers.GetWorkspaceMembers(rec, userRequest(adminID, http.MethodGet, path+"/members", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var members struct {
+		Data []models.WorkspaceMemberResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &members))
+	if assert.Len(t, members.Data, 2) {
+		assert.Equal(t, ownerID, members.Data[0].UserID)
+		assert.Equal(t, adminID, members.Data[1].UserID)
+	}
+
+	// Deleting the workspace removes its tasks
+	taskRec := httptest.NewRecorder()
+	handlers.CreateTask(taskRec, workspaceRequest(t, adminID, workspaceID, http.MethodPost, "/api/v1/tasks", strings.NewReader(`{"title": "team task"}`)))
+	assert.Equal(t, http.StatusCreated, taskRec.Code)
+
+	rec = httptest.NewRecorder()
+	handlers.DeleteWorkspace(rec, userRequest(ownerID, http.MethodDelete, path, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var count int
+	assert.NoError(t, config.DB.QueryRow("SELECT COUNT(*) FROM tasks WHERE workspace_id = ?", workspaceID).Scan(&count))
+	assert.Equal(t, 0, count)
+
+	// Personal workspaces cannot be deleted
+	personal, err := middleware.LookupWorkspace(ownerID, "")
+	assert.NoError(t, err)
+
+	rec = httptest.NewRecorder()
+	handlers.DeleteWorkspace(rec, userRequest(ownerID, http.MethodDelete, "/api/v1/workspaces/"+strconv.Itoa(personal.WorkspaceID), nil))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestWorkspaceIsolation(t *testing.T) {
+	// Set up test database
+	config.DB = config.InitTestDB()
+	defer config.DB.Close()
+
+	ownerID := createTestUser(t, "owner@example.com", "password123")
+	memberID := createTestUser(t, "member@example.com", "password123")
+	createTestUser(t, "stranger@example.com", "password123")
+
+	workspaceID := createTestWorkspace(t, ownerID, "Team")
+	addTestMember(t, workspaceID, memberID, models.WorkspaceRoleMember)
+
+	// A task created by the owner in the team workspace
+	rec := httptest.NewRecorder()
+	handlers.CreateTask(rec, workspaceRequest(t, ownerID, workspaceID, http.MethodPost, "/api/v1/tasks", strings.NewReader(`{"title": "team invoice"}`)))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	var created struct {
+		Data models.TaskResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.Equal(t, workspaceID, created.Data.WorkspaceID)
+	teamTaskID := created.Data.ID
+
+	personalTaskID := insertTestTask(t, ownerID, "personal invoice", "2025-01-01 10:00:00", false)
+
+	// Members see and edit the team's tasks, but not the owner's personal ones
+	rec = httptest.NewRecorder()
+	handlers.GetTasks(rec, workspaceRequest(t, memberID, workspaceID, http.MethodGet, "/api/v1/tasks", nil))
+	var list taskListResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	assert.Equal(t, []string{"team invoice"}, titles(list.Data))
+
+	rec = httptest.NewRecorder()
+	handlers.UpdateTask(rec, workspaceRequest(t, memberID, workspaceID, http.MethodPut, "/api/v1/tasks/"+strconv.Itoa(teamTaskID), strings.NewReader(`{"title": "team invoice"}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handlers.DeleteTask(rec, workspaceRequest(t, memberID, workspaceID, http.MethodDelete, "/api/v1/tasks/"+strconv.Itoa(teamTaskID), nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handlers.GetOneTask(rec, workspaceRequest(t, memberID, workspaceID, http.MethodGet, "/api/v1/tasks/"+strconv.Itoa(personalTaskID), nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// The team's tasks do not leak into personal workspaces
+	_, own := listTestTasks(t, ownerID, nil)
+	assert.Equal(t, []string{"personal invoice"}, titles(own.Data))
+	assert.Equal(t, http.StatusNotFound, getTestTask(memberID, teamTaskID).Code)
+	assert.Equal(t, http.StatusNotFound, getTestTask(ownerID, teamTaskID).Code)
+
+	_, search := searchTestTasks(t, ownerID, "invoice")
+	if assert.Len(t, search.Data, 1) {
+		assert.Equal(t, "personal invoice", search.Data[0].Title)
+	}
+
+	// Projects and parents must belong to the same workspace
+	personalProjectID := createTestProject(t, ownerID, `{"name": "Home"}`)
+
+	rec = httptest.NewRecorder()
+	handlers.CreateTask(rec, workspaceRequest(t, ownerID, workspaceID, http.MethodPost, "/api/v1/tasks", strings.NewReader(`{"title": "x", "project_id": `+strconv.Itoa(personalProjectID)+`}`)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handlers.CreateTask(rec, workspaceRequest(t, ownerID, workspaceID, http.MethodPost, "/api/v1/tasks", strings.NewReader(`{"title": "x", "parent_id": `+strconv.Itoa(personalTaskID)+`}`)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	projects := listTestProjects(t, memberID, "")
+	assert.Empty(t, projects)
+
+	// Through AuthMiddleware, the header selects the workspace and non-members get a 404
+	token := loginTestUser(t, "member@example.com", "password123")
+	strangerToken := loginTestUser(t, "stranger@example.com", "password123")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(middleware.WorkspaceHeader, strconv.Itoa(workspaceID))
+	rec = httptest.NewRecorder()
+	middleware.AuthMiddleware(handlers.GetTasks)(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	assert.Equal(t, []string{"team invoice"}, titles(list.Data))
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/tasks", nil)
+	req.Header.Set("Authorization", "Bearer "+strangerToken)
+	req.Header.Set(middleware.WorkspaceHeader, strconv.Itoa(workspaceID))
+	rec = httptest.NewRecorder()
+	middleware.AuthMiddleware(handlers.GetTasks)(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/tasks", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(middleware.WorkspaceHeader, "team")
+	rec = httptest.NewRecorder()
+	middleware.AuthMiddleware(handlers.GetTasks)(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// The path prefix works like the header
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/tasks/{id}", middleware.AuthMiddleware(handlers.GetOneTask))
+	mux.HandleFunc("/api/v1/workspaces/{workspace_id}/", middleware.WorkspacePrefix(mux))
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/workspaces/"+strconv.Itoa(workspaceID)+"/tasks/"+strconv.Itoa(teamTaskID), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/tasks/"+strconv.Itoa(teamTaskID), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
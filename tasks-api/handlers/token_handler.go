@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/go-playground/validator/v10"
+)
+
+// RefreshToken exchanges a refresh token for a new access and refresh token pair.
+// Every refresh token can be used only once; presenting one that was already
+// rotated revokes the whole token family because it has likely been stolen.
+func RefreshToken(w http.ResponseWriter, r *http.Request) {
+	// Check that method is post
+	if r.Method != http.MethodPost {
+		config.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed. Use POST", nil)
+		return
+	}
+
+	// Check if request body is empty
+	if r.ContentLength == 0 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Request body is empty", nil)
+		return
+	}
+
+	var req models.RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	// Validate input
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Validation failed", err)
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to refresh token", err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Find the refresh token by its hash
+	var token models.RefreshToken
+	err = tx.QueryRow(
+		"SELECT id, user_id, family_id, expires_at, used_at, revoked_at FROM refresh_tokens WHERE token_hash = ?",
+		config.HashToken(req.RefreshToken),
+	).Scan(&token.ID, &token.UserID, &token.FamilyID, &token.ExpiresAt, &token.UsedAt, &token.RevokedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			config.WriteErrorResponse(w, http.StatusUnauthorized, "Invalid refresh token", nil)
+			return
+		}
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to refresh token", err)
+		return
+	}
+
+	if token.RevokedAt != nil {
+		config.WriteErrorResponse(w, http.StatusUnauthorized, "Refresh token has been revoked", nil)
+		return
+	}
+
+	if token.UsedAt != nil {
+		rejectReusedRefreshToken(w, tx, token.FamilyID)
+		return
+	}
+
+	now := time.Now().UTC()
+	if token.ExpiresAt.Before(now) {
+		config.WriteErrorResponse(w, http.StatusUnauthorized, "Refresh token has expired", nil)
+		return
+	}
+
+	// Mark the token as used; a concurrent refresh with the same token loses the race
+	result, err := tx.Exec("UPDATE refresh_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL", now, token.ID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to refresh token", err)
+		return
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to refresh token", err)
+		return
+	}
+
+	if affected == 0 {
+		rejectReusedRefreshToken(w, tx, token.FamilyID)
+		return
+	}
+
+	// Replace the access tokens of this family with a fresh pair
+	if _, err := tx.Exec("DELETE FROM sessions WHERE family_id = ?", token.FamilyID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to refresh token", err)
+		return
+	}
+
+	tokens, err := issueTokens(tx, token.UserID, token.FamilyID, r)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to refresh token", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to refresh token", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Token refreshed successfully", tokens)
+}
+
+// rejectReusedRefreshToken revokes the token family after a refresh token was
+// presented a second time and writes the error response
+func rejectReusedRefreshToken(w http.ResponseWriter, tx *sql.Tx, familyID string) {
+	if err := revokeTokenFamily(tx, familyID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to revoke tokens", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to revoke tokens", err)
+		return
+	}
+
+	config.WriteErrorResponse(w, http.StatusUnauthorized, "Refresh token reuse detected", nil)
+}
+
+// issueTokens creates a new access token session and refresh token in the given family
+func issueTokens(q querier, userID int, familyID string, r *http.Request) (*models.TokenResponse, error) {
+	accessToken, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	session := models.Session{
+		UserID:    userID,
+		FamilyID:  familyID,
+		Token:     accessToken,
+		CreatedAt: now,
+		ExpiresAt: now.Add(config.AccessTokenDuration),
+		UserAgent: r.UserAgent(),
+		IPAddress: middleware.ClientIP(r),
+	}
+
+	// Insert access token session
+	if _, err := q.Exec(
+		"INSERT INTO sessions (user_id, family_id, token, created_at, expires_at, user_agent, ip_address) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		session.UserID,
+		session.FamilyID,
+		session.Token,
+		session.CreatedAt,
+		session.ExpiresAt,
+		session.UserAgent,
+		session.IPAddress,
+	); err != nil {
+		return nil, err
+	}
+
+	// Insert refresh token; only its hash is stored
+	refreshExpiresAt := now.Add(config.RefreshTokenDuration)
+	if _, err := q.Exec(
+		"INSERT INTO refresh_tokens (user_id, family_id, token_hash, created_at, expires_at) VALUES (?, ?, ?, ?, ?)",
+		userID,
+		familyID,
+		config.HashToken(refreshToken),
+		now,
+		refreshExpiresAt,
+	); err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{
+		Token:                 accessToken,
+		ExpiresAt:             session.ExpiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+// revokeTokenFamily revokes every refresh token of a family and deletes its access tokens
+func revokeTokenFamily(q querier, familyID string) error {
+	if familyID == "" {
+		return nil
+	}
+
+	if _, err := q.Exec(
+		"UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL",
+		time.Now().UTC(),
+		familyID,
+	); err != nil {
+		return err
+	}
+
+	_, err := q.Exec("DELETE FROM sessions WHERE family_id = ?", familyID)
+	return err
+}
@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+)
+
+// GetTrash lists the deleted tasks of the current workspace, most recently
+// deleted first: GET /api/v1/trash
+func GetTrash(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	rows, err := config.DB.Query(
+		"SELECT "+taskColumns+" FROM tasks WHERE workspace_id = ? AND deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC",
+		membership.WorkspaceID,
+	)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch trash", err)
+		return
+	}
+
+	defer rows.Close()
+
+	tasks := []models.TaskResponse{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to scan task", err)
+			return
+		}
+		tasks = append(tasks, newTaskResponse(task))
+	}
+
+	if err := rows.Err(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch trash", err)
+		return
+	}
+
+	if err := loadTaskDetails(config.DB, taskResponsePointers(tasks)); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch task details", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Trash retrieved successfully", tasks)
+}
+
+// RestoreTask moves a task out of the trash: POST /api/v1/tasks/{id}/restore
+func RestoreTask(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get task ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/tasks/")
+	if len(segments) != 2 || segments[1] != "restore" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Task ID is required", nil)
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to restore task", err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Only the owner can restore a task, as only they can delete it
+	task, ok := authorizeTrashedTask(w, tx, membership, segments[0], models.RoleOwner)
+	if !ok {
+		return
+	}
+
+	// A task whose parent is gone or still in the trash moves to the top level
+	_, err = tx.Exec(
+		"UPDATE tasks SET deleted_at = NULL, parent_id = CASE WHEN parent_id IN (SELECT id FROM tasks WHERE deleted_at IS NULL) THEN parent_id END, "+touchTaskSQL+" WHERE id = ?",
+		task.ID,
+	)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to restore task", err)
+		return
+	}
+
+	// Fetch the task
+	task, err = getTask(tx, task.WorkspaceID, task.ID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch restored task", err)
+		return
+	}
+
+	// Record the restore with the values the task came back with
+	if err := recordTaskSnapshotEvent(tx, membership.UserID, models.TaskEventRestored, task, nil); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to record task history", err)
+		return
+	}
+
+	response, err := taskResponseWithDetails(tx, task)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch task details", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to restore task", err)
+		return
+	}
+
+	w.Header().Set("ETag", taskETag(task))
+	config.WriteSuccessResponse(w, "Task restored successfully", response)
+}
+
+// PurgeTask permanently deletes a task in the trash: DELETE /api/v1/trash/{id}
+func PurgeTask(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Get task ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/trash/")
+	if len(segments) != 1 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Task ID is required", nil)
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete task", err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Only the owner can delete a task
+	task, ok := authorizeTrashedTask(w, tx, membership, segments[0], models.RoleOwner)
+	if !ok {
+		return
+	}
+
+	// Remember the attached files so they can be removed once the task is gone
+	files, err := taskAttachmentFiles(tx, "?", task.ID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch attachments", err)
+		return
+	}
+
+	// Delete the task with its labels, dependencies, shares, comments and attachments
+	if _, err := deleteTasks(tx, "?", task.ID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete task", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete task", err)
+		return
+	}
+
+	removeAttachmentFiles(files)
+
+	config.WriteSuccessResponse(w, "Task permanently deleted", nil)
+}
+
+// EmptyTrash permanently deletes the tasks in the trash of the current
+// workspace that the user is allowed to delete: DELETE /api/v1/trash
+func EmptyTrash(w http.ResponseWriter, r *http.Request) {
+	// Get the workspace membership from context
+	membership, ok := middleware.GetWorkspaceFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Workspace not found in context", fmt.Errorf("workspace not found in context"))
+		return
+	}
+
+	// Members can only delete the tasks they created; admins and the owner
+	// can delete every task
+	idQuery := "SELECT id FROM tasks WHERE workspace_id = ? AND deleted_at IS NOT NULL"
+	args := []any{membership.WorkspaceID}
+	if workspaceRank(membership) < roleRanks[models.RoleOwner] {
+		idQuery += " AND user_id = ?"
+		args = append(args, membership.UserID)
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to empty trash", err)
+		return
+	}
+	defer tx.Rollback()
+
+	files, err := taskAttachmentFiles(tx, idQuery, args...)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch attachments", err)
+		return
+	}
+
+	affected, err := deleteTasks(tx, idQuery, args...)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to empty trash", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to empty trash", err)
+		return
+	}
+
+	removeAttachmentFiles(files)
+
+	config.WriteSuccessResponse(w, "Trash emptied successfully", map[string]int64{"tasks_deleted": affected})
+}
+
+// PurgeExpiredTrash permanently deletes every task that has been in the trash
+// for longer than config.TrashRetention
+func PurgeExpiredTrash() (int64, error) {
+	if config.TrashRetention <= 0 {
+		return 0, nil
+	}
+
+	cutoff := time.Now().Add(-config.TrashRetention)
+	idQuery := "SELECT id FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at <= ?"
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	files, err := taskAttachmentFiles(tx, idQuery, sqlTime(&cutoff))
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := deleteTasks(tx, idQuery, sqlTime(&cutoff))
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	removeAttachmentFiles(files)
+	return affected, nil
+}
+
+// trashTasks moves the tasks selected by idQuery to the trash and records a
+// deleted event for each of them. Their subtasks move up to their closest
+// ancestor outside the trash, the same way as when deleting tasks.
+func trashTasks(q querier, actorID int, idQuery string, args ...any) (int64, error) {
+	rows, err := q.Query("SELECT "+taskColumns+" FROM tasks WHERE id IN ("+idQuery+") AND deleted_at IS NULL", args...)
+	if err != nil {
+		return 0, err
+	}
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		tasks = append(tasks, task)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// Record the deletions with the last values of the tasks
+	for _, task := range tasks {
+		before, err := taskSnapshot(q, task)
+		if err != nil {
+			return 0, err
+		}
+		if err := recordTaskEvent(q, actorID, models.TaskEventDeleted, task, before, nil); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := moveSubtasksUp(q, idQuery, args...); err != nil {
+		return 0, err
+	}
+
+	// Every task gets the same deletion time
+	now := time.Now()
+	result, err := q.Exec(
+		"UPDATE tasks SET deleted_at = ?, "+touchTaskSQL+" WHERE id IN ("+idQuery+") AND deleted_at IS NULL",
+		append([]any{sqlTime(&now)}, args...)...,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
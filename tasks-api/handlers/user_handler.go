@@ -56,17 +56,25 @@ func Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Default to UTC when no timezone is given
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+
 	// Create user
 	user := models.User{
 		Name:      req.Name,
 		Email:     req.Email,
 		Password:  hashedPassword,
+		Timezone:  timezone,
 		CreatedAt: time.Now(),
 	}
 
-	// Insert user into database and get result
-	result, err := config.DB.Exec(`INSERT INTO users (name, email, password) VALUES (?, ?, ?)`,
-		user.Name, user.Email, user.Password)
+	// Insert user into database and get result; the email is not verified yet
+	sentAt := user.CreatedAt.UTC()
+	result, err := config.DB.Exec(`INSERT INTO users (name, email, password, timezone, verification_sent_at) VALUES (?, ?, ?, ?, ?)`,
+		user.Name, user.Email, user.Password, user.Timezone, sqlTime(&sentAt))
 	if err != nil {
 		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create user", nil)
 		return
@@ -78,13 +86,19 @@ func Register(w http.ResponseWriter, r *http.Request) {
 		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get user ID", nil)
 		return
 	}
+	user.ID = int(lastID)
+
+	// A failed email is only logged; the user can ask for another one
+	sendVerificationEmail(user)
 
 	// Prepare response
 	response := models.RegisterResponse{
-		ID:        int(lastID),
-		Name:      user.Name,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
+		ID:            user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		EmailVerified: false,
+		Timezone:      user.Timezone,
+		CreatedAt:     user.CreatedAt,
 	}
 
 	// Write success response
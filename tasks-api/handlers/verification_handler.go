@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/mail"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/go-playground/validator/v10"
+)
+
+// resendVerificationMessage is the response to every request for another
+// verification email, so it does not reveal which emails have an account
+const resendVerificationMessage = "If an unverified account with this email exists, a verification link has been sent to it"
+
+// errInvalidVerificationToken is returned for tokens that were not signed by
+// this server, or have expired
+var errInvalidVerificationToken = errors.New("invalid or expired verification token")
+
+// VerifyEmail marks the email of a user as verified with the token from the
+// verification email
+func VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	// Check that method is post
+	if r.Method != http.MethodPost {
+		config.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed. Use POST", nil)
+		return
+	}
+
+	// Check if request body is empty
+	if r.ContentLength == 0 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Request body is empty", nil)
+		return
+	}
+
+	var req models.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	// Validate input
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Validation failed", err)
+		return
+	}
+
+	userID, email, err := parseVerificationToken(req.Token, time.Now())
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid or expired verification token", nil)
+		return
+	}
+
+	// The token only verifies the email it was sent to
+	var verifiedAt sql.NullTime
+	err = config.DB.QueryRow("SELECT email_verified_at FROM users WHERE id = ? AND email = ?", userID, email).Scan(&verifiedAt)
+	if err == sql.ErrNoRows {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid or expired verification token", nil)
+		return
+	}
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch user", err)
+		return
+	}
+
+	// Opening the link twice is fine
+	if verifiedAt.Valid {
+		config.WriteSuccessResponse(w, "Email already verified", nil)
+		return
+	}
+
+	now := time.Now().UTC()
+	if _, err := config.DB.Exec("UPDATE users SET email_verified_at = ? WHERE id = ? AND email_verified_at IS NULL", sqlTime(&now), userID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to verify email", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Email verified successfully", nil)
+}
+
+// ResendVerification sends another verification email to an unverified
+// user. It does not need a login, since unverified users may not be allowed
+// to log in. A user gets at most one email every
+// config.EmailVerificationResendInterval; requests in between are ignored.
+func ResendVerification(w http.ResponseWriter, r *http.Request) {
+	// Check that method is post
+	if r.Method != http.MethodPost {
+		config.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed. Use POST", nil)
+		return
+	}
+
+	// Check if request body is empty
+	if r.ContentLength == 0 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Request body is empty", nil)
+		return
+	}
+
+	var req models.ResendVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	// Validate input
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Validation failed", err)
+		return
+	}
+
+	// Claim the next email in a single statement, so every request does the
+	// same work; unknown, verified and throttled accounts are skipped with the
+	// same response, and the email goes out in the background
+	now := time.Now().UTC()
+	cutoff := now.Add(-config.EmailVerificationResendInterval)
+	var user models.User
+	err := config.DB.QueryRow(
+		"UPDATE users SET verification_sent_at = ? WHERE email = ? AND email_verified_at IS NULL AND (verification_sent_at IS NULL OR verification_sent_at <= ?) RETURNING id, name, email",
+		sqlTime(&now),
+		req.Email,
+		sqlTime(&cutoff),
+	).Scan(&user.ID, &user.Name, &user.Email)
+	if err != nil && err != sql.ErrNoRows {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to send verification email", err)
+		return
+	}
+
+	if err == nil {
+		sendVerificationEmail(user)
+	}
+
+	config.WriteSuccessResponse(w, resendVerificationMessage, nil)
+}
+
+// sendVerificationEmail emails a verification link to user in the
+// background, so the response does not wait for the mail server. Failures are
+// only logged; the user can ask for another email.
+func sendVerificationEmail(user models.User) {
+	token := signVerificationToken(user.ID, user.Email, time.Now().Add(config.EmailVerificationDuration))
+	link := config.BaseURL + "/verify-email?token=" + url.QueryEscape(token)
+
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm this is your email address by opening\n\n%s\n\nThe link expires in %d hours. If you did not create an account, you can ignore this email.\n",
+			user.Name,
+			link,
+			int(config.EmailVerificationDuration.Hours()),
+		),
+	}
+	// The mailer is picked now, in case it is replaced before the email goes out
+	mailer := config.Mailer
+	config.SendInBackground(func() {
+		if err := mailer.Send(msg); err != nil {
+			log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+		}
+	})
+}
+
+// signVerificationToken returns a token for verifying email as the email of
+// userID until expiresAt. Nothing is stored; the token carries its claims and
+// an HMAC of them with config.EmailVerificationSecret.
+func signVerificationToken(userID int, email string, expiresAt time.Time) string {
+	payload := strconv.Itoa(userID) + "|" + strconv.FormatInt(expiresAt.Unix(), 10) + "|" + email
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(verificationSignature(payload))
+}
+
+// parseVerificationToken checks the signature and expiry of a token from
+// signVerificationToken and returns its user ID and email
+func parseVerificationToken(token string, now time.Time) (int, string, error) {
+	encodedPayload, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return 0, "", errInvalidVerificationToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return 0, "", errInvalidVerificationToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, verificationSignature(string(payload))) {
+		return 0, "", errInvalidVerificationToken
+	}
+
+	// The email comes last since it is the only part that could contain "|"
+	parts := strings.SplitN(string(payload), "|", 3)
+	if len(parts) != 3 {
+		return 0, "", errInvalidVerificationToken
+	}
+	userID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, "", errInvalidVerificationToken
+	}
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || now.Unix() >= expiresAt {
+		return 0, "", errInvalidVerificationToken
+	}
+
+	return userID, parts[2], nil
+}
+
+// verificationSignature signs the payload of a verification token
+func verificationSignature(payload string) []byte {
+	mac := hmac.New(sha256.New, config.EmailVerificationSecret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
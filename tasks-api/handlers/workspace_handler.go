@@ -0,0 +1,540 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
+	"github.com/go-playground/validator/v10"
+)
+
+// workspaceRoleRanks orders the workspace roles from least to most privileged
+var workspaceRoleRanks = map[string]int{
+	models.WorkspaceRoleMember: 1,
+	models.WorkspaceRoleAdmin:  2,
+	models.WorkspaceRoleOwner:  3,
+}
+
+// workspaceColumns lists the workspace columns in the order scanWorkspace
+// expects them. The query must join the user's membership as m.
+const workspaceColumns = "w.id, w.name, w.personal_user_id IS NOT NULL, m.role, (SELECT COUNT(*) FROM workspace_members c WHERE c.workspace_id = w.id), w.created_at, w.updated_at"
+
+// GetWorkspaces lists the workspaces the authenticated user is a member of,
+// starting with their personal workspace
+func GetWorkspaces(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	rows, err := config.DB.Query(
+		"SELECT "+workspaceColumns+" FROM workspaces w JOIN workspace_members m ON m.workspace_id = w.id AND m.user_id = ?"+
+			" ORDER BY w.personal_user_id IS NULL, w.name COLLATE NOCASE, w.id",
+		userID,
+	)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch workspaces", err)
+		return
+	}
+
+	defer rows.Close()
+
+	workspaces := []models.WorkspaceResponse{}
+	for rows.Next() {
+		workspace, err := scanWorkspace(rows)
+		if err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to scan workspace", err)
+			return
+		}
+		workspaces = append(workspaces, workspace)
+	}
+
+	if err := rows.Err(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch workspaces", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Workspaces retrieved successfully", workspaces)
+}
+
+// GetOneWorkspace retrieves a workspace the authenticated user is a member of
+func GetOneWorkspace(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	// Get workspace ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/workspaces/")
+	if len(segments) != 1 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Workspace ID is required", nil)
+		return
+	}
+
+	membership, ok := authorizeWorkspace(w, userID, segments[0], models.WorkspaceRoleMember)
+	if !ok {
+		return
+	}
+
+	workspace, err := getWorkspace(config.DB, membership)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch workspace", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Workspace retrieved successfully", workspace)
+}
+
+// CreateWorkspace creates a workspace with the authenticated user as its owner
+func CreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	req, ok := decodeWorkspaceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create workspace", err)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO workspaces (name) VALUES (?)", req.Name)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create workspace", err)
+		return
+	}
+
+	// Get the last inserted ID
+	lastID, err := result.LastInsertId()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get workspace ID", err)
+		return
+	}
+
+	membership := models.WorkspaceMembership{WorkspaceID: int(lastID), UserID: userID, Role: models.WorkspaceRoleOwner}
+	if _, err := tx.Exec(
+		"INSERT INTO workspace_members (workspace_id, user_id, role) VALUES (?, ?, ?)",
+		membership.WorkspaceID,
+		membership.UserID,
+		membership.Role,
+	); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create workspace", err)
+		return
+	}
+
+	workspace, err := getWorkspace(tx, membership)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch created workspace", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create workspace", err)
+		return
+	}
+
+	config.WriteCreatedResponse(w, "Workspace created successfully", workspace)
+}
+
+// UpdateWorkspace renames a workspace. Admins and the owner can rename it.
+func UpdateWorkspace(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	// Get workspace ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/workspaces/")
+	if len(segments) != 1 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Workspace ID is required", nil)
+		return
+	}
+
+	membership, ok := authorizeWorkspace(w, userID, segments[0], models.WorkspaceRoleAdmin)
+	if !ok {
+		return
+	}
+
+	req, ok := decodeWorkspaceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := config.DB.Exec(
+		"UPDATE workspaces SET name = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+		req.Name,
+		membership.WorkspaceID,
+	); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update workspace", err)
+		return
+	}
+
+	workspace, err := getWorkspace(config.DB, membership)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch updated workspace", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Workspace updated successfully", workspace)
+}
+
+// DeleteWorkspace deletes a workspace with its tasks, projects, members and
+// invitations. Only the owner can delete it, and personal workspaces cannot
+// be deleted.
+func DeleteWorkspace(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	// Get workspace ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/workspaces/")
+	if len(segments) != 1 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Workspace ID is required", nil)
+		return
+	}
+
+	membership, ok := authorizeWorkspace(w, userID, segments[0], models.WorkspaceRoleOwner)
+	if !ok {
+		return
+	}
+
+	if membership.Personal {
+		config.WriteErrorResponse(w, http.StatusConflict, "Personal workspaces cannot be deleted", nil)
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete workspace", err)
+		return
+	}
+	defer tx.Rollback()
+
+	files, err := deleteWorkspace(tx, membership.WorkspaceID)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete workspace", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete workspace", err)
+		return
+	}
+
+	removeAttachmentFiles(files)
+
+	config.WriteSuccessResponse(w, "Workspace deleted successfully", nil)
+}
+
+// GetWorkspaceMembers lists the members of a workspace:
+// GET /api/v1/workspaces/{id}/members
+func GetWorkspaceMembers(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	// Get workspace ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/workspaces/")
+	if len(segments) != 2 || segments[1] != "members" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Workspace ID is required", nil)
+		return
+	}
+
+	membership, ok := authorizeWorkspace(w, userID, segments[0], models.WorkspaceRoleMember)
+	if !ok {
+		return
+	}
+
+	rows, err := config.DB.Query(
+		"SELECT users.id, users.name, users.email, m.role, m.created_at FROM workspace_members m"+
+			" JOIN users ON users.id = m.user_id WHERE m.workspace_id = ?"+
+			" ORDER BY CASE m.role WHEN 'owner' THEN 0 WHEN 'admin' THEN 1 ELSE 2 END, users.name COLLATE NOCASE, users.id",
+		membership.WorkspaceID,
+	)
+	if err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch members", err)
+		return
+	}
+
+	defer rows.Close()
+
+	members := []models.WorkspaceMemberResponse{}
+	for rows.Next() {
+		var member models.WorkspaceMemberResponse
+		if err := rows.Scan(&member.UserID, &member.Name, &member.Email, &member.Role, &member.JoinedAt); err != nil {
+			config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to scan member", err)
+			return
+		}
+		members = append(members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch members", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Members retrieved successfully", members)
+}
+
+// UpdateWorkspaceMember changes the role of a member to admin or member:
+// PUT /api/v1/workspaces/{id}/members/{user_id}. Only the owner can change roles.
+func UpdateWorkspaceMember(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	// Get workspace ID and member ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/workspaces/")
+	if len(segments) != 3 || segments[1] != "members" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Workspace ID and user ID are required", nil)
+		return
+	}
+
+	membership, ok := authorizeWorkspace(w, userID, segments[0], models.WorkspaceRoleOwner)
+	if !ok {
+		return
+	}
+
+	// Check if request body is empty
+	if r.ContentLength == 0 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Request body is required", nil)
+		return
+	}
+
+	// Parse request body
+	var req models.WorkspaceMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	// Validate input
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Validation failed", err)
+		return
+	}
+
+	member, err := getWorkspaceMember(config.DB, membership.WorkspaceID, segments[2])
+	if err != nil {
+		if err == sql.ErrNoRows {
+			config.WriteErrorResponse(w, http.StatusNotFound, "Member not found", nil)
+			return
+		}
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch member", err)
+		return
+	}
+
+	if member.Role == models.WorkspaceRoleOwner {
+		config.WriteErrorResponse(w, http.StatusConflict, "The owner's role cannot be changed", nil)
+		return
+	}
+
+	if _, err := config.DB.Exec(
+		"UPDATE workspace_members SET role = ? WHERE workspace_id = ? AND user_id = ?",
+		req.Role,
+		membership.WorkspaceID,
+		member.UserID,
+	); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update member", err)
+		return
+	}
+
+	member.Role = req.Role
+	config.WriteSuccessResponse(w, "Member updated successfully", member)
+}
+
+// RemoveWorkspaceMember removes a member from a workspace:
+// DELETE /api/v1/workspaces/{id}/members/{user_id}. Members can remove
+// themselves, admins can remove members and the owner can remove anyone but
+// themselves. The tasks a member created stay in the workspace.
+func RemoveWorkspaceMember(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "User ID not found in context", fmt.Errorf("user id not found in context"))
+		return
+	}
+
+	// Get workspace ID and member ID from URL path
+	segments := pathSegments(r.URL.Path, "/api/v1/workspaces/")
+	if len(segments) != 3 || segments[1] != "members" {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Workspace ID and user ID are required", nil)
+		return
+	}
+
+	membership, ok := authorizeWorkspace(w, userID, segments[0], models.WorkspaceRoleMember)
+	if !ok {
+		return
+	}
+
+	member, err := getWorkspaceMember(config.DB, membership.WorkspaceID, segments[2])
+	if err != nil {
+		if err == sql.ErrNoRows {
+			config.WriteErrorResponse(w, http.StatusNotFound, "Member not found", nil)
+			return
+		}
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch member", err)
+		return
+	}
+
+	if member.Role == models.WorkspaceRoleOwner {
+		config.WriteErrorResponse(w, http.StatusConflict, "The owner cannot leave the workspace", nil)
+		return
+	}
+
+	// Removing someone else needs a higher role than theirs, and at least admin
+	if member.UserID != userID {
+		rank := workspaceRoleRanks[membership.Role]
+		if rank < workspaceRoleRanks[models.WorkspaceRoleAdmin] || rank <= workspaceRoleRanks[member.Role] {
+			config.WriteErrorResponse(w, http.StatusForbidden, "You cannot remove this member", nil)
+			return
+		}
+	}
+
+	if _, err := config.DB.Exec("DELETE FROM workspace_members WHERE workspace_id = ? AND user_id = ?", membership.WorkspaceID, member.UserID); err != nil {
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to remove member", err)
+		return
+	}
+
+	config.WriteSuccessResponse(w, "Member removed successfully", nil)
+}
+
+// authorizeWorkspace looks up the user's membership of a workspace and checks
+// that their role is at least minRole. It writes a 404 when the user is not a
+// member and a 403 when the role is not enough, and returns false in both cases.
+func authorizeWorkspace(w http.ResponseWriter, userID int, workspaceID string, minRole string) (models.WorkspaceMembership, bool) {
+	membership, err := middleware.LookupWorkspace(userID, workspaceID)
+	if err != nil {
+		if err == middleware.ErrWorkspaceNotFound || err == middleware.ErrInvalidWorkspace {
+			config.WriteErrorResponse(w, http.StatusNotFound, "Workspace not found", nil)
+			return membership, false
+		}
+		config.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch workspace", err)
+		return membership, false
+	}
+
+	if workspaceRoleRanks[membership.Role] < workspaceRoleRanks[minRole] {
+		config.WriteErrorResponse(w, http.StatusForbidden, "You need the "+minRole+" role in this workspace", nil)
+		return membership, false
+	}
+	return membership, true
+}
+
+// decodeWorkspaceRequest parses and validates a workspace request body,
+// writing the error response when it is invalid
+func decodeWorkspaceRequest(w http.ResponseWriter, r *http.Request) (models.WorkspaceRequest, bool) {
+	var req models.WorkspaceRequest
+
+	// Check if request body is empty
+	if r.ContentLength == 0 {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Request body is required", nil)
+		return req, false
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		config.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return req, false
+	}
+
+	// Validate input
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		config.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Validation failed", err)
+		return req, false
+	}
+
+	return req, true
+}
+
+// deleteWorkspace deletes a workspace with its tasks, projects, members and
+// invitations. It returns the attachment files of the tasks, to be removed
+// with removeAttachmentFiles once the transaction is committed.
+func deleteWorkspace(q querier, workspaceID int) ([]string, error) {
+	// Delete the tasks with everything attached to them
+	files, err := taskAttachmentFiles(q, "SELECT id FROM tasks WHERE workspace_id = ?", workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := deleteTasks(q, "SELECT id FROM tasks WHERE workspace_id = ?", workspaceID); err != nil {
+		return nil, err
+	}
+
+	statements := []string{
+		"DELETE FROM project_shares WHERE project_id IN (SELECT id FROM projects WHERE workspace_id = ?)",
+		"DELETE FROM projects WHERE workspace_id = ?",
+		"DELETE FROM workspace_invitations WHERE workspace_id = ?",
+		"DELETE FROM workspace_members WHERE workspace_id = ?",
+		"DELETE FROM workspaces WHERE id = ?",
+	}
+	for _, statement := range statements {
+		if _, err := q.Exec(statement, workspaceID); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// scanWorkspace scans a row selected with workspaceColumns
+func scanWorkspace(row rowScanner) (models.WorkspaceResponse, error) {
+	var workspace models.WorkspaceResponse
+	err := row.Scan(
+		&workspace.ID,
+		&workspace.Name,
+		&workspace.Personal,
+		&workspace.Role,
+		&workspace.MemberCount,
+		&workspace.CreatedAt,
+		&workspace.UpdatedAt,
+	)
+	return workspace, err
+}
+
+// getWorkspace fetches the workspace of a membership
+func getWorkspace(q querier, membership models.WorkspaceMembership) (models.WorkspaceResponse, error) {
+	return scanWorkspace(q.QueryRow(
+		"SELECT "+workspaceColumns+" FROM workspaces w JOIN workspace_members m ON m.workspace_id = w.id AND m.user_id = ? WHERE w.id = ?",
+		membership.UserID,
+		membership.WorkspaceID,
+	))
+}
+
+// getWorkspaceMember fetches a single member of a workspace
+func getWorkspaceMember(q querier, workspaceID int, userID any) (models.WorkspaceMemberResponse, error) {
+	var member models.WorkspaceMemberResponse
+	err := q.QueryRow(
+		"SELECT users.id, users.name, users.email, m.role, m.created_at FROM workspace_members m"+
+			" JOIN users ON users.id = m.user_id WHERE m.workspace_id = ? AND m.user_id = ?",
+		workspaceID,
+		userID,
+	).Scan(&member.UserID, &member.Name, &member.Email, &member.Role, &member.JoinedAt)
+	return member, err
+}
@@ -3,10 +3,12 @@ package main
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/eokwukwe/golearn/tasks/config"
 	"github.com/eokwukwe/golearn/tasks/handlers"
 	"github.com/eokwukwe/golearn/tasks/middleware"
+	"github.com/eokwukwe/golearn/tasks/models"
 )
 
 func main() {
@@ -15,14 +17,152 @@ func main() {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
+	// Give the admin role to the configured emails
+	if promoted, err := handlers.PromoteAdmins(config.AdminEmails); err != nil {
+		log.Fatalf("Failed to promote admins: %v", err)
+	} else if promoted > 0 {
+		log.Printf("Promoted %d users to admin", promoted)
+	}
+
+	// Periodically remove expired sessions, idempotency keys, login attempts, password resets and access tokens and empty the trash
+	go runPeriodically("expired sessions cleanup", time.Hour, handlers.CleanupExpiredSessions)
+	go runPeriodically("trash purge", time.Hour, handlers.PurgeExpiredTrash)
+	go runPeriodically("idempotency keys cleanup", time.Hour, middleware.PurgeExpiredIdempotencyKeys)
+	go runPeriodically("login attempts cleanup", time.Hour, handlers.PurgeStaleLoginAttempts)
+	go runPeriodically("password resets cleanup", time.Hour, handlers.PurgeExpiredPasswordResets)
+	go runPeriodically("access tokens cleanup", time.Hour, handlers.PurgeExpiredAccessTokens)
+
 	// Define routes
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(`{"status": "healthy"}`))
 	})
 
-	http.HandleFunc("/api/v1/register", handlers.Register)
+	http.HandleFunc("/api/v1/register", middleware.Idempotency(handlers.Register))
 	http.HandleFunc("/api/v1/login", handlers.Login)
+	http.HandleFunc("/api/v1/logout", middleware.AuthMiddleware(handlers.Logout))
+	http.HandleFunc("/api/v1/token/refresh", handlers.RefreshToken)
+	http.HandleFunc("/api/v1/password/forgot", middleware.Idempotency(handlers.ForgotPassword))
+	http.HandleFunc("/api/v1/password/reset", handlers.ResetPassword)
+	http.HandleFunc("/api/v1/email/verify", handlers.VerifyEmail)
+	http.HandleFunc("/api/v1/email/resend", handlers.ResendVerification)
+
+	// Handle the account of the authenticated user
+	http.HandleFunc("/api/v1/me", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetMe(w, r)
+		} else if r.Method == http.MethodPatch {
+			handlers.UpdateMe(w, r)
+		} else if r.Method == http.MethodDelete {
+			handlers.DeleteMe(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/api/v1/me/password", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handlers.ChangePassword(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle session listing and "log out everywhere"
+	http.HandleFunc("/api/v1/sessions", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetSessions(w, r)
+		} else if r.Method == http.MethodDelete {
+			handlers.DeleteAllSessions(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle revoking a single session
+	http.HandleFunc("/api/v1/sessions/{id}", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			handlers.DeleteSession(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle personal access tokens; they need a login and cannot manage themselves
+	http.HandleFunc("/api/v1/tokens", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetAccessTokens(w, r)
+		} else if r.Method == http.MethodPost {
+			handlers.CreateAccessToken(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/api/v1/tokens/{id}", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			handlers.DeleteAccessToken(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle the admin API; every route needs the admin role
+	http.HandleFunc("/api/v1/admin/users", middleware.AuthMiddleware(middleware.RequireRole(models.UserRoleAdmin, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetAdminUsers(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	http.HandleFunc("/api/v1/admin/users/{id}", middleware.AuthMiddleware(middleware.RequireRole(models.UserRoleAdmin, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetAdminUser(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	http.HandleFunc("/api/v1/admin/users/{id}/disable", middleware.AuthMiddleware(middleware.RequireRole(models.UserRoleAdmin, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handlers.DisableUser(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	http.HandleFunc("/api/v1/admin/users/{id}/enable", middleware.AuthMiddleware(middleware.RequireRole(models.UserRoleAdmin, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handlers.EnableUser(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	http.HandleFunc("/api/v1/admin/users/{id}/logout", middleware.AuthMiddleware(middleware.RequireRole(models.UserRoleAdmin, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handlers.LogoutUser(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	http.HandleFunc("/api/v1/admin/stats", middleware.AuthMiddleware(middleware.RequireRole(models.UserRoleAdmin, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetAdminStats(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	http.HandleFunc("/api/v1/admin/audit", middleware.AuthMiddleware(middleware.RequireRole(models.UserRoleAdmin, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetAdminAudit(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
 
 	// Handle GET and POST requests for tasks separately
 	http.HandleFunc("/api/v1/tasks", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
@@ -35,6 +175,24 @@ func main() {
 		}
 	}))
 
+	// Handle full-text search over tasks
+	http.HandleFunc("/api/v1/tasks/search", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.SearchTasks(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle bulk task operations
+	http.HandleFunc("/api/v1/tasks/batch", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handlers.BatchTasks(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
 	// Handle GET request for a single task
 	http.HandleFunc("/api/v1/tasks/{id}", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodGet {
@@ -43,16 +201,375 @@ func main() {
 			handlers.DeleteTask(w, r)
 		} else if r.Method == http.MethodPut {
 			handlers.UpdateTask(w, r)
+		} else if r.Method == http.MethodPatch && r.ContentLength == 0 {
+			// PATCH without a body completes the task, as it always has
+			handlers.CompleteTask(w, r)
 		} else if r.Method == http.MethodPatch {
+			handlers.PatchTask(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle completing and reopening a task
+	http.HandleFunc("/api/v1/tasks/{id}/complete", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
 			handlers.CompleteTask(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}))
 
+	http.HandleFunc("/api/v1/tasks/{id}/reopen", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handlers.ReopenTask(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle attaching and detaching a single label
+	http.HandleFunc("/api/v1/tasks/{id}/labels/{label_id}", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handlers.AttachTaskLabel(w, r)
+		} else if r.Method == http.MethodDelete {
+			handlers.DetachTaskLabel(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle listing and adding task dependencies
+	http.HandleFunc("/api/v1/tasks/{id}/dependencies", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetTaskDependencies(w, r)
+		} else if r.Method == http.MethodPost {
+			handlers.AddTaskDependency(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle removing a task dependency
+	http.HandleFunc("/api/v1/tasks/{id}/dependencies/{blocked_by_id}", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			handlers.RemoveTaskDependency(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle GET request for a task with all of its subtasks
+	http.HandleFunc("/api/v1/tasks/{id}/subtree", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetTaskSubtree(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle previewing the next occurrences of a recurring task
+	http.HandleFunc("/api/v1/tasks/{id}/occurrences", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetTaskOccurrences(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle listing and creating task shares
+	http.HandleFunc("/api/v1/tasks/{id}/shares", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetTaskShares(w, r)
+		} else if r.Method == http.MethodPost {
+			handlers.ShareTask(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle revoking a task share
+	http.HandleFunc("/api/v1/tasks/{id}/shares/{user_id}", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			handlers.RevokeTaskShare(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle task comments
+	http.HandleFunc("/api/v1/tasks/{id}/comments", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetTaskComments(w, r)
+		} else if r.Method == http.MethodPost {
+			handlers.CreateTaskComment(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/api/v1/tasks/{id}/comments/{comment_id}", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			handlers.UpdateTaskComment(w, r)
+		} else if r.Method == http.MethodDelete {
+			handlers.DeleteTaskComment(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle task attachments
+	http.HandleFunc("/api/v1/tasks/{id}/attachments", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetTaskAttachments(w, r)
+		} else if r.Method == http.MethodPost {
+			handlers.UploadTaskAttachment(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/api/v1/tasks/{id}/attachments/{attachment_id}", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.DownloadTaskAttachment(w, r)
+		} else if r.Method == http.MethodDelete {
+			handlers.DeleteTaskAttachment(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle task history and the activity feed
+	http.HandleFunc("/api/v1/tasks/{id}/history", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetTaskHistory(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/api/v1/activity", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetActivity(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle the trash and restoring tasks from it
+	http.HandleFunc("/api/v1/trash", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetTrash(w, r)
+		} else if r.Method == http.MethodDelete {
+			handlers.EmptyTrash(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/api/v1/trash/{id}", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			handlers.PurgeTask(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/api/v1/tasks/{id}/restore", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handlers.RestoreTask(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle GET and POST requests for labels
+	http.HandleFunc("/api/v1/labels", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetLabels(w, r)
+		} else if r.Method == http.MethodPost {
+			handlers.CreateLabel(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle requests for a single label
+	http.HandleFunc("/api/v1/labels/{id}", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetOneLabel(w, r)
+		} else if r.Method == http.MethodPut {
+			handlers.UpdateLabel(w, r)
+		} else if r.Method == http.MethodDelete {
+			handlers.DeleteLabel(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle GET and POST requests for projects
+	http.HandleFunc("/api/v1/projects", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetProjects(w, r)
+		} else if r.Method == http.MethodPost {
+			handlers.CreateProject(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle requests for a single project
+	http.HandleFunc("/api/v1/projects/{id}", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetOneProject(w, r)
+		} else if r.Method == http.MethodPut {
+			handlers.UpdateProject(w, r)
+		} else if r.Method == http.MethodDelete {
+			handlers.DeleteProject(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle listing the tasks of a project
+	http.HandleFunc("/api/v1/projects/{id}/tasks", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetProjectTasks(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle listing and creating project shares
+	http.HandleFunc("/api/v1/projects/{id}/shares", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetProjectShares(w, r)
+		} else if r.Method == http.MethodPost {
+			handlers.ShareProject(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle revoking a project share
+	http.HandleFunc("/api/v1/projects/{id}/shares/{user_id}", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			handlers.RevokeProjectShare(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle listing tasks and projects shared with the user
+	http.HandleFunc("/api/v1/shared/tasks", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetSharedTasks(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/api/v1/shared/projects", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetSharedProjects(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle workspace listing and creation
+	http.HandleFunc("/api/v1/workspaces", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetWorkspaces(w, r)
+		} else if r.Method == http.MethodPost {
+			handlers.CreateWorkspace(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle single workspace operations
+	http.HandleFunc("/api/v1/workspaces/{id}", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetOneWorkspace(w, r)
+		} else if r.Method == http.MethodPut {
+			handlers.UpdateWorkspace(w, r)
+		} else if r.Method == http.MethodDelete {
+			handlers.DeleteWorkspace(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle workspace members
+	http.HandleFunc("/api/v1/workspaces/{id}/members", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetWorkspaceMembers(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/api/v1/workspaces/{id}/members/{user_id}", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			handlers.UpdateWorkspaceMember(w, r)
+		} else if r.Method == http.MethodDelete {
+			handlers.RemoveWorkspaceMember(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Handle workspace invitations
+	http.HandleFunc("/api/v1/workspaces/{id}/invitations", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetInvitations(w, r)
+		} else if r.Method == http.MethodPost {
+			handlers.CreateInvitation(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/api/v1/workspaces/{id}/invitations/{invitation_id}", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			handlers.RevokeInvitation(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/api/v1/invitations/accept", middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handlers.AcceptInvitation(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Serve every other route inside a workspace, e.g. /api/v1/workspaces/3/tasks
+	http.HandleFunc("/api/v1/workspaces/{workspace_id}/", middleware.WorkspacePrefix(http.DefaultServeMux))
+
 	// Start server
 	log.Printf("Starting server on :7070")
-	if err := http.ListenAndServe(":7070", nil); err != nil {
+	if err := http.ListenAndServe(":7070", middleware.RateLimit(http.DefaultServeMux)); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// runPeriodically calls job every interval and logs how many rows it removed
+func runPeriodically(name string, interval time.Duration, job func() (int64, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		removed, err := job()
+		if err != nil {
+			log.Printf("%s failed: %v", name, err)
+			continue
+		}
+		if removed > 0 {
+			log.Printf("%s removed %d rows", name, removed)
+		}
+	}
+}
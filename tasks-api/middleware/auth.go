@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,8 +15,13 @@ import (
 type contextKey string
 
 const (
-	ContextUserIDKey contextKey = "user_id"
-	ContextUserKey   contextKey = "user"
+	ContextUserIDKey    contextKey = "user_id"
+	ContextUserKey      contextKey = "user"
+	ContextSessionIDKey contextKey = "session_id"
+	ContextWorkspaceKey contextKey = "workspace"
+	// ContextAccessTokenIDKey is set instead of ContextSessionIDKey for
+	// requests made with a personal access token
+	ContextAccessTokenIDKey contextKey = "access_token_id"
 )
 
 // AuthMiddleware checks for valid token and adds user to context
@@ -39,43 +45,184 @@ func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		// Get user ID and expires_at from sessions table
-		var userID int
+		// Get session ID, user ID and expires_at from sessions table, or the
+		// user ID from personal_access_tokens for tokens with its prefix
+		var sessionID, accessTokenID, userID int
 		var expiresAt time.Time
 		var err error
-		if err = config.DB.QueryRow("SELECT user_id, expires_at FROM sessions WHERE token = ?", token).Scan(&userID, &expiresAt); err != nil {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(models.NewErrorResponse("Invalid token", nil))
-			return
+		if strings.HasPrefix(token, models.AccessTokenPrefix) {
+			// Only the hash of personal access tokens is stored
+			var scopes string
+			var tokenExpiresAt *time.Time
+			if err = config.DB.QueryRow(
+				"SELECT id, user_id, scopes, expires_at FROM personal_access_tokens WHERE token_hash = ?",
+				config.HashToken(token),
+			).Scan(&accessTokenID, &userID, &scopes, &tokenExpiresAt); err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(models.NewErrorResponse("Invalid token", nil))
+				return
+			}
+
+			// Check if token has expired; expired tokens are removed by a background job
+			if tokenExpiresAt != nil && tokenExpiresAt.Before(time.Now()) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(models.NewErrorResponse("Token has expired", nil))
+				return
+			}
+
+			// Check that the scopes of the token cover the route
+			required := requiredScope(r)
+			if required == "" {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(models.NewErrorResponse("Personal access tokens cannot be used for this route", nil))
+				return
+			}
+			if !hasScope(strings.Fields(scopes), required) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(models.NewErrorResponse("Token is missing the "+required+" scope", nil))
+				return
+			}
+		} else {
+			if err = config.DB.QueryRow("SELECT id, user_id, expires_at FROM sessions WHERE token = ?", token).Scan(&sessionID, &userID, &expiresAt); err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(models.NewErrorResponse("Invalid token", nil))
+				return
+			}
+
+			// Check if token has expired and remove the stale session
+			if expiresAt.Before(time.Now()) {
+				config.DB.Exec("DELETE FROM sessions WHERE id = ?", sessionID)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(models.NewErrorResponse("Token has expired", nil))
+				return
+			}
 		}
 
-		// Check if token has expired
-		if expiresAt.Before(time.Now()) {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(models.NewErrorResponse("Token has expired", nil))
+		// Limit the requests of each user to the route
+		if !allowRequest(w, "user:"+strconv.Itoa(userID), r.Pattern) {
 			return
 		}
 
 		// Get user from database
 		var user models.User
-		if err = config.DB.QueryRow("SELECT id, email, created_at FROM users WHERE id = ?", userID).Scan(&user.ID, &user.Email, &user.CreatedAt); err != nil {
+		if err = config.DB.QueryRow("SELECT id, name, email, timezone, created_at, email_verified_at, role, disabled_at FROM users WHERE id = ?", userID).Scan(
+			&user.ID,
+			&user.Name,
+			&user.Email,
+			&user.Timezone,
+			&user.CreatedAt,
+			&user.EmailVerifiedAt,
+			&user.Role,
+			&user.DisabledAt,
+		); err != nil {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusUnauthorized)
 			json.NewEncoder(w).Encode(models.NewErrorResponse("User not found", nil))
 			return
 		}
 
-		// Add user and user ID to context
+		// Reject users an admin has disabled
+		if user.DisabledAt != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(models.NewErrorResponse("Account is disabled", nil))
+			return
+		}
+
+		// Hold back users who have not verified their email yet
+		if user.EmailVerifiedAt == nil && !unverifiedUserAllowed(r) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(models.NewErrorResponse("Email address is not verified", nil))
+			return
+		}
+
+		// Resolve the workspace the request acts in
+		membership, err := LookupWorkspace(userID, r.Header.Get(WorkspaceHeader))
+		if err != nil {
+			status, message := http.StatusInternalServerError, "Failed to load workspace"
+			if err == ErrInvalidWorkspace {
+				status, message = http.StatusBadRequest, "Invalid workspace ID"
+			} else if err == ErrWorkspaceNotFound {
+				status, message = http.StatusNotFound, "Workspace not found"
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(models.NewErrorResponse(message, nil))
+			return
+		}
+
+		// Record when the personal access token was last used; its expiry is fixed
+		now := time.Now().UTC()
+		if accessTokenID != 0 {
+			if _, err = config.DB.Exec("UPDATE personal_access_tokens SET last_used_at = ? WHERE id = ?", now, accessTokenID); err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(models.NewErrorResponse("Failed to update token", nil))
+				return
+			}
+		} else {
+			// Record when the session was last used and slide its expiry forward
+			if slidingExpiry := now.Add(config.AccessTokenDuration); slidingExpiry.After(expiresAt) {
+				expiresAt = slidingExpiry
+			}
+			if _, err = config.DB.Exec("UPDATE sessions SET last_used_at = ?, expires_at = ? WHERE id = ?", now, expiresAt, sessionID); err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(models.NewErrorResponse("Failed to update session", nil))
+				return
+			}
+		}
+
+		// Add user, user ID, session or access token ID and workspace
+		// membership to context
 		ctx := r.Context()
 		ctx = context.WithValue(ctx, ContextUserIDKey, userID)
 		ctx = context.WithValue(ctx, ContextUserKey, user)
+		if accessTokenID != 0 {
+			ctx = context.WithValue(ctx, ContextAccessTokenIDKey, accessTokenID)
+		} else {
+			ctx = context.WithValue(ctx, ContextSessionIDKey, sessionID)
+		}
+		ctx = context.WithValue(ctx, ContextWorkspaceKey, membership)
 
-		// Call next handler with updated context
+		// Call next handler with updated context, replaying the response to
+		// requests repeated with the same Idempotency-Key
 		r = r.WithContext(ctx)
-		next.ServeHTTP(w, r)
+		Idempotency(next).ServeHTTP(w, r)
+	}
+}
+
+// unverifiedRoutes are the routes unverified users can always use, so they
+// can log out and fix or delete their account under any policy
+var unverifiedRoutes = map[string]bool{
+	"/api/v1/logout":        true,
+	"/api/v1/sessions":      true,
+	"/api/v1/sessions/{id}": true,
+	"/api/v1/me":            true,
+	"/api/v1/me/password":   true,
+}
+
+// unverifiedUserAllowed reports whether config.UnverifiedUsers lets a user
+// who has not verified their email make the request
+func unverifiedUserAllowed(r *http.Request) bool {
+	if unverifiedRoutes[r.Pattern] {
+		return true
+	}
+
+	switch config.UnverifiedUsers {
+	case config.UnverifiedAllow:
+		return true
+	case config.UnverifiedReadOnly:
+		return isSafeMethod(r.Method)
 	}
+	return false
 }
 
 // GetUserIDFromContext retrieves user ID from request context
@@ -84,8 +231,27 @@ func GetUserIDFromContext(r *http.Request) (int, bool) {
 	return userID, ok
 }
 
-// GetUserFromContext retrieves user from request context
-func GetUserFromContext(r *http.Request) (*models.User, bool) {
-	user, ok := r.Context().Value(ContextUserKey).(*models.User)
+// GetSessionIDFromContext retrieves the current session ID from request context
+func GetSessionIDFromContext(r *http.Request) (int, bool) {
+	sessionID, ok := r.Context().Value(ContextSessionIDKey).(int)
+	return sessionID, ok
+}
+
+// GetAccessTokenIDFromContext retrieves the ID of the personal access token
+// used to authenticate the request from context
+func GetAccessTokenIDFromContext(r *http.Request) (int, bool) {
+	accessTokenID, ok := r.Context().Value(ContextAccessTokenIDKey).(int)
+	return accessTokenID, ok
+}
+
+// GetWorkspaceFromContext retrieves the workspace membership of the request from context
+func GetWorkspaceFromContext(r *http.Request) (models.WorkspaceMembership, bool) {
+	membership, ok := r.Context().Value(ContextWorkspaceKey).(models.WorkspaceMembership)
+	return membership, ok
+}
+
+// GetUserFromContext retrieves the authenticated user from request context
+func GetUserFromContext(r *http.Request) (models.User, bool) {
+	user, ok := r.Context().Value(ContextUserKey).(models.User)
 	return user, ok
 }
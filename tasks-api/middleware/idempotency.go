@@ -0,0 +1,248 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/models"
+)
+
+// IdempotencyKeyHeader lets clients retry unsafe requests without repeating
+// their effects
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength is the longest Idempotency-Key accepted
+const maxIdempotencyKeyLength = 255
+
+// secretResponseRoutes are the route patterns whose responses hold a secret
+// that is otherwise only stored hashed: new personal access tokens, the
+// session tokens issued by a password change and invitation tokens. Storing
+// their responses would keep the secret in plain text, so Idempotency-Key is
+// ignored on them.
+var secretResponseRoutes = map[string]bool{
+	"/api/v1/tokens":                      true,
+	"/api/v1/me/password":                 true,
+	"/api/v1/workspaces/{id}/invitations": true,
+}
+
+// Idempotency honours the Idempotency-Key header on unsafe requests. The
+// first request with a key runs next and its response is stored with a hash
+// of the request; repeating the request with the same key replays the stored
+// response instead of running next again. Keys are scoped to the user, or to
+// the client IP address on routes without authentication, and expire after
+// config.IdempotencyKeyTTL. Responses with a 5xx status are not stored so the
+// request can be retried, and routes in secretResponseRoutes are never
+// recorded.
+func Idempotency(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || isSafeMethod(r.Method) || secretResponseRoutes[r.Pattern] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			writeJSONError(w, http.StatusBadRequest, "Idempotency-Key must be at most "+strconv.Itoa(maxIdempotencyKeyLength)+" characters")
+			return
+		}
+
+		// Read the body so it can be hashed and still passed on, up to a limit
+		// since it is held in memory before the handler checks its own
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, config.MaxIdempotentRequestSize))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				writeJSONError(w, http.StatusRequestEntityTooLarge, "Requests with an Idempotency-Key can be at most "+strconv.FormatInt(config.MaxIdempotentRequestSize, 10)+" bytes")
+				return
+			}
+			writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := "ip:" + ClientIP(r)
+		if userID, ok := GetUserIDFromContext(r); ok {
+			scope = "user:" + strconv.Itoa(userID)
+		}
+		hash := requestHash(r, body)
+
+		// Reserve the key; only one request can insert it
+		reserved, err := reserveIdempotencyKey(scope, key, hash)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to check Idempotency-Key")
+			return
+		}
+
+		if !reserved {
+			replayIdempotentResponse(w, scope, key, hash)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		// Let the client retry requests that failed on the server
+		if recorder.status >= http.StatusInternalServerError {
+			releaseIdempotencyKey(scope, key)
+			return
+		}
+
+		// A key left without a response would answer every retry with 409
+		// until it expires, so it is released when the response cannot be stored
+		headers, err := json.Marshal(w.Header())
+		if err == nil {
+			_, err = config.DB.Exec(
+				"UPDATE idempotency_keys SET status = ?, headers = ?, body = ? WHERE scope = ? AND idempotency_key = ?",
+				recorder.status,
+				string(headers),
+				recorder.body.Bytes(),
+				scope,
+				key,
+			)
+		}
+		if err != nil {
+			log.Printf("Failed to store the response for Idempotency-Key %q of %s: %v", key, scope, err)
+			releaseIdempotencyKey(scope, key)
+		}
+	}
+}
+
+// PurgeExpiredIdempotencyKeys deletes the stored responses whose keys have expired
+func PurgeExpiredIdempotencyKeys() (int64, error) {
+	result, err := config.DB.Exec("DELETE FROM idempotency_keys WHERE expires_at <= ?", time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// reserveIdempotencyKey stores a key without a response yet. It returns false
+// when the key is already in use; an expired key is replaced.
+func reserveIdempotencyKey(scope, key, hash string) (bool, error) {
+	now := time.Now().UTC()
+	if _, err := config.DB.Exec("DELETE FROM idempotency_keys WHERE scope = ? AND idempotency_key = ? AND expires_at <= ?", scope, key, now); err != nil {
+		return false, err
+	}
+
+	result, err := config.DB.Exec(
+		"INSERT INTO idempotency_keys (scope, idempotency_key, request_hash, created_at, expires_at) VALUES (?, ?, ?, ?, ?) ON CONFLICT (scope, idempotency_key) DO NOTHING",
+		scope,
+		key,
+		hash,
+		now,
+		now.Add(config.IdempotencyKeyTTL),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// releaseIdempotencyKey deletes a reserved key so the request can be retried
+func releaseIdempotencyKey(scope, key string) {
+	if _, err := config.DB.Exec("DELETE FROM idempotency_keys WHERE scope = ? AND idempotency_key = ?", scope, key); err != nil {
+		log.Printf("Failed to release Idempotency-Key %q of %s: %v", key, scope, err)
+	}
+}
+
+// replayIdempotentResponse writes the response stored for a key that is
+// already in use
+func replayIdempotentResponse(w http.ResponseWriter, scope, key, hash string) {
+	var storedHash string
+	var status sql.NullInt64
+	var headers sql.NullString
+	var body []byte
+	err := config.DB.QueryRow(
+		"SELECT request_hash, status, headers, body FROM idempotency_keys WHERE scope = ? AND idempotency_key = ?",
+		scope,
+		key,
+	).Scan(&storedHash, &status, &headers, &body)
+	if err == sql.ErrNoRows {
+		// The first request failed and released the key in the meantime
+		w.Header().Set("Retry-After", "1")
+		writeJSONError(w, http.StatusConflict, "A request with this Idempotency-Key is being processed")
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to check Idempotency-Key")
+		return
+	}
+
+	if storedHash != hash {
+		writeJSONError(w, http.StatusUnprocessableEntity, "Idempotency-Key has already been used for a different request")
+		return
+	}
+
+	// The first request has not finished yet
+	if !status.Valid {
+		w.Header().Set("Retry-After", "1")
+		writeJSONError(w, http.StatusConflict, "A request with this Idempotency-Key is being processed")
+		return
+	}
+
+	var stored http.Header
+	if headers.Valid {
+		json.Unmarshal([]byte(headers.String), &stored)
+	}
+	for name, values := range stored {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(int(status.Int64))
+	w.Write(body)
+}
+
+// requestHash identifies a request by its method, path, workspace and body
+func requestHash(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.Path+"\n"+r.Header.Get(WorkspaceHeader)+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// isSafeMethod reports whether a method is not meant to change anything
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions || method == http.MethodTrace
+}
+
+// writeJSONError writes an error response in the format of the handlers
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.NewErrorResponse(message, nil))
+}
+
+// responseRecorder passes a response through while keeping a copy of its
+// status and body
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
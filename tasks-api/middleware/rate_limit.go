@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+)
+
+// tokenBucket holds the requests a client has left on a route
+type tokenBucket struct {
+	limit   config.RateLimit
+	tokens  float64
+	updated time.Time
+}
+
+// refill adds the requests that have come back since the bucket was last used
+func (b *tokenBucket) refill(now time.Time) {
+	rate := float64(b.limit.Requests) / b.limit.Window.Seconds()
+	b.tokens = math.Min(float64(b.limit.Requests), b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+}
+
+// rateLimiter keeps a token bucket per client and route
+type rateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+var limiter = &rateLimiter{buckets: map[string]*tokenBucket{}}
+
+// allow takes a request from the bucket of key. It returns whether the
+// request is allowed, how many requests are left, how long until the next
+// request is allowed and how long until the bucket is full again.
+func (l *rateLimiter) allow(key string, limit config.RateLimit, now time.Time) (bool, int, time.Duration, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Forget the buckets that have filled up again now and then
+	if now.Sub(l.lastSweep) > time.Minute {
+		for bucketKey, bucket := range l.buckets {
+			bucket.refill(now)
+			if bucket.tokens >= float64(bucket.limit.Requests) {
+				delete(l.buckets, bucketKey)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok || bucket.limit != limit {
+		bucket = &tokenBucket{limit: limit, tokens: float64(limit.Requests), updated: now}
+		l.buckets[key] = bucket
+	}
+	bucket.refill(now)
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+
+	rate := float64(limit.Requests) / limit.Window.Seconds()
+	retryAfter := time.Duration(math.Max(0, 1-bucket.tokens) / rate * float64(time.Second))
+	reset := time.Duration((float64(limit.Requests) - bucket.tokens) / rate * float64(time.Second))
+	return allowed, int(bucket.tokens), retryAfter, reset
+}
+
+// RateLimit limits the requests each client IP address makes to every route
+// of mux, using the limits in config.RouteRateLimits by route pattern.
+// AuthMiddleware applies the same limits per authenticated user.
+func RateLimit(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		if !allowRequest(w, "ip:"+ClientIP(r), pattern) {
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// allowRequest takes a request of client from the bucket of the route with
+// the given pattern and sets the RateLimit headers. Over the limit it writes
+// a 429 with Retry-After and returns false.
+func allowRequest(w http.ResponseWriter, client, pattern string) bool {
+	limit := config.RouteRateLimit(pattern)
+	if limit.Requests <= 0 {
+		return true
+	}
+
+	allowed, remaining, retryAfter, reset := limiter.allow(client+" "+pattern, limit, time.Now())
+
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+	w.Header().Set("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+strconv.Itoa(ceilSeconds(limit.Window)))
+	if allowed {
+		return true
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+	writeJSONError(w, http.StatusTooManyRequests, "Too many requests")
+	return false
+}
+
+// ceilSeconds rounds a duration up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/eokwukwe/golearn/tasks/models"
+)
+
+// userRoleRanks orders the user roles so a role includes the ones below it
+var userRoleRanks = map[string]int{
+	models.UserRoleUser:  1,
+	models.UserRoleAdmin: 2,
+}
+
+// RequireRole lets only users with at least the given role through to next.
+// It goes inside AuthMiddleware, which puts the user in the context:
+//
+//	middleware.AuthMiddleware(middleware.RequireRole(models.UserRoleAdmin, handler))
+func RequireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := GetUserFromContext(r)
+		if !ok {
+			writeJSONError(w, http.StatusUnauthorized, "Authorization token required")
+			return
+		}
+
+		if userRoleRanks[user.Role] < userRoleRanks[role] {
+			writeJSONError(w, http.StatusForbidden, "You do not have permission to access this resource")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/eokwukwe/golearn/tasks/models"
+)
+
+// scopeResources maps route patterns, and the patterns below them, to the
+// resource whose scopes a personal access token needs to use them
+var scopeResources = []struct {
+	pattern  string
+	resource string
+}{
+	{"/api/v1/tasks", "tasks"},
+	{"/api/v1/activity", "tasks"},
+	{"/api/v1/trash", "tasks"},
+	{"/api/v1/shared/tasks", "tasks"},
+	{"/api/v1/projects", "projects"},
+	{"/api/v1/shared/projects", "projects"},
+	{"/api/v1/labels", "labels"},
+	{"/api/v1/workspaces", "workspaces"},
+	{"/api/v1/invitations", "workspaces"},
+}
+
+// requiredScope returns the scope a personal access token needs for the
+// request: the read scope of the route's resource for safe methods such as GET
+// and the write scope otherwise. It returns "" for routes tokens cannot use,
+// such as sessions, passwords, tokens themselves and the admin API.
+func requiredScope(r *http.Request) string {
+	action := "write"
+	if isSafeMethod(r.Method) {
+		action = "read"
+	}
+
+	// The profile can be read, but changing the account needs a login
+	if r.Pattern == "/api/v1/me" {
+		if action == "read" {
+			return models.ScopeProfileRead
+		}
+		return ""
+	}
+
+	for _, route := range scopeResources {
+		if r.Pattern == route.pattern || strings.HasPrefix(r.Pattern, route.pattern+"/") {
+			return route.resource + ":" + action
+		}
+	}
+	return ""
+}
+
+// hasScope reports whether scopes grant the required scope, counting write
+// scopes as including the read scope of the same resource
+func hasScope(scopes []string, required string) bool {
+	resource, action, _ := strings.Cut(required, ":")
+	for _, scope := range scopes {
+		if scope == required || (action == "read" && scope == resource+":write") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/eokwukwe/golearn/tasks/config"
+	"github.com/eokwukwe/golearn/tasks/models"
+)
+
+// WorkspaceHeader selects the workspace a request acts in. Requests without
+// it act in the user's personal workspace.
+const WorkspaceHeader = "X-Workspace-ID"
+
+var (
+	// ErrInvalidWorkspace is returned for workspace IDs that are not numbers
+	ErrInvalidWorkspace = errors.New("invalid workspace id")
+	// ErrWorkspaceNotFound is returned when the workspace does not exist or the
+	// user is not one of its members
+	ErrWorkspaceNotFound = errors.New("workspace not found")
+)
+
+// LookupWorkspace returns the user's membership of the workspace with the
+// given ID, or of their personal workspace when workspaceID is empty
+func LookupWorkspace(userID int, workspaceID string) (models.WorkspaceMembership, error) {
+	membership := models.WorkspaceMembership{UserID: userID}
+
+	query := "SELECT w.id, m.role, w.personal_user_id IS NOT NULL FROM workspaces w" +
+		" JOIN workspace_members m ON m.workspace_id = w.id AND m.user_id = ?"
+	args := []any{userID}
+	if workspaceID == "" {
+		query += " WHERE w.personal_user_id = ?"
+		args = append(args, userID)
+	} else {
+		id, err := strconv.Atoi(workspaceID)
+		if err != nil || id < 1 {
+			return membership, ErrInvalidWorkspace
+		}
+		query += " WHERE w.id = ?"
+		args = append(args, id)
+	}
+
+	err := config.DB.QueryRow(query, args...).Scan(&membership.WorkspaceID, &membership.Role, &membership.Personal)
+	if err == sql.ErrNoRows {
+		return membership, ErrWorkspaceNotFound
+	}
+	return membership, err
+}
+
+// WorkspacePrefix serves requests under /api/v1/workspaces/{workspace_id}/ by
+// selecting that workspace and passing the rest of the path to mux, so
+// /api/v1/workspaces/3/tasks is handled as /api/v1/tasks in workspace 3.
+// RateLimit only sees the prefix route, so the IP limit of the route the
+// request ends up on is applied here; otherwise /api/v1/workspaces/3/login
+// would escape the limit of /api/v1/login.
+func WorkspacePrefix(mux *http.ServeMux) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		workspaceID := r.PathValue("workspace_id")
+		rest := strings.TrimPrefix(r.URL.Path, "/api/v1/workspaces/"+workspaceID)
+		if rest == "/" || strings.HasPrefix(rest, "/workspaces") {
+			http.NotFound(w, r)
+			return
+		}
+
+		scoped := r.Clone(r.Context())
+		scoped.URL.Path = "/api/v1" + rest
+		scoped.URL.RawPath = ""
+		scoped.Header.Set(WorkspaceHeader, workspaceID)
+
+		_, pattern := mux.Handler(scoped)
+		if !allowRequest(w, "ip:"+ClientIP(r), pattern) {
+			return
+		}
+		mux.ServeHTTP(w, scoped)
+	}
+}
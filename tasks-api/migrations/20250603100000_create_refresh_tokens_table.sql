@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    family_id TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME,
+    revoked_at DATETIME,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+
+ALTER TABLE sessions ADD COLUMN family_id TEXT NOT NULL DEFAULT '';
+CREATE INDEX idx_sessions_family_id ON sessions(family_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_sessions_family_id;
+ALTER TABLE sessions DROP COLUMN family_id;
+DROP INDEX idx_refresh_tokens_user_id;
+DROP INDEX idx_refresh_tokens_family_id;
+DROP TABLE IF EXISTS refresh_tokens;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX idx_tasks_user_created_at ON tasks(user_id, created_at, id);
+CREATE INDEX idx_tasks_user_updated_at ON tasks(user_id, updated_at, id);
+CREATE INDEX idx_tasks_user_title ON tasks(user_id, title COLLATE NOCASE, id);
+CREATE INDEX idx_tasks_user_completed ON tasks(user_id, completed, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_tasks_user_completed;
+DROP INDEX idx_tasks_user_title;
+DROP INDEX idx_tasks_user_updated_at;
+DROP INDEX idx_tasks_user_created_at;
+-- +goose StatementEnd
@@ -0,0 +1,101 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upCreateTasksFTS, downCreateTasksFTS)
+}
+
+// upCreateTasksFTS creates the tasks_fts full-text index over task titles and
+// descriptions and the triggers that keep it in sync with the tasks table.
+//
+// go-sqlite3 only compiles FTS5 in when built with the sqlite_fts5 tag, so the
+// index falls back to FTS4 on default builds. The search handler checks which
+// module the table uses.
+func upCreateTasksFTS(ctx context.Context, tx *sql.Tx) error {
+	var fts5 bool
+	if err := tx.QueryRowContext(ctx, "SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5); err != nil {
+		return err
+	}
+
+	statements := tasksFTS4Statements
+	if fts5 {
+		statements = tasksFTS5Statements
+	}
+
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	// Index the tasks that already exist
+	_, err := tx.ExecContext(ctx, "INSERT INTO tasks_fts(tasks_fts) VALUES ('rebuild')")
+	return err
+}
+
+func downCreateTasksFTS(ctx context.Context, tx *sql.Tx) error {
+	statements := []string{
+		"DROP TRIGGER IF EXISTS tasks_fts_after_insert",
+		"DROP TRIGGER IF EXISTS tasks_fts_before_update",
+		"DROP TRIGGER IF EXISTS tasks_fts_after_update",
+		"DROP TRIGGER IF EXISTS tasks_fts_before_delete",
+		"DROP TRIGGER IF EXISTS tasks_fts_after_delete",
+		"DROP TABLE IF EXISTS tasks_fts",
+	}
+
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var tasksFTS5Statements = []string{
+	`CREATE VIRTUAL TABLE tasks_fts USING fts5(
+		title,
+		description,
+		content = 'tasks',
+		content_rowid = 'id',
+		tokenize = 'unicode61 remove_diacritics 2',
+		prefix = '2 3'
+	)`,
+	`CREATE TRIGGER tasks_fts_after_insert AFTER INSERT ON tasks BEGIN
+		INSERT INTO tasks_fts (rowid, title, description) VALUES (new.id, new.title, new.description);
+	END`,
+	`CREATE TRIGGER tasks_fts_after_update AFTER UPDATE OF title, description ON tasks BEGIN
+		INSERT INTO tasks_fts (tasks_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+		INSERT INTO tasks_fts (rowid, title, description) VALUES (new.id, new.title, new.description);
+	END`,
+	`CREATE TRIGGER tasks_fts_after_delete AFTER DELETE ON tasks BEGIN
+		INSERT INTO tasks_fts (tasks_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+	END`,
+}
+
+var tasksFTS4Statements = []string{
+	`CREATE VIRTUAL TABLE tasks_fts USING fts4(
+		content="tasks",
+		title,
+		description,
+		tokenize=unicode61 "remove_diacritics=2",
+		prefix="2,3"
+	)`,
+	`CREATE TRIGGER tasks_fts_after_insert AFTER INSERT ON tasks BEGIN
+		INSERT INTO tasks_fts (docid, title, description) VALUES (new.id, new.title, new.description);
+	END`,
+	`CREATE TRIGGER tasks_fts_before_update BEFORE UPDATE OF title, description ON tasks BEGIN
+		DELETE FROM tasks_fts WHERE docid = old.id;
+	END`,
+	`CREATE TRIGGER tasks_fts_after_update AFTER UPDATE OF title, description ON tasks BEGIN
+		INSERT INTO tasks_fts (docid, title, description) VALUES (new.id, new.title, new.description);
+	END`,
+	`CREATE TRIGGER tasks_fts_before_delete BEFORE DELETE ON tasks BEGIN
+		DELETE FROM tasks_fts WHERE docid = old.id;
+	END`,
+}
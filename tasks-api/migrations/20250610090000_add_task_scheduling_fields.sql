@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE tasks ADD COLUMN due_at DATETIME;
+ALTER TABLE tasks ADD COLUMN priority TEXT NOT NULL DEFAULT 'medium' CHECK (priority IN ('low', 'medium', 'high', 'urgent'));
+ALTER TABLE tasks ADD COLUMN completed_at DATETIME;
+
+UPDATE tasks SET completed_at = updated_at WHERE completed = TRUE;
+
+CREATE INDEX idx_tasks_user_due_at ON tasks(user_id, due_at);
+CREATE INDEX idx_tasks_user_priority ON tasks(user_id, priority);
+
+ALTER TABLE users ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN timezone;
+
+DROP INDEX idx_tasks_user_priority;
+DROP INDEX idx_tasks_user_due_at;
+
+ALTER TABLE tasks DROP COLUMN completed_at;
+ALTER TABLE tasks DROP COLUMN priority;
+ALTER TABLE tasks DROP COLUMN due_at;
+-- +goose StatementEnd
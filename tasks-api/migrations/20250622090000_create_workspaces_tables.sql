@@ -0,0 +1,78 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS workspaces (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    personal_user_id INTEGER UNIQUE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (personal_user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+    workspace_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'admin', 'member')),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workspace_id, user_id),
+    FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_workspace_members_user_id ON workspace_members(user_id);
+
+CREATE TABLE IF NOT EXISTS workspace_invitations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_id INTEGER NOT NULL,
+    email TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('admin', 'member')),
+    token_hash TEXT NOT NULL UNIQUE,
+    invited_by INTEGER NOT NULL,
+    expires_at DATETIME NOT NULL,
+    accepted_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE,
+    FOREIGN KEY (invited_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_workspace_invitations_workspace_id ON workspace_invitations(workspace_id);
+
+-- Every user has a personal workspace, used when a request does not pick one
+INSERT INTO workspaces (name, personal_user_id) SELECT 'Personal', id FROM users;
+INSERT INTO workspace_members (workspace_id, user_id, role) SELECT id, personal_user_id, 'owner' FROM workspaces;
+
+CREATE TRIGGER users_personal_workspace AFTER INSERT ON users
+BEGIN
+    INSERT INTO workspaces (name, personal_user_id) VALUES ('Personal', NEW.id);
+    INSERT INTO workspace_members (workspace_id, user_id, role)
+        SELECT id, NEW.id, 'owner' FROM workspaces WHERE personal_user_id = NEW.id;
+END;
+
+-- Existing tasks and projects move to their owner's personal workspace
+ALTER TABLE tasks ADD COLUMN workspace_id INTEGER REFERENCES workspaces(id);
+UPDATE tasks SET workspace_id = (SELECT id FROM workspaces WHERE personal_user_id = tasks.user_id);
+
+CREATE INDEX idx_tasks_workspace_created_at ON tasks(workspace_id, created_at, id);
+
+ALTER TABLE projects ADD COLUMN workspace_id INTEGER REFERENCES workspaces(id);
+UPDATE projects SET workspace_id = (SELECT id FROM workspaces WHERE personal_user_id = projects.user_id);
+
+CREATE INDEX idx_projects_workspace_position ON projects(workspace_id, position);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_projects_workspace_position;
+ALTER TABLE projects DROP COLUMN workspace_id;
+
+DROP INDEX idx_tasks_workspace_created_at;
+ALTER TABLE tasks DROP COLUMN workspace_id;
+
+DROP TRIGGER IF EXISTS users_personal_workspace;
+
+DROP INDEX idx_workspace_invitations_workspace_id;
+DROP TABLE IF EXISTS workspace_invitations;
+DROP INDEX idx_workspace_members_user_id;
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
+-- +goose StatementEnd
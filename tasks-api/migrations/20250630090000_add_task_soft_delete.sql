@@ -0,0 +1,59 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE tasks ADD COLUMN deleted_at DATETIME;
+
+CREATE INDEX idx_tasks_deleted_at ON tasks(deleted_at);
+
+-- SQLite cannot change a CHECK constraint, so task_events is rebuilt to allow
+-- the restored action
+CREATE TABLE task_events_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER NOT NULL,
+    task_title TEXT NOT NULL,
+    workspace_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('created', 'updated', 'completed', 'deleted', 'restored')),
+    changes TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO task_events_new SELECT * FROM task_events;
+
+DROP TABLE task_events;
+
+ALTER TABLE task_events_new RENAME TO task_events;
+
+CREATE INDEX idx_task_events_task_id ON task_events(task_id, id);
+CREATE INDEX idx_task_events_user_id ON task_events(user_id, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE TABLE task_events_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER NOT NULL,
+    task_title TEXT NOT NULL,
+    workspace_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('created', 'updated', 'completed', 'deleted')),
+    changes TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO task_events_old SELECT * FROM task_events WHERE action != 'restored';
+
+DROP TABLE task_events;
+
+ALTER TABLE task_events_old RENAME TO task_events;
+
+CREATE INDEX idx_task_events_task_id ON task_events(task_id, id);
+CREATE INDEX idx_task_events_user_id ON task_events(user_id, id);
+
+-- Tasks still in the trash are deleted for good
+DELETE FROM tasks WHERE deleted_at IS NOT NULL;
+
+DROP INDEX idx_tasks_deleted_at;
+ALTER TABLE tasks DROP COLUMN deleted_at;
+-- +goose StatementEnd
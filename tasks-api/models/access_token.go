@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// AccessTokenPrefix starts every personal access token, so they can be told
+// apart from session tokens
+const AccessTokenPrefix = "tpat_"
+
+// Scopes of personal access tokens. A write scope includes the read scope of
+// the same resource.
+const (
+	ScopeTasksRead       = "tasks:read"
+	ScopeTasksWrite      = "tasks:write"
+	ScopeProjectsRead    = "projects:read"
+	ScopeProjectsWrite   = "projects:write"
+	ScopeLabelsRead      = "labels:read"
+	ScopeLabelsWrite     = "labels:write"
+	ScopeWorkspacesRead  = "workspaces:read"
+	ScopeWorkspacesWrite = "workspaces:write"
+	ScopeProfileRead     = "profile:read"
+)
+
+// AccessTokenResponse is a personal access token without its secret
+type AccessTokenResponse struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// CreatedAccessTokenResponse is a new personal access token. This is the only
+// time the token itself is shown.
+type CreatedAccessTokenResponse struct {
+	AccessTokenResponse
+	Token string `json:"token"`
+}
+
+// CreateAccessTokenRequest creates a personal access token. Tokens without
+// expires_at never expire.
+type CreateAccessTokenRequest struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,oneof=tasks:read tasks:write projects:read projects:write labels:read labels:write workspaces:read workspaces:write profile:read"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
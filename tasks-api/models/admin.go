@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// Roles of users on the instance, from least to most privileged
+const (
+	UserRoleUser  = "user"
+	UserRoleAdmin = "admin"
+)
+
+// Admin actions recorded in the audit log
+const (
+	AdminActionListUsers   = "users.list"
+	AdminActionViewUser    = "users.view"
+	AdminActionDisableUser = "users.disable"
+	AdminActionEnableUser  = "users.enable"
+	AdminActionLogoutUser  = "users.logout"
+	AdminActionViewStats   = "stats.view"
+	AdminActionViewAudit   = "audit.view"
+)
+
+// AdminUserResponse is a user as admins see it
+type AdminUserResponse struct {
+	ID             int        `json:"id"`
+	Name           string     `json:"name"`
+	Email          string     `json:"email"`
+	Role           string     `json:"role"`
+	EmailVerified  bool       `json:"email_verified"`
+	Timezone       string     `json:"timezone"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DisabledAt     *time.Time `json:"disabled_at"`
+	ActiveSessions int        `json:"active_sessions"`
+	LastActiveAt   *time.Time `json:"last_active_at"`
+}
+
+// DisableUserRequest optionally explains why an account is disabled
+type DisableUserRequest struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+// AdminStatsResponse counts what is stored on the instance
+type AdminStatsResponse struct {
+	Users struct {
+		Total      int `json:"total"`
+		Admins     int `json:"admins"`
+		Disabled   int `json:"disabled"`
+		Unverified int `json:"unverified"`
+	} `json:"users"`
+	ActiveSessions int `json:"active_sessions"`
+	Workspaces     int `json:"workspaces"`
+	Projects       int `json:"projects"`
+	Tasks          struct {
+		Total     int `json:"total"`
+		Completed int `json:"completed"`
+		Trashed   int `json:"trashed"`
+	} `json:"tasks"`
+	Comments        int   `json:"comments"`
+	Attachments     int   `json:"attachments"`
+	AttachmentBytes int64 `json:"attachment_bytes"`
+}
+
+// AdminAuditEntry is an action an admin took
+type AdminAuditEntry struct {
+	ID           int            `json:"id"`
+	AdminID      int            `json:"admin_id"`
+	AdminEmail   string         `json:"admin_email"`
+	Action       string         `json:"action"`
+	TargetUserID *int           `json:"target_user_id"`
+	Details      map[string]any `json:"details"`
+	IPAddress    string         `json:"ip_address"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
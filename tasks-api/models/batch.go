@@ -0,0 +1,50 @@
+package models
+
+// Batch modes: an atomic batch is rolled back as a whole when an operation
+// fails, a continue batch keeps the operations that succeeded
+const (
+	BatchModeAtomic   = "atomic"
+	BatchModeContinue = "continue"
+)
+
+// Operations a batch can run
+const (
+	BatchOpCreate   = "create"
+	BatchOpUpdate   = "update"
+	BatchOpComplete = "complete"
+	BatchOpReopen   = "reopen"
+	BatchOpDelete   = "delete"
+)
+
+type BatchRequest struct {
+	Mode       string           `json:"mode" validate:"omitempty,oneof=atomic continue"`
+	Operations []BatchOperation `json:"operations" validate:"required,min=1"`
+}
+
+// BatchOperation is a single operation of a batch. Task holds the fields of
+// create and update operations, which replace every field like PUT does.
+type BatchOperation struct {
+	Op   string       `json:"op"`
+	ID   int          `json:"id"`
+	Task *TaskRequest `json:"task"`
+}
+
+// BatchResult is the outcome of a batch operation. Status is the HTTP status
+// the single task endpoint would have answered with; failed operations carry
+// the same message and field errors as its error response.
+type BatchResult struct {
+	Index   int               `json:"index"`
+	Op      string            `json:"op"`
+	Status  int               `json:"status"`
+	Task    *TaskResponse     `json:"task,omitempty"`
+	Message string            `json:"message,omitempty"`
+	Errors  map[string]string `json:"errors,omitempty"`
+}
+
+type BatchResponse struct {
+	Mode      string        `json:"mode"`
+	Committed bool          `json:"committed"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []BatchResult `json:"results"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// CommentRequest creates or edits a comment. The body is Markdown, stored and
+// returned as written for clients to render.
+type CommentRequest struct {
+	Body string `json:"body" validate:"required,max=10000"`
+}
+
+type CommentResponse struct {
+	ID        int         `json:"id"`
+	TaskID    int         `json:"task_id"`
+	Body      string      `json:"body"`
+	Author    UserSummary `json:"author"`
+	CreatedAt time.Time   `json:"created_at"`
+	EditedAt  *time.Time  `json:"edited_at"`
+}
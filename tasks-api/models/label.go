@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// DefaultLabelColor is used when a label is created without a colour
+const DefaultLabelColor = "#9e9e9e"
+
+type Label struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type LabelRequest struct {
+	Name  string `json:"name" validate:"required,min=1,max=50,excludesall=0x2C"`
+	Color string `json:"color" validate:"omitempty,hexcolor"`
+}
+
+type LabelResponse struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
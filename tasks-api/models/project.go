@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// DefaultProjectColor is used when a project is created without a colour
+const DefaultProjectColor = "#4a90d9"
+
+type Project struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"`
+	Archived  bool      `json:"archived"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ProjectRequest struct {
+	Name     string `json:"name" validate:"required,min=1,max=100"`
+	Color    string `json:"color" validate:"omitempty,hexcolor"`
+	Archived bool   `json:"archived"`
+	Position *int   `json:"position" validate:"omitempty,min=0"`
+}
+
+type ProjectResponse struct {
+	ID          int       `json:"id"`
+	WorkspaceID int       `json:"workspace_id"`
+	Name        string    `json:"name"`
+	Color       string    `json:"color"`
+	Archived    bool      `json:"archived"`
+	Position    int       `json:"position"`
+	TaskCount   int       `json:"task_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
@@ -11,9 +11,16 @@ type Response struct {
 	Status  string            `json:"status"`
 	Message string            `json:"message"`
 	Data    any               `json:"data,omitempty"`
+	Meta    *Meta             `json:"meta,omitempty"`
 	Errors  map[string]string `json:"errors,omitempty"`
 }
 
+// Meta holds pagination details for list responses
+type Meta struct {
+	Limit      int     `json:"limit"`
+	NextCursor *string `json:"next_cursor"`
+}
+
 // NewErrorResponse creates a new error response
 func NewErrorResponse(message string, validationErrors any) *Response {
 	resp := &Response{Status: "error", Message: message}
@@ -44,6 +51,8 @@ func NewErrorResponse(message string, validationErrors any) *Response {
 				errMap[field] = msg
 			}
 			resp.Errors = errMap
+		case map[string]string:
+			resp.Errors = v
 		case error:
 			resp.Message = v.Error()
 		}
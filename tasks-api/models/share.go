@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Roles a user can have on a task or project, from least to most privileged.
+// Viewers can read, editors can also update and complete tasks, and only the
+// owner can delete or share.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleOwner  = "owner"
+)
+
+type ShareRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=viewer editor"`
+}
+
+type ShareResponse struct {
+	UserID    int       `json:"user_id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SharedProjectResponse struct {
+	ProjectResponse
+	Role       string `json:"role"`
+	OwnerName  string `json:"owner_name"`
+	OwnerEmail string `json:"owner_email"`
+}
@@ -2,26 +2,112 @@ package models
 
 import "time"
 
+// Task priorities, from least to most urgent
+const (
+	PriorityLow    = "low"
+	PriorityMedium = "medium"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
+)
+
 type Task struct {
-	ID          int       `json:"id"`
-	UserID      int       `json:"user_id"`
-	Title       string    `json:"title" validate:"required,min=1,max=255"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Completed   bool      `json:"completed"`
+	ID          int        `json:"id"`
+	UserID      int        `json:"user_id"`
+	WorkspaceID int        `json:"workspace_id"`
+	Title       string     `json:"title" validate:"required,min=1,max=255"`
+	Description string     `json:"description"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Completed   bool       `json:"completed"`
+	DueAt       *time.Time `json:"due_at"`
+	Priority    string     `json:"priority"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ProjectID   *int       `json:"project_id"`
+	ParentID    *int       `json:"parent_id"`
+	Recurrence  *string    `json:"recurrence"`
+	Occurrence  int        `json:"occurrence"`
+	DeletedAt   *time.Time `json:"deleted_at"`
+	Version     int        `json:"version"`
 }
 
 type TaskRequest struct {
-	Title       string `json:"title" validate:"required,min=1,max=255"`
-	Description string `json:"description"`
+	Title       string     `json:"title" validate:"required,min=1,max=255"`
+	Description string     `json:"description"`
+	DueAt       *time.Time `json:"due_at"`
+	Priority    string     `json:"priority" validate:"omitempty,oneof=low medium high urgent"`
+	LabelIDs    []int      `json:"label_ids"`
+	ProjectID   *int       `json:"project_id"`
+	ParentID    *int       `json:"parent_id"`
+	Recurrence  string     `json:"recurrence"`
+}
+
+// TaskPatch holds the fields of a task a PATCH request can change. Patches
+// are applied to the current values and only the fields they touch are
+// validated.
+type TaskPatch struct {
+	Title       string     `json:"title" validate:"required,min=1,max=255"`
+	Description string     `json:"description"`
+	DueAt       *time.Time `json:"due_at"`
+	Priority    string     `json:"priority" validate:"omitempty,oneof=low medium high urgent"`
+	LabelIDs    []int      `json:"label_ids"`
+	ProjectID   *int       `json:"project_id"`
+	ParentID    *int       `json:"parent_id"`
+	Recurrence  *string    `json:"recurrence"`
+	Completed   bool       `json:"completed"`
 }
 
 type TaskResponse struct {
-	ID          int       `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Completed   bool      `json:"completed"`
+	ID          int             `json:"id"`
+	WorkspaceID int             `json:"workspace_id"`
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	Version     int             `json:"version"`
+	Completed   bool            `json:"completed"`
+	DueAt       *time.Time      `json:"due_at"`
+	Priority    string          `json:"priority"`
+	CompletedAt *time.Time      `json:"completed_at"`
+	ProjectID   *int            `json:"project_id"`
+	ParentID    *int            `json:"parent_id"`
+	Recurrence  *string         `json:"recurrence"`
+	Labels      []LabelResponse `json:"labels"`
+	Progress    *TaskProgress   `json:"progress"`
+	DeletedAt   *time.Time      `json:"deleted_at,omitempty"`
+}
+
+// TaskProgress counts the completed direct subtasks of a task. It is null for
+// tasks without subtasks.
+type TaskProgress struct {
+	Completed int `json:"completed"`
+	Total     int `json:"total"`
+}
+
+// TaskTreeNode is a task with its nested subtasks
+type TaskTreeNode struct {
+	TaskResponse
+	Subtasks []*TaskTreeNode `json:"subtasks"`
+}
+
+// TaskOccurrencesResponse previews the upcoming occurrences of a recurring task
+type TaskOccurrencesResponse struct {
+	Recurrence  string      `json:"recurrence"`
+	Timezone    string      `json:"timezone"`
+	Occurrences []time.Time `json:"occurrences"`
+}
+
+type DependencyRequest struct {
+	BlockedByID int `json:"blocked_by_id" validate:"required"`
+}
+
+type TaskDependenciesResponse struct {
+	BlockedBy []TaskResponse `json:"blocked_by"`
+	Blocking  []TaskResponse `json:"blocking"`
+}
+
+type TaskSearchResult struct {
+	TaskResponse
+	Rank               float64 `json:"rank"`
+	TitleHighlight     string  `json:"title_highlight"`
+	DescriptionSnippet string  `json:"description_snippet"`
 }
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Actions recorded in the history of a task
+const (
+	TaskEventCreated   = "created"
+	TaskEventUpdated   = "updated"
+	TaskEventCompleted = "completed"
+	TaskEventDeleted   = "deleted"
+	TaskEventRestored  = "restored"
+)
+
+// FieldChange is the value of a task field before and after an event. Old is
+// null for created and restored tasks and New is null for deleted ones.
+type FieldChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+type TaskEventResponse struct {
+	ID          int                    `json:"id"`
+	TaskID      int                    `json:"task_id"`
+	TaskTitle   string                 `json:"task_title"`
+	WorkspaceID int                    `json:"workspace_id"`
+	Action      string                 `json:"action"`
+	Actor       UserSummary            `json:"actor"`
+	Changes     map[string]FieldChange `json:"changes"`
+	CreatedAt   time.Time              `json:"created_at"`
+}
@@ -9,18 +9,80 @@ type User struct {
 	Name      string    `json:"name"`
 	Email     string    `json:"email"`
 	Password  string    `json:"-"` // Don't expose password in JSON
+	Timezone  string    `json:"timezone"`
 	CreatedAt time.Time `json:"created_at"`
+	// EmailVerifiedAt is nil until the user opens the link in the
+	// verification email
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	Role            string     `json:"role"`
+	DisabledAt      *time.Time `json:"disabled_at"`
+}
+
+// UserSummary identifies the user behind a comment or event
+type UserSummary struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
 }
 
 type RegisterRequest struct {
 	Name     string `json:"name" validate:"required,min=3,max=100"`
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=6,max=100"`
+	Timezone string `json:"timezone" validate:"omitempty,timezone"`
 }
 
 type RegisterResponse struct {
-	ID        int       `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
+	ID            int       `json:"id"`
+	Name          string    `json:"name"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	Timezone      string    `json:"timezone"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ProfileResponse is the account of the authenticated user
+type ProfileResponse struct {
+	ID            int       `json:"id"`
+	Name          string    `json:"name"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	Role          string    `json:"role"`
+	Timezone      string    `json:"timezone"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// UpdateProfileRequest changes the fields that are set and keeps the others
+type UpdateProfileRequest struct {
+	Name     *string `json:"name" validate:"omitempty,min=3,max=100"`
+	Email    *string `json:"email" validate:"omitempty,email"`
+	Timezone *string `json:"timezone" validate:"omitempty,timezone"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=6,max=100"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// AccountExport is everything a user created, returned when the account is deleted
+type AccountExport struct {
+	User       ProfileResponse     `json:"user"`
+	Workspaces []WorkspaceResponse `json:"workspaces"`
+	Projects   []ProjectResponse   `json:"projects"`
+	Tasks      []TaskResponse      `json:"tasks"`
+	Labels     []LabelResponse     `json:"labels"`
+	Comments   []CommentResponse   `json:"comments"`
+	ExportedAt time.Time           `json:"exported_at"`
 }
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// Roles of workspace members, from most to least privileged
+const (
+	WorkspaceRoleOwner  = "owner"
+	WorkspaceRoleAdmin  = "admin"
+	WorkspaceRoleMember = "member"
+)
+
+// WorkspaceMembership is the workspace a request acts in and the role the
+// authenticated user has in it
+type WorkspaceMembership struct {
+	WorkspaceID int    `json:"workspace_id"`
+	UserID      int    `json:"user_id"`
+	Role        string `json:"role"`
+	Personal    bool   `json:"personal"`
+}
+
+type WorkspaceRequest struct {
+	Name string `json:"name" validate:"required,min=1,max=100"`
+}
+
+type WorkspaceResponse struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Personal    bool      `json:"personal"`
+	Role        string    `json:"role"`
+	MemberCount int       `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type WorkspaceMemberRequest struct {
+	Role string `json:"role" validate:"required,oneof=admin member"`
+}
+
+type WorkspaceMemberResponse struct {
+	UserID   int       `json:"user_id"`
+	Name     string    `json:"name"`
+	Email    string    `json:"email"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+type InvitationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=admin member"`
+}
+
+// InvitationResponse describes a pending invitation. Token is only returned
+// when the invitation is created.
+type InvitationResponse struct {
+	ID        int       `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	InvitedBy int       `json:"invited_by"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	Token     string    `json:"token,omitempty"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token" validate:"required"`
+}